@@ -20,7 +20,7 @@ var WMs = []WM{
 	&PCManFMQt{},
 	&SwayBG{},
 	&Weston{},
-	// xbg.New(), // X11
+	&X11{}, // set the X11 root window pixmap directly
 	&Feh{}, // use feh for X11, for now
 }
 
@@ -35,42 +35,10 @@ func Info() (string, error) {
 	return "", errNoWaylandNoX
 }
 
-// Monitors returns information about all monitors, regardless of if it's under
-// Wayland or X11. Will use additional plugins, if available.
-func Monitors() ([]Monitor, error) {
-	IDs, widths, heights, wDPIs, hDPIs := []uint{}, []uint{}, []uint{}, []uint{}, []uint{}
-	if WaylandCanConnect() {
-		if err := WaylandMonitors(&IDs, &widths, &heights, &wDPIs, &hDPIs); err != nil {
-			return []Monitor{}, err
-		}
-	} else if XCanConnect() {
-		if err := XMonitors(&IDs, &widths, &heights, &wDPIs, &hDPIs); err != nil {
-			return []Monitor{}, err
-		}
-	}
-	if len(IDs) == 0 {
-		return []Monitor{}, errNoWaylandNoX
-	}
-	// Build and return a []Monitor slice
-	monitors := []Monitor{}
-	for i, ID := range IDs {
-		monitors = append(monitors, Monitor{ID, widths[i], heights[i], wDPIs[i], hDPIs[i]})
-	}
-	return monitors, nil
-}
-
-// AverageResolution returns the average resolution for all connected monitors.
-func AverageResolution() (*Res, error) {
-	monitors, err := Monitors()
-	if err != nil {
-		return nil, err
-	}
-	var ws, hs uint
-	for _, mon := range monitors {
-		ws += mon.Width
-		hs += mon.Height
-	}
-	ws /= uint(len(monitors))
-	hs /= uint(len(monitors))
-	return NewRes(ws, hs), nil
+// monitorDetectors are tried in order by Monitors, until one of them finds
+// at least one monitor
+var monitorDetectors = []func() ([]Monitor, error){
+	waylandMonitors,
+	xMonitors,
+	func() ([]Monitor, error) { return drmMonitors(drmPath) },
 }
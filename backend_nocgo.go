@@ -19,6 +19,14 @@ var WMs = []WM{
 	&Pekwm{},
 	&PCManFMQt{},
 	&Weston{},
-	// xbg.New(), // X11
+	&X11{}, // set the X11 root window pixmap directly
 	&Feh{}, // use feh for X11
 }
+
+// monitorDetectors are tried in order by Monitors, until one of them finds
+// at least one monitor. These are implemented in pure Go.
+var monitorDetectors = []func() ([]Monitor, error){
+	wireWaylandMonitors,
+	x11Monitors,
+	func() ([]Monitor, error) { return drmMonitors(drmPath) },
+}
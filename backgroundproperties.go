@@ -0,0 +1,182 @@
+package wallutils
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// GnomeWallpaper is a <wallpaper> entry in a GNOME background-properties
+// XML file, like the ones in /usr/share/gnome-background-properties
+type GnomeWallpaper struct {
+	Name           string // the display name, like "Adwaita"
+	Filename       string // the image or GNOME timed wallpaper XML file
+	FilenameDark   string // the image or timed wallpaper to use with a dark color scheme, if any
+	Options        string // the recommended wallpaper mode, like "zoom" or "centered"
+	ShadeType      string // "solid", "horizontal-gradient" or "vertical-gradient"
+	PrimaryColor   string // the primary color, like "#3071ae"
+	SecondaryColor string // the secondary color, for gradients
+	Path           string // the full path to the background-properties XML file
+}
+
+// gnomeWallpapersXML is the format of a background-properties XML file
+type gnomeWallpapersXML struct {
+	XMLName    xml.Name `xml:"wallpapers"`
+	Wallpapers []struct {
+		Deleted string `xml:"deleted,attr"`
+		Names   []struct {
+			Lang  string `xml:"http://www.w3.org/XML/1998/namespace lang,attr"`
+			Value string `xml:",chardata"`
+		} `xml:"name"`
+		Filename     string `xml:"filename"`
+		FilenameDark string `xml:"filename-dark"`
+		Options      string `xml:"options"`
+		ShadeType    string `xml:"shade_type"`
+		PColor       string `xml:"pcolor"`
+		SColor       string `xml:"scolor"`
+	} `xml:"wallpaper"`
+}
+
+// xmlRootElement returns the name of the root element of the given XML file,
+// like "background" for GNOME timed wallpapers or "wallpapers" for GNOME
+// background-properties files
+func xmlRootElement(filename string) (string, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	decoder := xml.NewDecoder(f)
+	for {
+		token, err := decoder.Token()
+		if errors.Is(err, io.EOF) {
+			return "", fmt.Errorf("%s: no XML elements", filename)
+		} else if err != nil {
+			return "", fmt.Errorf("%s: %v", filename, err)
+		}
+		if start, ok := token.(xml.StartElement); ok {
+			return start.Name.Local, nil
+		}
+	}
+}
+
+// ParseBackgroundProperties parses a GNOME background-properties XML file.
+// Entries that are marked as deleted are skipped.
+func ParseBackgroundProperties(filename string) ([]*GnomeWallpaper, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	var parsed gnomeWallpapersXML
+	if err := xml.Unmarshal(data, &parsed); err != nil {
+		return nil, fmt.Errorf("could not parse %s: %v", filename, err)
+	}
+	var gnomeWallpapers []*GnomeWallpaper
+	for _, entry := range parsed.Wallpapers {
+		if entry.Deleted == "true" {
+			continue
+		}
+		gw := &GnomeWallpaper{
+			Filename:       strings.TrimSpace(entry.Filename),
+			FilenameDark:   strings.TrimSpace(entry.FilenameDark),
+			Options:        strings.TrimSpace(entry.Options),
+			ShadeType:      strings.TrimSpace(entry.ShadeType),
+			PrimaryColor:   strings.TrimSpace(entry.PColor),
+			SecondaryColor: strings.TrimSpace(entry.SColor),
+			Path:           filename,
+		}
+		// Use the name that is not translated, if there is one
+		for _, name := range entry.Names {
+			if name.Lang == "" {
+				gw.Name = strings.TrimSpace(name.Value)
+				break
+			}
+		}
+		if gw.Name == "" && len(entry.Names) > 0 {
+			gw.Name = strings.TrimSpace(entry.Names[0].Value)
+		}
+		if gw.Name == "" {
+			gw.Name = firstname(filepath.Base(gw.Filename))
+		}
+		gnomeWallpapers = append(gnomeWallpapers, gw)
+	}
+	return gnomeWallpapers, nil
+}
+
+// Image returns the image or timed wallpaper filename, using the dark
+// variant if dark is true and there is one. An empty string is returned if
+// the wallpaper only consists of colors.
+func (gw *GnomeWallpaper) Image(dark bool) string {
+	filename := gw.Filename
+	if dark && gw.FilenameDark != "" {
+		filename = gw.FilenameDark
+	}
+	if filename == "(none)" {
+		return ""
+	}
+	return filename
+}
+
+// Timed checks if the given variant of the wallpaper is a GNOME timed wallpaper
+func (gw *GnomeWallpaper) Timed(dark bool) bool {
+	return filepath.Ext(gw.Image(dark)) == ".xml"
+}
+
+// Mode returns the wallpaper mode that is recommended for this wallpaper,
+// like "fill", or the default mode if there is no recommendation. The GNOME
+// picture options are converted to the modes that all backends understand.
+func (gw *GnomeWallpaper) Mode() string {
+	switch gw.Options {
+	case "", "none":
+		return defaultMode
+	case "wallpaper":
+		return "tile"
+	case "centered":
+		return "center"
+	case "scaled":
+		return "fit"
+	case "stretched":
+		return "stretch"
+	case "zoom", "spanned":
+		// "spanned" covers all monitors with the image
+		return "fill"
+	}
+	return gw.Options
+}
+
+// SetWallpaper sets this wallpaper, with the recommended mode. If it has no
+// image, or the options are "none", the colors are used instead: a solid
+// color or a gradient, depending on the shade type. Set dark to true to use
+// the dark variant, if there is one. Timed wallpapers can not be set with
+// this function, see settimed.
+func (gw *GnomeWallpaper) SetWallpaper(dark, verbose bool) error {
+	if gw.Timed(dark) {
+		return fmt.Errorf("%s is a timed wallpaper: %s", gw.Name, gw.Image(dark))
+	}
+	if imageFilename := gw.Image(dark); imageFilename != "" && gw.Options != "none" {
+		if gw.Options == "spanned" {
+			return SetWallpaperSpanned(imageFilename, 0, gw.Mode(), verbose)
+		}
+		return SetWallpaperCustom(imageFilename, gw.Mode(), verbose)
+	}
+	if gw.PrimaryColor == "" {
+		return fmt.Errorf("%s has no image and no color", gw.Name)
+	}
+	switch {
+	case gw.SecondaryColor == "":
+		// Use a solid color
+	case gw.ShadeType == "horizontal-gradient":
+		return SetWallpaperGradient(gw.PrimaryColor+","+gw.SecondaryColor, 0, verbose)
+	case gw.ShadeType == "vertical-gradient":
+		return SetWallpaperGradient(gw.PrimaryColor+","+gw.SecondaryColor, 90, verbose)
+	}
+	c, err := ParseColor(gw.PrimaryColor)
+	if err != nil {
+		return err
+	}
+	return SetWallpaperColor(c, verbose)
+}
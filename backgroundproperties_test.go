@@ -0,0 +1,121 @@
+package wallutils
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+const testBackgroundProperties = `<?xml version="1.0"?>
+<!DOCTYPE wallpapers SYSTEM "gnome-wp-list.dtd">
+<wallpapers>
+  <wallpaper deleted="false">
+    <name xml:lang="de">Adwaita auf Deutsch</name>
+    <name>Adwaita</name>
+    <filename>/usr/share/backgrounds/gnome/adwaita-l.jpg</filename>
+    <filename-dark>/usr/share/backgrounds/gnome/adwaita-d.jpg</filename-dark>
+    <options>zoom</options>
+    <shade_type>solid</shade_type>
+    <pcolor>#3071AE</pcolor>
+    <scolor>#000000</scolor>
+  </wallpaper>
+  <wallpaper deleted="true">
+    <name>Removed</name>
+    <filename>/usr/share/backgrounds/gnome/removed.jpg</filename>
+  </wallpaper>
+  <wallpaper>
+    <name>Blue Gradient</name>
+    <filename>(none)</filename>
+    <options>none</options>
+    <shade_type>vertical-gradient</shade_type>
+    <pcolor>#3071ae</pcolor>
+    <scolor>#000000</scolor>
+  </wallpaper>
+  <wallpaper>
+    <name>Adwaita Timed</name>
+    <filename>/usr/share/backgrounds/gnome/adwaita-timed.xml</filename>
+    <options>spanned</options>
+  </wallpaper>
+</wallpapers>
+`
+
+func TestParseBackgroundProperties(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "adwaita.xml")
+	if err := os.WriteFile(filename, []byte(testBackgroundProperties), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	gnomeWallpapers, err := ParseBackgroundProperties(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(gnomeWallpapers) != 3 {
+		t.Fatalf("expected three wallpapers, got %d", len(gnomeWallpapers))
+	}
+
+	adwaita := gnomeWallpapers[0]
+	if adwaita.Name != "Adwaita" || adwaita.Path != filename || adwaita.PrimaryColor != "#3071AE" || adwaita.ShadeType != "solid" {
+		t.Errorf("unexpected wallpaper: %+v", adwaita)
+	}
+	if adwaita.Image(false) != "/usr/share/backgrounds/gnome/adwaita-l.jpg" || adwaita.Image(true) != "/usr/share/backgrounds/gnome/adwaita-d.jpg" {
+		t.Errorf("unexpected images: %s and %s", adwaita.Image(false), adwaita.Image(true))
+	}
+	if adwaita.Mode() != "fill" || adwaita.Timed(false) {
+		t.Errorf("expected an image with the fill mode, got %s", adwaita.Mode())
+	}
+
+	gradient := gnomeWallpapers[1]
+	if gradient.Image(false) != "" || gradient.Image(true) != "" || gradient.Mode() != defaultMode || gradient.SecondaryColor != "#000000" {
+		t.Errorf("unexpected wallpaper: %+v", gradient)
+	}
+
+	timed := gnomeWallpapers[2]
+	if !timed.Timed(false) || timed.Mode() != "fill" {
+		t.Errorf("expected a timed wallpaper with the fill mode: %+v", timed)
+	}
+}
+
+func TestGnomeWallpaperMode(t *testing.T) {
+	for _, test := range []struct {
+		options, mode string
+	}{
+		{"", defaultMode},
+		{"none", defaultMode},
+		{"wallpaper", "tile"},
+		{"centered", "center"},
+		{"scaled", "fit"},
+		{"stretched", "stretch"},
+		{"zoom", "fill"},
+		{"spanned", "fill"},
+	} {
+		if mode := (&GnomeWallpaper{Options: test.options}).Mode(); mode != test.mode {
+			t.Errorf("expected %s for the %q option, got %s", test.mode, test.options, mode)
+		}
+	}
+}
+
+func TestFindBackgroundProperties(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "adwaita.xml"), []byte(testBackgroundProperties), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	// Other XML files are skipped
+	if err := os.WriteFile(filepath.Join(dir, "other.xml"), []byte("<?xml version=\"1.0\"?>\n<something/>\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	useWallpaperDirectory(t, dir)
+	for i := 0; i < 2; i++ {
+		sr, err := FindWallpapers()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(sr.GnomeWallpapers()) != 3 || len(sr.GnomeTimedWallpapers()) != 0 || sr.Empty() {
+			t.Fatalf("expected three GNOME wallpapers, got %v", sr.GnomeWallpapers())
+		}
+		if found := sr.GnomeWallpapersByName("Blue Gradient"); len(found) != 1 || found[0].ShadeType != "vertical-gradient" {
+			t.Errorf("expected the Blue Gradient wallpaper, got %v", found)
+		}
+		if !hasS(sr.CollectionNames(), "Adwaita") {
+			t.Errorf("expected Adwaita to be a collection, got %v", sr.CollectionNames())
+		}
+	}
+}
@@ -0,0 +1,40 @@
+package wallutils
+
+// Capabilities describes what a backend can do
+type Capabilities struct {
+	Modes      []string // the supported wallpaper modes, like "fill" and "tile"
+	PerOutput  bool     // one wallpaper per output (monitor) can be set
+	Persistent bool     // the wallpaper is kept after logging out and in again
+	Restart    bool     // the backend must be restarted before a new wallpaper is shown
+	Formats    []string // image file extensions that can be used directly, in addition to PNG and JPEG
+}
+
+// SupportsMode checks if the given mode is one of the supported modes
+func (c Capabilities) SupportsMode(mode string) bool {
+	for _, m := range c.Modes {
+		if m == mode {
+			return true
+		}
+	}
+	return false
+}
+
+// BackendCapabilities returns what the given backend can do, if it
+// implements the CapabilitiesWM interface. If not, the zero value and false
+// is returned, and the backend may or may not support a given mode.
+func BackendCapabilities(wm WM) (Capabilities, bool) {
+	if cwm, ok := wm.(CapabilitiesWM); ok {
+		return cwm.Capabilities(), true
+	}
+	return Capabilities{}, false
+}
+
+// unsupportedMode checks if the given backend is known to not support the
+// given mode, so that an error can be returned before trying to set the wallpaper
+func unsupportedMode(wm WM, mode string) bool {
+	if mode == "" || mode == defaultMode {
+		return false
+	}
+	capabilities, ok := BackendCapabilities(wm)
+	return ok && len(capabilities.Modes) > 0 && !capabilities.SupportsMode(mode)
+}
@@ -17,7 +17,8 @@ func getDPIAction(c *cli.Context) error {
 
 	if c.IsSet("all") {
 		for i, monitor := range monitors {
-			fmt.Printf("[%d] %dx%d\n", i, monitor.DPIw, monitor.DPIh)
+			// Also output where the DPI values came from
+			fmt.Printf("[%d] %dx%d (%s)\n", i, monitor.DPIw, monitor.DPIh, monitor.DPISource)
 		}
 		return nil
 	}
@@ -60,7 +61,7 @@ func main() {
 	app.Flags = []cli.Flag{
 		cli.BoolFlag{
 			Name:  "all, a, l",
-			Usage: "output DPI information for all available monitors",
+			Usage: "output DPI information for all available monitors, and where it came from",
 		},
 		cli.BoolFlag{
 			Name:  "both, b",
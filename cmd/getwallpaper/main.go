@@ -0,0 +1,83 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"sort"
+
+	"github.com/urfave/cli"
+	"github.com/xyproto/wallutils"
+)
+
+func getWallpaperAction(c *cli.Context) error {
+	verbose := c.IsSet("verbose")
+
+	// Only output the last wallpaper that was set with wallutils, if requested
+	if c.IsSet("saved") {
+		s, err := wallutils.LoadState()
+		if err != nil {
+			return err
+		}
+		if s.Color != "" {
+			fmt.Println(s.Color)
+		}
+		if s.Image != "" {
+			fmt.Println(s.Image)
+		}
+		if s.Dark != "" {
+			fmt.Println(s.Dark)
+		}
+		// List the wallpaper per output, sorted by output name
+		var outputs []string
+		for output := range s.Outputs {
+			outputs = append(outputs, output)
+		}
+		sort.Strings(outputs)
+		for _, output := range outputs {
+			fmt.Printf("%s: %s\n", output, s.Outputs[output])
+		}
+		if verbose {
+			fmt.Println("mode:", s.Mode)
+		}
+		return nil
+	}
+
+	imageFilename, err := wallutils.CurrentWallpaperVerbose(verbose)
+	if err != nil {
+		return err
+	}
+	fmt.Println(imageFilename)
+	return nil
+}
+
+func main() {
+	app := cli.NewApp()
+
+	app.Name = "getwallpaper"
+	app.Usage = "output the filename of the current desktop wallpaper"
+	app.UsageText = "getwallpaper [options]"
+
+	app.Version = wallutils.VersionString
+	app.HideHelp = true
+
+	cli.VersionFlag = cli.BoolFlag{
+		Name:  "version, V",
+		Usage: "output version information",
+	}
+
+	app.Flags = []cli.Flag{
+		cli.BoolFlag{
+			Name:  "verbose, v",
+			Usage: "verbose output",
+		},
+		cli.BoolFlag{
+			Name:  "saved, s",
+			Usage: "output the wallpaper that was last set with wallutils, instead of asking the desktop environment",
+		},
+	}
+
+	app.Action = getWallpaperAction
+	if err := app.Run(os.Args); err != nil {
+		wallutils.Quit(err)
+	}
+}
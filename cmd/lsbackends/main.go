@@ -0,0 +1,94 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/urfave/cli"
+	"github.com/xyproto/wallutils"
+)
+
+// yesNo returns "yes" or "no"
+func yesNo(b bool) string {
+	if b {
+		return "yes"
+	}
+	return "no"
+}
+
+func listBackendsAction(c *cli.Context) error {
+	// Select a backend, if one is given
+	if err := wallutils.SetBackend(c.String("backend")); err != nil {
+		return err
+	}
+	selected := wallutils.SelectedBackend()
+	if selected != "" {
+		if _, err := wallutils.FindBackend(selected); err != nil {
+			return err
+		}
+	}
+
+	// The backend that would be used for setting the wallpaper, if any
+	var detectedName string
+	if wm := wallutils.DetectedBackend(); wm != nil {
+		detectedName = wm.Name()
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "BACKEND\tEXECUTABLES\tRUNNING\tPER OUTPUT\tPERSISTENT\tMODES")
+	for _, wm := range wallutils.WMs {
+		name := wm.Name()
+		if name == detectedName {
+			name += " *"
+		}
+		capabilities, _ := wallutils.BackendCapabilities(wm)
+		modes := "-"
+		if len(capabilities.Modes) > 0 {
+			modes = strings.Join(capabilities.Modes, ", ")
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n", name, yesNo(wm.ExecutablesExists()), yesNo(wm.Running()), yesNo(capabilities.PerOutput), yesNo(capabilities.Persistent), modes)
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	switch {
+	case detectedName != "" && selected != "":
+		fmt.Printf("\n* is selected with --backend or %s\n", wallutils.BackendEnvVar)
+	case detectedName != "":
+		fmt.Println("\n* is used for setting the wallpaper")
+	case selected != "":
+		fmt.Printf("\n%s is selected, but the executables it needs could not be found\n", selected)
+	}
+	return nil
+}
+
+func main() {
+	app := cli.NewApp()
+
+	app.Name = "lsbackends"
+	app.Usage = "list all backends for setting the wallpaper, and which one is used"
+	app.UsageText = "lsbackends [options]"
+
+	app.Version = wallutils.VersionString
+	app.HideHelp = true
+
+	cli.VersionFlag = cli.BoolFlag{
+		Name:  "version, V",
+		Usage: "output version information",
+	}
+
+	app.Flags = []cli.Flag{
+		cli.StringFlag{
+			Name:  "backend",
+			Usage: "mark the backend with the given name as selected, like the set* commands would",
+		},
+	}
+
+	app.Action = listBackendsAction
+	if err := app.Run(os.Args); err != nil {
+		wallutils.Quit(err)
+	}
+}
@@ -24,7 +24,11 @@ func listWallpaperCollectionAction(c *cli.Context) error {
 	alsoPrintPath := c.IsSet("long")
 
 	// Find all wallpapers
-	searchResults, err := wallutils.FindWallpapers()
+	findWallpapers := wallutils.FindWallpapers
+	if c.IsSet("rescan") {
+		findWallpapers = wallutils.RescanWallpapers
+	}
+	searchResults, err := findWallpapers()
 	if err != nil {
 		return err
 	}
@@ -46,8 +50,14 @@ func listWallpaperCollectionAction(c *cli.Context) error {
 		if wp.PartOfCollection {
 			name := wp.CollectionName
 			dir := filepath.Dir(wp.Path) + "/"
+			collectionType := "Wallpaper Collection"
+			if wp.Name != "" {
+				// Part of a KDE Plasma wallpaper package
+				collectionType = "Wallpaper Package"
+				dir = filepath.Dir(filepath.Dir(filepath.Dir(wp.Path))) + "/"
+			}
 			if alsoPrintPath && !has(collectionNames, name) {
-				fmt.Fprintf(w, "%s\t%s\t\t%s\n", name, "Wallpaper Collection", dir)
+				fmt.Fprintf(w, "%s\t%s\t\t%s\n", name, collectionType, dir)
 				collectionNames = append(collectionNames, wp.CollectionName)
 			}
 		}
@@ -78,6 +88,21 @@ func listWallpaperCollectionAction(c *cli.Context) error {
 		}
 	}
 
+	// Output all wallpapers from GNOME background-properties files, with
+	// the path to the image or timed wallpaper.
+	collectionNames = []string{}
+	for _, gw := range searchResults.GnomeWallpapers() {
+		name := gw.Name
+		path := gw.Image(false)
+		if path == "" {
+			path = gw.Path
+		}
+		if alsoPrintPath && !has(collectionNames, name) {
+			fmt.Fprintf(w, "%s\t%s\t\t%s\n", name, "GNOME Wallpaper", path)
+			collectionNames = append(collectionNames, name)
+		}
+	}
+
 	// Write the output to stdout
 	w.Flush()
 
@@ -104,6 +129,10 @@ func main() {
 			Name:  "long, l",
 			Usage: "also list collection type and full path",
 		},
+		cli.BoolFlag{
+			Name:  "rescan",
+			Usage: "read all wallpapers again, instead of using the wallpaper index",
+		},
 	}
 
 	app.Action = listWallpaperCollectionAction
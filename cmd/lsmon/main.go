@@ -16,9 +16,12 @@ func listMonitorAction(c *cli.Context) error {
 	}
 	// For every monitor, output the ID, width and height
 	alsoDPI := c.IsSet("dpi")
+	long := c.IsSet("long")
 	for _, mon := range monitors {
-		if alsoDPI {
-			fmt.Printf("%d: %dx%d (DPI: %dx%d)\n", mon.ID, mon.Width, mon.Height, mon.DPIw, mon.DPIh)
+		if long {
+			fmt.Println(mon.Long())
+		} else if alsoDPI {
+			fmt.Printf("%d: %dx%d (DPI: %dx%d, from %s)\n", mon.ID, mon.Width, mon.Height, mon.DPIw, mon.DPIh, mon.DPISource)
 		} else {
 			fmt.Printf("%d: %dx%d\n", mon.ID, mon.Width, mon.Height)
 		}
@@ -44,7 +47,11 @@ func main() {
 	app.Flags = []cli.Flag{
 		cli.BoolFlag{
 			Name:  "d, dpi",
-			Usage: "also output the monitor DPI",
+			Usage: "also output the monitor DPI, and where it came from",
+		},
+		cli.BoolFlag{
+			Name:  "l, long",
+			Usage: "also output the name, position, refresh rate, scale, rotation and model",
 		},
 	}
 
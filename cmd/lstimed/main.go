@@ -15,7 +15,11 @@ func listTimedWallpapersAction(c *cli.Context) error {
 	// Prepare to write text in columns
 	w := tabwriter.NewWriter(os.Stdout, 0, 0, 4, ' ', 0)
 
-	searchResults, err := wallutils.FindWallpapers()
+	findWallpapers := wallutils.FindWallpapers
+	if c.IsSet("rescan") {
+		findWallpapers = wallutils.RescanWallpapers
+	}
+	searchResults, err := findWallpapers()
 	if err != nil {
 		return err
 	}
@@ -60,6 +64,10 @@ func main() {
 			Name:  "long, l",
 			Usage: "also list paths, and the number of timed events",
 		},
+		cli.BoolFlag{
+			Name:  "rescan",
+			Usage: "read all wallpapers again, instead of using the wallpaper index",
+		},
 	}
 
 	app.Action = listTimedWallpapersAction
@@ -9,7 +9,11 @@ import (
 )
 
 func listWallpapersAction(c *cli.Context) error {
-	searchResults, err := wallutils.FindWallpapers()
+	findWallpapers := wallutils.FindWallpapers
+	if c.IsSet("rescan") {
+		findWallpapers = wallutils.RescanWallpapers
+	}
+	searchResults, err := findWallpapers()
 	if err != nil {
 		return err
 	}
@@ -75,6 +79,10 @@ func main() {
 		//	Name:  "goodfit, g",
 		//	Usage: "list the wallpaper that is the best fit for the current resolution",
 		//},
+		cli.BoolFlag{
+			Name:  "rescan",
+			Usage: "read all wallpapers again, instead of using the wallpaper index",
+		},
 	}
 
 	app.Action = listWallpapersAction
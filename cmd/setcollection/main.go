@@ -32,13 +32,18 @@ func SelectAndSetWallpaper(wallpapers []*wallutils.Wallpaper) error {
 
 	// Set the desktop wallpaper
 	if err := wallutils.SetWallpaper(imageFilename); err != nil {
-		return fmt.Errorf("could not set wallpaper: %s", err)
+		return fmt.Errorf("could not set wallpaper: %w", err)
 	}
 
 	return nil
 }
 
 func setWallpaperCollectionAction(c *cli.Context) error {
+	// Use the given backend, if one was given
+	if err := wallutils.SetBackend(c.String("backend")); err != nil {
+		return err
+	}
+
 	if c.NArg() == 0 {
 		return errors.New("please specify a wallpaper collection name")
 	}
@@ -51,7 +56,11 @@ func setWallpaperCollectionAction(c *cli.Context) error {
 		fmt.Print("Searching for wallpapers...")
 	}
 
-	searchResults, err := wallutils.FindWallpapers()
+	findWallpapers := wallutils.FindWallpapers
+	if c.IsSet("rescan") {
+		findWallpapers = wallutils.RescanWallpapers
+	}
+	searchResults, err := findWallpapers()
 	if err != nil {
 		return err
 	}
@@ -73,6 +82,18 @@ func setWallpaperCollectionAction(c *cli.Context) error {
 		fmt.Println("ok")
 	}
 
+	// Use the recommended options and colors from a GNOME background-properties file
+	if gnomeWallpapers := searchResults.GnomeWallpapersByName(collectionName); len(wallpapers) == 0 && len(gnomeWallpapers) > 0 {
+		gw := gnomeWallpapers[0]
+		if gw.Timed(false) {
+			return errors.New("timed wallpapers are not supported by this utility, please try \"settimed\" instead")
+		}
+		if verbose {
+			fmt.Printf("Using %s from %s\n", gw.Name, gw.Path)
+		}
+		return gw.SetWallpaper(false, verbose)
+	}
+
 	if len(wallpapers) == 0 && (len(gnomeTimedWallpapers) > 0 || len(simpleTimedWallpapers) > 0) {
 		return errors.New("timed wallpapers are not supported by this utility, please try \"settimed\" instead")
 	}
@@ -81,7 +102,8 @@ func setWallpaperCollectionAction(c *cli.Context) error {
 		return fmt.Errorf("no such collection: %s", collectionName)
 	}
 
-	return SelectAndSetWallpaper(wallpapers)
+	// Use the light variant, if the collection also has a dark variant
+	return SelectAndSetWallpaper(wallutils.Variant(wallpapers, false))
 }
 
 func main() {
@@ -104,9 +126,26 @@ func main() {
 			Name:  "verbose, v",
 			Usage: "verbose output",
 		},
+		cli.StringFlag{
+			Name:  "backend",
+			Usage: "use the given backend, like \"Feh\" or \"Gnome3\", instead of detecting one (see lsbackends)",
+		},
+		cli.BoolFlag{
+			Name:  "rescan",
+			Usage: "read all wallpapers again, instead of using the wallpaper index",
+		},
 	}
 
-	app.Action = setWallpaperCollectionAction
+	app.Action = func(c *cli.Context) error {
+		if err := setWallpaperCollectionAction(c); err != nil {
+			return err
+		}
+		// Some backends only show the new wallpaper after being restarted
+		if hint := wallutils.RestartHint(); hint != "" {
+			fmt.Fprintln(os.Stderr, hint)
+		}
+		return nil
+	}
 	if err := app.Run(os.Args); err != nil {
 		wallutils.Quit(err)
 	}
@@ -6,6 +6,7 @@ import (
 	"math/rand"
 	"os"
 	"path/filepath"
+	"strings"
 	"time"
 
 	"github.com/urfave/cli"
@@ -17,6 +18,11 @@ func init() {
 }
 
 func setRandomWallpaperAction(c *cli.Context) error {
+	// Use the given backend, if one was given
+	if err := wallutils.SetBackend(c.String("backend")); err != nil {
+		return err
+	}
+
 	if c.NArg() == 0 {
 		return errors.New("please specify a directory to choose wallpapers from")
 	}
@@ -40,7 +46,7 @@ func setRandomWallpaperAction(c *cli.Context) error {
 			fmt.Printf("Searching %s recursively: ", dir)
 		}
 		// onlyLarge means >= 640x480
-		matches, err = wallutils.FindImagesAt(dir, []string{".png", ".jpg", ".jpeg"}, onlyLarge)
+		matches, err = wallutils.FindImagesAt(dir, wallutils.ImageExtensions, onlyLarge)
 		if err != nil {
 			fmt.Printf("error: %v\n", err)
 			return err
@@ -51,27 +57,19 @@ func setRandomWallpaperAction(c *cli.Context) error {
 
 	} else {
 
-		pngMatches, err := filepath.Glob(filepath.Join(dir, "/*.png"))
+		entries, err := os.ReadDir(dir)
 		if err != nil {
 			return err
 		}
-		matches = append(matches, pngMatches...)
-
-		jpgMatches, err := filepath.Glob(filepath.Join(dir, "/*.jpg"))
-		if err != nil {
-			return err
+		for _, entry := range entries {
+			if !entry.IsDir() && wallutils.IsImage(entry.Name()) {
+				matches = append(matches, filepath.Join(dir, entry.Name()))
+			}
 		}
-		matches = append(matches, jpgMatches...)
-
-		jpegMatches, err := filepath.Glob(filepath.Join(dir, "/*.jpeg"))
-		if err != nil {
-			return err
-		}
-		matches = append(matches, jpegMatches...)
 	}
 
 	if len(matches) == 0 {
-		return fmt.Errorf("found no .png, .jpg or .jpeg files in %s", dir)
+		return fmt.Errorf("found no images in %s (%s)", dir, strings.Join(wallutils.ImageExtensions, ", "))
 	}
 
 	imageFilename := matches[rand.Int()%len(matches)]
@@ -82,7 +80,7 @@ func setRandomWallpaperAction(c *cli.Context) error {
 	if verbose {
 		fmt.Printf("Setting background image to: %s\n", imageFilename)
 	}
-	return wallutils.SetWallpaperCustom(imageFilename, mode, verbose)
+	return wallutils.SetWallpaperCustomAndSave(imageFilename, mode, verbose)
 }
 
 func main() {
@@ -118,9 +116,22 @@ func main() {
 			Value: "stretch", // the default value
 			Usage: "wallpaper mode (stretch | center | tile | scale) \n\t+ modes specific to the currently running DE/WM",
 		},
+		cli.StringFlag{
+			Name:  "backend",
+			Usage: "use the given backend, like \"Feh\" or \"Gnome3\", instead of detecting one (see lsbackends)",
+		},
 	}
 
-	app.Action = setRandomWallpaperAction
+	app.Action = func(c *cli.Context) error {
+		if err := setRandomWallpaperAction(c); err != nil {
+			return err
+		}
+		// Some backends only show the new wallpaper after being restarted
+		if hint := wallutils.RestartHint(); hint != "" {
+			fmt.Fprintln(os.Stderr, hint)
+		}
+		return nil
+	}
 	if err := app.Run(os.Args); err != nil {
 		wallutils.Quit(err)
 	}
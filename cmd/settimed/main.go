@@ -4,11 +4,15 @@ import (
 	"errors"
 	"fmt"
 	"os"
+	"os/signal"
 	"path/filepath"
 	"strings"
+	"syscall"
+	"time"
 
 	"github.com/urfave/cli"
 	"github.com/xyproto/wallutils"
+	"github.com/xyproto/wallutils/pkg/event"
 	"github.com/xyproto/wallutils/pkg/gnometimed"
 	"github.com/xyproto/wallutils/pkg/simpletimed"
 )
@@ -18,44 +22,51 @@ func exists(path string) bool {
 	return err == nil
 }
 
-// SetTimedWallpaper launches an event loop for switching the timed wallpaper
-func SetTimedWallpaper(collectionOrFilename string, verbose bool, mode string, tempImageFilename string) error {
+// preferDark checks if the current color scheme is dark, for choosing the
+// dark variants of Simple Timed Wallpapers
+func preferDark() bool {
+	cs, err := wallutils.CurrentColorScheme()
+	return err == nil && cs.Dark()
+}
+
+// watchColorScheme sets the wallpaper again every time the color scheme
+// changes between light and dark, instead of waiting for the next event
+func watchColorScheme(stw *simpletimed.Wallpaper, verbose bool, setWallpaper func(string) error, tempImageFilename string) {
+	dark := stw.UseDark()
+	err := wallutils.WatchColorScheme(func(cs wallutils.ColorScheme) error {
+		if cs.Dark() == dark {
+			return nil
+		}
+		dark = cs.Dark()
+		if verbose {
+			fmt.Printf("The color scheme is %s, setting the wallpaper again\n", cs)
+		}
+		if err := stw.Refresh(verbose, setWallpaper, tempImageFilename); err != nil {
+			fmt.Fprintln(os.Stderr, "Error:", err)
+		}
+		return nil
+	}, verbose)
+	if err != nil && verbose {
+		fmt.Fprintf(os.Stderr, "could not watch the color scheme: %v\n", err)
+	}
+}
+
+// findTimedWallpaper finds a Simple Timed Wallpaper or a GNOME timed
+// wallpaper, given a filename or a name. One of the returned wallpapers is nil.
+// Set rescan to true to read all wallpapers again, instead of using the wallpaper index.
+func findTimedWallpaper(collectionOrFilename string, verbose, rescan bool) (*simpletimed.Wallpaper, *gnometimed.Wallpaper, error) {
 	// Check if it is a timed wallpaper filename
 	if strings.Contains(collectionOrFilename, ".") && exists(collectionOrFilename) {
 		filename := collectionOrFilename
 		switch filepath.Ext(filename) {
 		case ".stw":
 			stw, err := simpletimed.ParseSTW(filename)
-			if err != nil {
-				return err
-			}
-			if verbose {
-				fmt.Printf("Launching event loop for: %s\n", stw.Path)
-			}
-			// Start endless event loop
-			if err := stw.EventLoop(verbose,
-				func(path string) error {
-					return wallutils.SetWallpaperCustom(path, mode, verbose)
-				}, tempImageFilename); err != nil {
-				return err
-			}
+			return stw, nil, err
 		case ".xml":
 			gtw, err := gnometimed.ParseXML(filename)
-			if err != nil {
-				return err
-			}
-			if verbose {
-				fmt.Printf("Launching event loop for: %s\n", gtw.Path)
-			}
-			// Start endless event loop
-			if err := gtw.EventLoop(verbose,
-				func(path string) error {
-					return wallutils.SetWallpaperCustom(path, mode, verbose)
-				}, tempImageFilename); err != nil {
-				return err
-			}
+			return nil, gtw, err
 		default:
-			return fmt.Errorf("unrecognized file extension: %s", filepath.Ext(filename))
+			return nil, nil, fmt.Errorf("unrecognized file extension: %s", filepath.Ext(filename))
 		}
 	}
 
@@ -63,12 +74,16 @@ func SetTimedWallpaper(collectionOrFilename string, verbose bool, mode string, t
 		fmt.Printf("Setting timed wallpaper: %s\n", collectionOrFilename)
 		fmt.Println("Searching for wallpapers...")
 	}
-	searchResults, err := wallutils.FindWallpapers()
+	findWallpapers := wallutils.FindWallpapers
+	if rescan {
+		findWallpapers = wallutils.RescanWallpapers
+	}
+	searchResults, err := findWallpapers()
 	if err != nil {
-		return err
+		return nil, nil, err
 	}
 	if searchResults.NoTimedWallpapers() {
-		return errors.New("could not find any timed wallpapers on the system")
+		return nil, nil, errors.New("could not find any timed wallpapers on the system")
 	}
 	if verbose {
 		fmt.Println("Filtering wallpapers by name...")
@@ -79,38 +94,227 @@ func SetTimedWallpaper(collectionOrFilename string, verbose bool, mode string, t
 	// gnomeTimedWallpapers and simpleTimedWallpapers have now been filtered so that they only contain elements with matching collection names
 
 	if (len(gnomeTimedWallpapers) == 0) && (len(simpleTimedWallpapers) == 0) {
-		return fmt.Errorf("could not find timed wallpaper: %s", collectionOrFilename)
+		// GNOME background-properties files may refer to a timed wallpaper, by a friendlier name
+		for _, gw := range searchResults.GnomeWallpapersByName(collectionOrFilename) {
+			if gw.Timed(false) && exists(gw.Image(false)) {
+				return findTimedWallpaper(gw.Image(false), verbose, false)
+			}
+		}
+		return nil, nil, fmt.Errorf("could not find timed wallpaper: %s", collectionOrFilename)
 	}
 
 	if (len(gnomeTimedWallpapers) > 1) || (len(simpleTimedWallpapers) > 1) {
-		return errors.New("found several timed backgrounds with the same name")
+		return nil, nil, errors.New("found several timed backgrounds with the same name")
 	}
 
 	if len(simpleTimedWallpapers) == 1 {
-		stw := simpleTimedWallpapers[0]
+		return simpleTimedWallpapers[0], nil, nil
+	}
+	return nil, gnomeTimedWallpapers[0], nil
+}
+
+// fadeFromFlags returns how transitions should be crossfaded, as configured
+// by the --interval and --steps flags. The blended images are cached in a
+// directory per timed wallpaper in ~/.cache/wallutils/transitions.
+func fadeFromFlags(c *cli.Context) (*simpletimed.Fade, error) {
+	if c.Duration("interval") < 0 {
+		return nil, errors.New("--interval can not be negative")
+	}
+	if c.Int("steps") < 0 {
+		return nil, errors.New("--steps can not be negative")
+	}
+	fade := &simpletimed.Fade{
+		Interval: c.Duration("interval"),
+		Steps:    c.Int("steps"),
+	}
+	if cacheDir, err := os.UserCacheDir(); err == nil {
+		fade.CacheDir = filepath.Join(cacheDir, "wallutils", "transitions")
+	}
+	return fade, nil
+}
+
+// SetTimedWallpaper launches an event loop for switching the timed wallpaper.
+// Set rescan to true to read all wallpapers again, instead of using the wallpaper index.
+func SetTimedWallpaper(collectionOrFilename string, verbose bool, mode string, tempImageFilename string, rescan bool, fade *simpletimed.Fade) error {
+	stw, gtw, err := findTimedWallpaper(collectionOrFilename, verbose, rescan)
+	if err != nil {
+		return err
+	}
+	setWallpaper := func(path string) error {
+		return wallutils.SetWallpaperCustom(path, mode, verbose)
+	}
+	// Start endless event loop
+	if stw != nil {
 		if verbose {
-			fmt.Printf("Using: %s\n", stw.Path)
+			fmt.Printf("Launching event loop for: %s\n", stw.Path)
 		}
-		// Start endless event loop
-		if err := stw.EventLoop(verbose, func(path string) error { return wallutils.SetWallpaperCustom(path, mode, verbose) }, tempImageFilename); err != nil {
-			return err
+		stw.PreferDark = preferDark
+		stw.Fade = pruning(fade.ForWallpaper(stw.Path))
+		if stw.FollowsColorScheme() {
+			go watchColorScheme(stw, verbose, setWallpaper, tempImageFilename)
 		}
-	} else if len(gnomeTimedWallpapers) == 1 {
-		gtw := gnomeTimedWallpapers[0]
-		if verbose {
-			fmt.Printf("Using: %s\n", gtw.Path)
+		return stw.EventLoop(verbose, setWallpaper, tempImageFilename)
+	}
+	if verbose {
+		fmt.Printf("Launching event loop for: %s\n", gtw.Path)
+	}
+	gtw.Fade = pruning(fade.ForWallpaper(gtw.Path))
+	return gtw.EventLoop(verbose, setWallpaper, tempImageFilename)
+}
+
+// pruning returns the given fade, where the blended images that are not used
+// are removed from the cache directory. This is not done for simulations,
+// since the timed wallpaper may also be running with other settings.
+func pruning(fade *simpletimed.Fade) *simpletimed.Fade {
+	if fade != nil {
+		fade.Prune = true
+	}
+	return fade
+}
+
+// simulation is how a timed wallpaper should be simulated
+type simulation struct {
+	start     time.Time         // the simulated time to start at
+	duration  time.Duration     // how much time to simulate
+	speed     float64           // simulated seconds per second, or 0 for as fast as possible
+	framesDir string            // write frames to this directory instead of setting the wallpaper, if not empty
+	mode      string            // the wallpaper mode, when setting the wallpaper
+	fade      *simpletimed.Fade // how transitions are crossfaded
+}
+
+// describe returns a line for the timeline, for when the given image is set at the given time
+func describe(schedule *simpletimed.Wallpaper, at time.Time, imageFilename string) string {
+	e, ratio, err := schedule.Progress(at)
+	if err != nil {
+		return fmt.Sprintf("%-10s       %s", "?", imageFilename)
+	}
+	if t, ok := e.(*simpletimed.Transition); ok {
+		from, to := t.Filenames(schedule.UseDark())
+		kind := "transition"
+		if imageFilename != from {
+			kind = "crossfade"
+			if t.Type != "overlay" {
+				kind = t.Type
+			}
+		}
+		return fmt.Sprintf("%-10s %3d%%  %s .. %s", kind, int(ratio*100), from, to)
+	}
+	return fmt.Sprintf("%-10s       %s", "static", imageFilename)
+}
+
+// copyFile copies a file
+func copyFile(src, dst string) error {
+	data, err := os.ReadFile(src)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(dst, data, 0o644)
+}
+
+// restorer returns a function that sets the current wallpaper again, or nil
+// if the current wallpaper is not known. The wallpaper that was last set with
+// wallutils is set again the same way, if it is the current wallpaper.
+func restorer() func() error {
+	current, err := wallutils.CurrentWallpaper()
+	if s, stateErr := wallutils.LoadState(); stateErr == nil && (err != nil || current == s.Image) {
+		return func() error { return s.Restore(false) }
+	}
+	if err != nil {
+		return nil
+	}
+	return func() error { return wallutils.SetWallpaperCustom(current, "", false) }
+}
+
+// Simulate runs the given timed wallpaper on a virtual clock, and prints a
+// timeline of which image is set when. One of stw and gtw should be nil.
+// Unless frames are written to a directory, the current wallpaper is set
+// again when the simulation is done or interrupted.
+func (sim *simulation) Simulate(stw *simpletimed.Wallpaper, gtw *gnometimed.Wallpaper, tempImageFilename string) error {
+	clock := event.NewFakeClock(sim.start)
+
+	if sim.framesDir == "" {
+		if restore := restorer(); restore != nil {
+			defer func() {
+				if err := restore(); err != nil {
+					fmt.Fprintf(os.Stderr, "could not set the previous wallpaper again: %v\n", err)
+				}
+			}()
+		} else {
+			fmt.Fprintln(os.Stderr, "The current wallpaper is not known, and will not be set again after the simulation.")
 		}
-		// Start endless event loop
-		if err := gtw.EventLoop(verbose, func(path string) error { return wallutils.SetWallpaperCustom(path, mode, verbose) }, tempImageFilename); err != nil {
+	}
+
+	// The schedule is used for describing the events in the timeline
+	schedule := stw
+	if gtw != nil {
+		var err error
+		if schedule, err = gnometimed.GnomeToSimple(gtw); err != nil {
 			return err
 		}
+	} else {
+		stw.PreferDark = preferDark
 	}
+	fade := sim.fade.ForWallpaper(schedule.Path)
+	schedule.Fade = fade
 
-	// this should never be reached
+	frame := 0
+	setWallpaper := func(path string) error {
+		now := clock.Now()
+		fmt.Printf("%s  %s\n", now.Format("Mon 15:04"), describe(schedule, now, path))
+		if sim.framesDir == "" {
+			return wallutils.SetWallpaperCustom(path, sim.mode, false)
+		}
+		// Copy the image, since the crossfaded image is overwritten by the next crossfade
+		frame++
+		return copyFile(path, filepath.Join(sim.framesDir, fmt.Sprintf("%04d-%s%s", frame, now.Format("Mon-1504"), filepath.Ext(path))))
+	}
+
+	var (
+		sys *event.EventSys
+		err error
+	)
+	if stw != nil {
+		stw.Clock = clock
+		stw.Fade = fade
+		sys, err = stw.EventSystem(false, setWallpaper, tempImageFilename)
+	} else {
+		gtw.Clock = clock
+		gtw.Fade = fade
+		sys, err = gtw.EventSystem(false, setWallpaper, tempImageFilename)
+	}
+	if err != nil {
+		return err
+	}
+
+	// Stop the simulation if ctrl-c is pressed, so that the wallpaper is set again
+	interrupted := make(chan os.Signal, 1)
+	signal.Notify(interrupted, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(interrupted)
+
+	// The events happen at whole minutes
+	const step = time.Minute
+	for simulated := time.Duration(0); simulated < sim.duration; simulated += step {
+		clock.Add(step)
+		sys.Step(false)
+		wait := time.Duration(0)
+		if sim.speed > 0 {
+			wait = time.Duration(float64(step) / sim.speed)
+		}
+		select {
+		case <-interrupted:
+			return nil
+		case <-time.After(wait):
+		}
+	}
 	return nil
 }
 
 func setTimedWallpaperAction(c *cli.Context) error {
+	// Use the given backend, if one was given
+	if err := wallutils.SetBackend(c.String("backend")); err != nil {
+		return err
+	}
+
 	if c.NArg() == 0 {
 		return errors.New("please provide a timed wallpaper filename as the first argument")
 	}
@@ -122,23 +326,76 @@ func setTimedWallpaperAction(c *cli.Context) error {
 		// Be verbose unless a silent flag (-s) has been given
 		verbose = !c.IsSet("silent")
 		mode    = c.String("mode")
+		rescan  = c.IsSet("rescan")
 
 		tempImageFilename = "/tmp/_settimed.jpg"
 	)
 
-	err := SetTimedWallpaper(collectionOrFilename, verbose, mode, tempImageFilename)
+	if c.IsSet("frames") && !c.IsSet("simulate") {
+		return errors.New("--frames can only be used together with --simulate")
+	}
+	fade, err := fadeFromFlags(c)
+	if err != nil {
+		return err
+	}
+	if c.IsSet("simulate") {
+		return simulateAction(c, collectionOrFilename, mode, rescan, fade)
+	}
+
+	err = SetTimedWallpaper(collectionOrFilename, verbose, mode, tempImageFilename, rescan, fade)
 	if err != nil {
 		// Output the capitalized error message
 		msg := err.Error()
 		if verbose {
 			fmt.Printf("%s%s", strings.ToUpper(string(msg[0])), msg[1:])
 		}
-		// Try again, but with the "-timed" suffix
-		err = SetTimedWallpaper(collectionOrFilename+"-timed", verbose, mode, tempImageFilename)
+		// Try again, but with the "-timed" suffix. The wallpaper index is up to date by now.
+		err = SetTimedWallpaper(collectionOrFilename+"-timed", verbose, mode, tempImageFilename, false, fade)
 	}
 	return err
 }
 
+// simulateAction runs the given timed wallpaper on a virtual clock, as
+// configured by the --hours, --speed, --start and --frames flags. A temporary
+// image file of its own is used, so that a running settimed is not disturbed.
+func simulateAction(c *cli.Context, collectionOrFilename, mode string, rescan bool, fade *simpletimed.Fade) error {
+	stw, gtw, err := findTimedWallpaper(collectionOrFilename, false, rescan)
+	if err != nil {
+		// Try again, but with the "-timed" suffix. The wallpaper index is up to date by now.
+		var err2 error
+		if stw, gtw, err2 = findTimedWallpaper(collectionOrFilename+"-timed", false, false); err2 != nil {
+			return err
+		}
+	}
+	sim := &simulation{
+		start:     time.Now().Truncate(time.Minute),
+		duration:  time.Duration(c.Uint("hours")) * time.Hour,
+		speed:     c.Float64("speed"),
+		framesDir: c.String("frames"),
+		mode:      mode,
+		fade:      fade,
+	}
+	if c.IsSet("start") {
+		at, err := time.Parse("15:04", c.String("start"))
+		if err != nil {
+			return fmt.Errorf("invalid start time, expected HH:MM: %s", c.String("start"))
+		}
+		sim.start = time.Date(sim.start.Year(), sim.start.Month(), sim.start.Day(), at.Hour(), at.Minute(), 0, 0, time.Local)
+	}
+	if sim.framesDir != "" {
+		if err := os.MkdirAll(sim.framesDir, 0o755); err != nil {
+			return err
+		}
+	}
+	tempImageFile, err := os.CreateTemp("", "settimed-simulate-*.jpg")
+	if err != nil {
+		return err
+	}
+	tempImageFile.Close()
+	defer os.Remove(tempImageFile.Name())
+	return sim.Simulate(stw, gtw, tempImageFile.Name())
+}
+
 func main() {
 	app := cli.NewApp()
 
@@ -164,6 +421,44 @@ func main() {
 			Value: "stretch", // the default value
 			Usage: "wallpaper mode (stretch | center | tile | scale) \n\t+ modes specific to the currently running DE/WM",
 		},
+		cli.StringFlag{
+			Name:  "backend",
+			Usage: "use the given backend, like \"Feh\" or \"Gnome3\", instead of detecting one (see lsbackends)",
+		},
+		cli.BoolFlag{
+			Name:  "rescan",
+			Usage: "read all wallpapers again, instead of using the wallpaper index",
+		},
+		cli.DurationFlag{
+			Name:  "interval",
+			Usage: "set a new blended image this often during transitions, like \"1m\" or \"5m\"",
+		},
+		cli.IntFlag{
+			Name:  "steps",
+			Usage: "the number of images each transition is shown as, if no --interval is given (the default is 2)",
+		},
+		cli.BoolFlag{
+			Name:  "simulate",
+			Usage: "run the timed wallpaper on a virtual clock, and output a timeline of which image is set when",
+		},
+		cli.UintFlag{
+			Name:  "hours",
+			Value: 48,
+			Usage: "how many hours to simulate, for use together with --simulate",
+		},
+		cli.Float64Flag{
+			Name:  "speed",
+			Value: 3600,
+			Usage: "how many simulated seconds pass per second, for use together with --simulate (0 is as fast as possible)",
+		},
+		cli.StringFlag{
+			Name:  "start",
+			Usage: "the time of day to start the simulation at, like \"06:00\" (the default is now)",
+		},
+		cli.StringFlag{
+			Name:  "frames",
+			Usage: "do not set the wallpaper when simulating, but write every image to the given directory, as numbered frames",
+		},
 	}
 
 	app.Action = setTimedWallpaperAction
@@ -50,15 +50,85 @@ func download(url, filename string, verbose, redownload bool) error {
 }
 
 func setWallpaperAction(c *cli.Context) error {
-	if c.NArg() == 0 {
-		return errors.New("please specify an image filename or URL")
+	// Use the given backend, if one was given
+	if err := wallutils.SetBackend(c.String("backend")); err != nil {
+		return err
+	}
+
+	// Set the wallpaper that was set the last time, if requested
+	if c.IsSet("restore") {
+		if err := wallutils.RestoreWallpaper(c.IsSet("verbose")); err != nil {
+			return fmt.Errorf("could not restore wallpaper: %w", err)
+		}
+		return nil
 	}
-	imageFilename := c.Args().Get(0)
 
 	// Retrieve flags from the context
 	verbose := c.IsSet("verbose")
+
+	// Use a solid color, a gradient or a bitmap instead of an image, if requested
+	switch {
+	case c.IsSet("color"):
+		col, err := wallutils.ParseColor(c.String("color"))
+		if err != nil {
+			return err
+		}
+		if err := wallutils.SetWallpaperColor(col, verbose); err != nil {
+			return fmt.Errorf("could not set wallpaper: %w", err)
+		}
+		return nil
+	case c.IsSet("gradient"):
+		if err := wallutils.SetWallpaperGradient(c.String("gradient"), c.Float64("angle"), verbose); err != nil {
+			return fmt.Errorf("could not set wallpaper: %w", err)
+		}
+		return nil
+	case c.IsSet("bitmap"):
+		fg, err := wallutils.ParseColor(c.String("fg"))
+		if err != nil {
+			return err
+		}
+		bg, err := wallutils.ParseColor(c.String("bg"))
+		if err != nil {
+			return err
+		}
+		if err := wallutils.SetWallpaperBitmap(c.String("bitmap"), fg, bg, verbose); err != nil {
+			return fmt.Errorf("could not set wallpaper: %w", err)
+		}
+		return nil
+	}
+
+	// Use one wallpaper for the light color scheme and one for the dark color scheme, if requested
+	if c.IsSet("light") || c.IsSet("dark") {
+		if !c.IsSet("light") || !c.IsSet("dark") {
+			return errors.New("please specify both --light and --dark")
+		}
+		lightFilename, err := filepath.Abs(c.String("light"))
+		if err != nil {
+			return err
+		}
+		darkFilename, err := filepath.Abs(c.String("dark"))
+		if err != nil {
+			return err
+		}
+		if c.IsSet("watch") {
+			if err := wallutils.WatchLightDark(lightFilename, darkFilename, c.String("mode"), verbose); err != nil {
+				return fmt.Errorf("could not set wallpaper: %w", err)
+			}
+			return nil
+		}
+		if err := wallutils.SetWallpaperLightDark(lightFilename, darkFilename, c.String("mode"), verbose); err != nil {
+			return fmt.Errorf("could not set wallpaper: %w", err)
+		}
+		return nil
+	}
+
+	if c.NArg() == 0 {
+		return errors.New("please specify an image filename or URL")
+	}
+	imageFilename := c.Args().Get(0)
 	mode := c.String("mode")
 	downloadDir := c.String("download")
+	output := c.String("output")
 
 	if !exists(downloadDir) {
 		// Last resort
@@ -88,9 +158,43 @@ func setWallpaperAction(c *cli.Context) error {
 		imageFilename = absImageFilename
 	}
 
+	// Span the image across all monitors, if requested
+	if c.IsSet("span") {
+		spanMode := "fill"
+		if c.IsSet("mode") {
+			spanMode = mode
+		}
+		if err := wallutils.SetWallpaperSpanned(imageFilename, c.Uint("bezel"), spanMode, verbose); err != nil {
+			return fmt.Errorf("could not span wallpaper: %w", err)
+		}
+		return nil
+	}
+
+	// Render the image for the exact monitor resolution first, if requested
+	if c.IsSet("render") {
+		if output != "" {
+			if err := wallutils.SetWallpaperRenderedForOutput(output, imageFilename, mode, verbose); err != nil {
+				return fmt.Errorf("could not set wallpaper for %s: %w", output, err)
+			}
+			return nil
+		}
+		if err := wallutils.SetWallpaperRendered(imageFilename, mode, verbose); err != nil {
+			return fmt.Errorf("could not set wallpaper: %w", err)
+		}
+		return nil
+	}
+
+	// Set the desktop wallpaper for a single output, if one was given
+	if output != "" {
+		if err := wallutils.SetWallpaperPerMonitorAndSave(map[string]string{output: imageFilename}, mode, verbose); err != nil {
+			return fmt.Errorf("could not set wallpaper for %s: %w", output, err)
+		}
+		return nil
+	}
+
 	// Set the desktop wallpaper
-	if err := wallutils.SetWallpaperCustom(imageFilename, mode, verbose); err != nil {
-		return fmt.Errorf("could not set wallpaper: %s", err)
+	if err := wallutils.SetWallpaperCustomAndSave(imageFilename, mode, verbose); err != nil {
+		return fmt.Errorf("could not set wallpaper: %w", err)
 	}
 	return nil
 }
@@ -148,7 +252,7 @@ func main() {
 
 	app.Name = "setwallpaper"
 	app.Usage = "set the desktop wallpaper"
-	app.UsageText = "setwallpaper [options] [path or URL to JPEG or PNG image]"
+	app.UsageText = "setwallpaper [options] [path or URL to an image]"
 
 	app.Version = wallutils.VersionString
 	app.HideHelp = true
@@ -168,6 +272,68 @@ func main() {
 			Value: "stretch", // the default value
 			Usage: "wallpaper mode (stretch | center | tile | scale) \n\t+ modes specific to the currently running DE/WM",
 		},
+		cli.StringFlag{
+			Name:  "output, o",
+			Usage: "only set the wallpaper for the given output (like DP-1)",
+		},
+		cli.BoolFlag{
+			Name:  "render, r",
+			Usage: "scale, crop or add borders to the image for each monitor first,\n\tso that the mode (fill | fit | stretch | center | tile) looks the same for all backends",
+		},
+		cli.BoolFlag{
+			Name:  "span, s",
+			Usage: "span the image across all monitors, by setting one part of the image per monitor",
+		},
+		cli.UintFlag{
+			Name:  "bezel, b",
+			Usage: "the gap between two monitors, in pixels, for use together with --span",
+		},
+		cli.StringFlag{
+			Name:  "color",
+			Usage: "use a solid color as the wallpaper, like \"#223344\" or \"navy\"",
+		},
+		cli.StringFlag{
+			Name:  "gradient",
+			Usage: "use a linear gradient between comma separated colors as the wallpaper, like \"#000,#336\"",
+		},
+		cli.Float64Flag{
+			Name:  "angle",
+			Usage: "the direction of the gradient, in degrees, where 0 is left to right and 90 is top to bottom",
+		},
+		cli.StringFlag{
+			Name:  "bitmap",
+			Usage: "use an X11 bitmap (XBM) file as the wallpaper, repeated across the screen, like xsetroot -bitmap",
+		},
+		cli.StringFlag{
+			Name:  "fg",
+			Value: "black",
+			Usage: "the foreground color, for use together with --bitmap",
+		},
+		cli.StringFlag{
+			Name:  "bg",
+			Value: "white",
+			Usage: "the background color, for use together with --bitmap",
+		},
+		cli.StringFlag{
+			Name:  "light",
+			Usage: "the image to use with a light color scheme, for use together with --dark",
+		},
+		cli.StringFlag{
+			Name:  "dark",
+			Usage: "the image to use with a dark color scheme, for use together with --light",
+		},
+		cli.BoolFlag{
+			Name:  "watch, w",
+			Usage: "keep running and change between the --light and --dark images when the color scheme changes,\n\tfor desktops that can not do this by themselves",
+		},
+		cli.BoolFlag{
+			Name:  "restore",
+			Usage: "set the wallpaper that was last set with wallutils again, for use at login",
+		},
+		cli.StringFlag{
+			Name:  "backend",
+			Usage: "use the given backend, like \"Feh\" or \"Gnome3\", instead of detecting one (see lsbackends)",
+		},
 		cli.StringFlag{
 			Name:  "download, d",
 			Value: downloadDirectory(), // the default value
@@ -175,7 +341,16 @@ func main() {
 		},
 	}
 
-	app.Action = setWallpaperAction
+	app.Action = func(c *cli.Context) error {
+		if err := setWallpaperAction(c); err != nil {
+			return err
+		}
+		// Some backends only show the new wallpaper after being restarted
+		if hint := wallutils.RestartHint(); hint != "" {
+			fmt.Fprintln(os.Stderr, hint)
+		}
+		return nil
+	}
 	if err := app.Run(os.Args); err != nil {
 		wallutils.Quit(err)
 	}
@@ -1,23 +1,164 @@
 package main
 
 import (
+	"errors"
 	"fmt"
+	"image"
+	"image/draw"
+	"math"
 	"os"
+	"path/filepath"
 	"strings"
+	"time"
 
+	"github.com/anthonynsimon/bild/imgio"
+	"github.com/anthonynsimon/bild/transform"
 	"github.com/fatih/color"
 	"github.com/urfave/cli"
 	"github.com/xyproto/wallutils"
+	"github.com/xyproto/wallutils/pkg/gnometimed"
+	"github.com/xyproto/wallutils/pkg/simpletimed"
 )
 
+// spacing is the number of pixels between the images on a contact sheet
+const spacing = 4
+
 // Indent all lines with the given prefix.
 // Will trim the right side of the string for newlines before indenting.
 func Indent(s string, prefix string) string {
 	return prefix + strings.Replace(strings.TrimRight(s, "\n"), "\n", "\n"+prefix, -1)
 }
 
+// renderTimes returns the given number of evenly spaced times, through a day
+func renderTimes(count int) []time.Time {
+	now := time.Now()
+	midnight := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	times := make([]time.Time, count)
+	for i := range times {
+		times[i] = midnight.Add(time.Duration(i) * 24 * time.Hour / time.Duration(count))
+	}
+	return times
+}
+
+// encoderFor returns an image encoder that matches the file extension
+func encoderFor(filename string) imgio.Encoder {
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".jpg", ".jpeg":
+		return imgio.JPEGEncoder(95)
+	}
+	return imgio.PNGEncoder()
+}
+
+// writeFrames renders the timed wallpaper at the given times, and writes
+// the images to the given directory, as numbered frames
+func writeFrames(stw *simpletimed.Wallpaper, times []time.Time, dir string) error {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	for i, at := range times {
+		img, err := stw.Render(at)
+		if err != nil {
+			return err
+		}
+		filename := filepath.Join(dir, fmt.Sprintf("%04d-%s.png", i, at.Format("1504")))
+		if err := imgio.Save(filename, img, imgio.PNGEncoder()); err != nil {
+			return err
+		}
+	}
+	fmt.Printf("Wrote %d frames to %s\n", len(times), dir)
+	return nil
+}
+
+// writeContactSheet renders the timed wallpaper at the given times, and
+// writes one image where the rendered images are placed in a grid, left to
+// right and top to bottom, each scaled to the given width
+func writeContactSheet(stw *simpletimed.Wallpaper, times []time.Time, filename string, width, columns int) error {
+	if columns > len(times) {
+		columns = len(times)
+	}
+	rows := int(math.Ceil(float64(len(times)) / float64(columns)))
+	var (
+		sheet  *image.RGBA
+		height int
+	)
+	for i, at := range times {
+		img, err := stw.Render(at)
+		if err != nil {
+			return err
+		}
+		if sheet == nil {
+			// The size of the first image decides the size of all the images on the sheet
+			b := img.Bounds()
+			height = int(math.Round(float64(width) * float64(b.Dy()) / float64(b.Dx())))
+			sheet = image.NewRGBA(image.Rect(0, 0, columns*(width+spacing)+spacing, rows*(height+spacing)+spacing))
+			draw.Draw(sheet, sheet.Bounds(), image.Black, image.Point{}, draw.Src)
+		}
+		x := spacing + (i%columns)*(width+spacing)
+		y := spacing + (i/columns)*(height+spacing)
+		thumbnail := transform.Resize(img, width, height, transform.Linear)
+		draw.Draw(sheet, image.Rect(x, y, x+width, y+height), thumbnail, image.Point{}, draw.Src)
+	}
+	if err := imgio.Save(filename, sheet, encoderFor(filename)); err != nil {
+		return err
+	}
+	var clockTimes []string
+	for _, at := range times {
+		clockTimes = append(clockTimes, at.Format("15:04"))
+	}
+	fmt.Printf("Wrote a contact sheet to %s, with the wallpaper at %s\n", filename, strings.Join(clockTimes, ", "))
+	return nil
+}
+
+// renderAction renders the timed wallpaper with the given name, as it is
+// shown throughout the day, as configured by the --render, --frames,
+// --count, --width, --columns, --dark, --interval and --steps flags
+func renderAction(c *cli.Context, searchResults *wallutils.SearchResults, name string) error {
+	if name == "" {
+		return errors.New("the name of a timed wallpaper must be given when rendering")
+	}
+	count := c.Int("count")
+	if count < 1 {
+		return errors.New("--count must be at least 1")
+	}
+	if c.Int("width") < 1 || c.Int("columns") < 1 {
+		return errors.New("--width and --columns must be at least 1")
+	}
+	if c.Duration("interval") < 0 || c.Int("steps") < 0 {
+		return errors.New("--interval and --steps can not be negative")
+	}
+	var stw *simpletimed.Wallpaper
+	if stws := searchResults.SimpleTimedWallpapersByName(name); len(stws) > 0 {
+		stw = stws[0]
+	} else if gtws := searchResults.GnomeTimedWallpapersByName(name); len(gtws) > 0 {
+		var err error
+		if stw, err = gnometimed.GnomeToSimple(gtws[0]); err != nil {
+			return err
+		}
+	} else {
+		return fmt.Errorf("could not find timed wallpaper: %s", name)
+	}
+	if c.IsSet("dark") {
+		stw.Theme = "dark"
+	}
+	stw.Fade = &simpletimed.Fade{Interval: c.Duration("interval"), Steps: c.Int("steps")}
+	times := renderTimes(count)
+	if c.IsSet("frames") {
+		if err := writeFrames(stw, times, c.String("frames")); err != nil {
+			return err
+		}
+	}
+	if c.IsSet("render") {
+		return writeContactSheet(stw, times, c.String("render"), c.Int("width"), c.Int("columns"))
+	}
+	return nil
+}
+
 func timedInfoAction(c *cli.Context) error {
-	searchResults, err := wallutils.FindWallpapers()
+	findWallpapers := wallutils.FindWallpapers
+	if c.IsSet("rescan") {
+		findWallpapers = wallutils.RescanWallpapers
+	}
+	searchResults, err := findWallpapers()
 	if err != nil {
 		return err
 	}
@@ -31,6 +172,10 @@ func timedInfoAction(c *cli.Context) error {
 		nameFilter = c.Args().Get(0)
 	}
 
+	if c.IsSet("render") || c.IsSet("frames") {
+		return renderAction(c, searchResults, nameFilter)
+	}
+
 	first := true
 	for _, stw := range searchResults.SimpleTimedWallpapers() {
 		if nameFilter == "" || stw.Name == nameFilter {
@@ -76,6 +221,48 @@ func main() {
 		Usage: "output version information",
 	}
 
+	app.Flags = []cli.Flag{
+		cli.BoolFlag{
+			Name:  "rescan",
+			Usage: "read all wallpapers again, instead of using the wallpaper index",
+		},
+		cli.StringFlag{
+			Name:  "render",
+			Usage: "write a contact sheet with the wallpaper at evenly spaced times through the day to the given image file",
+		},
+		cli.StringFlag{
+			Name:  "frames",
+			Usage: "write the wallpaper at evenly spaced times through the day to the given directory, as numbered frames",
+		},
+		cli.IntFlag{
+			Name:  "count, n",
+			Value: 24,
+			Usage: "the number of times through the day to render",
+		},
+		cli.IntFlag{
+			Name:  "width",
+			Value: 320,
+			Usage: "the width of each image on the contact sheet",
+		},
+		cli.IntFlag{
+			Name:  "columns",
+			Value: 6,
+			Usage: "the number of images per row on the contact sheet",
+		},
+		cli.BoolFlag{
+			Name:  "dark",
+			Usage: "render the dark variants of the images, if there are any",
+		},
+		cli.DurationFlag{
+			Name:  "interval",
+			Usage: "render transitions as if settimed is given the same --interval",
+		},
+		cli.IntFlag{
+			Name:  "steps",
+			Usage: "render transitions as if settimed is given the same --steps",
+		},
+	}
+
 	app.Action = timedInfoAction
 	if err := app.Run(os.Args); err != nil {
 		wallutils.Quit(err)
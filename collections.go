@@ -1,13 +1,14 @@
 package wallutils
 
 import (
-	"image/jpeg"
-	"image/png"
+	_ "image/jpeg" // register the JPEG image format
+	_ "image/png"  // register the PNG image format
 	"os"
 	"path/filepath"
 	"runtime"
 	"sort"
 	"sync"
+	"sync/atomic"
 
 	"github.com/stretchr/powerwalk"
 	"github.com/xyproto/wallutils/pkg/gnometimed"
@@ -20,7 +21,8 @@ const (
 	minimumHeight = 480
 )
 
-// DefaultWallpaperDirectories lists the default locations to look for wallpapers
+// DefaultWallpaperDirectories lists the default system-wide locations to look for wallpapers.
+// See WallpaperDirectories for all the directories that are searched.
 var DefaultWallpaperDirectories = []string{
 	"/usr/share/pixmaps",
 	"/usr/share/wallpapers",
@@ -36,12 +38,18 @@ var DefaultWallpaperDirectories = []string{
 // * gnome wallpapers (contains a GNOME-compatible XML file)
 // * sime timed wallpapers (contains a .stw file)
 type SearchResults struct {
-	wallpapers                  sync.Map                 // stores the full path -> *Wallpaper struct, for png + jpeg files
+	wallpapers                  sync.Map                 // stores the full path -> *Wallpaper struct, for image files
 	gnomeWallpapers             sync.Map                 // stores the full path -> *gnometimed.Wallpaper struct, for xml files
 	simpleTimedWallpapers       sync.Map                 // stores the full path -> *simpletimed.Wallpaper struct, for stw files
+	packages                    sync.Map                 // stores the full path -> *WallpaperPackage struct, for KDE Plasma metadata files
+	gnomeProperties             sync.Map                 // stores the full path -> []*GnomeWallpaper slice, for GNOME background-properties files
 	sortedWallpapers            []*Wallpaper             // holds sorted wallpapers
 	sortedGnomeTimedWallpapers  []*gnometimed.Wallpaper  // holds sorted Gnome Timed Wallpapers
 	sortedSimpleTimedWallpapers []*simpletimed.Wallpaper // holds sorted Simple Timed Wallpapers
+	sortedGnomeWallpapers       []*GnomeWallpaper        // holds sorted wallpapers from GNOME background-properties files
+	index                       map[string]*indexEntry   // the index from the previous search, if any
+	indexed                     sync.Map                 // stores the full path -> *indexEntry struct, for the next search
+	indexChanged                atomic.Bool              // files were read that were not in the index
 }
 
 // Find the number of available logical CPUs
@@ -56,16 +64,17 @@ func NewSearchResults() *SearchResults {
 		sortedWallpapers:            []*Wallpaper{},
 		sortedGnomeTimedWallpapers:  []*gnometimed.Wallpaper{},
 		sortedSimpleTimedWallpapers: []*simpletimed.Wallpaper{},
+		sortedGnomeWallpapers:       []*GnomeWallpaper{},
 	}
 }
 
-// collectionName will strip away the last part of the path, until the remaining last word is no "pixmaps", "contents", "images", "backgrounds", or "wallpapers".
+// collectionName will strip away the last part of the path, until the remaining last word is no "pixmaps", "contents", "images", "images_dark", "backgrounds", or "wallpapers".
 // This is usually the name of the wallpaper collection.
 func collectionName(path string) string {
 	dir := filepath.Dir(path)
 	for {
 		switch filepath.Base(dir) {
-		case "pixmaps", "contents", "images", "wallpapers", "backgrounds":
+		case "pixmaps", "contents", "images", "images_dark", "wallpapers", "backgrounds":
 			dir = filepath.Dir(dir)
 		default:
 			return filepath.Base(dir)
@@ -80,82 +89,101 @@ func partOfCollection(filename string) bool {
 	return err == nil
 }
 
-// pngSize returns the with and height of a PNG file, without reading the entire file
-func pngSize(path string) (uint, uint, error) {
-	pngFile, err := os.Open(path)
-	if err != nil {
-		return 0, 0, err
-	}
-	ic, err := png.DecodeConfig(pngFile)
-	if err != nil {
-		return 0, 0, err
-	}
-	return uint(ic.Width), uint(ic.Height), nil
-}
-
-// jpegSize returns the with and height of a JPEG file, without reading the entire file
-func jpegSize(path string) (uint, uint, error) {
-	jpegFile, err := os.Open(path)
-	if err != nil {
-		return 0, 0, err
-	}
-	ic, err := jpeg.DecodeConfig(jpegFile)
-	if err != nil {
-		return 0, 0, err
-	}
-	return uint(ic.Width), uint(ic.Height), nil
-}
-
 // largeEnough checks if the given size is equal to or larger than the global minimum size
 func largeEnough(width, height uint) bool {
 	return (width >= minimumWidth) && (height >= minimumHeight)
 }
 
-// visit is called per file that is found, and will be called concurrently by powerwalk.WalkLimit
-func (sr *SearchResults) visit(path string, _ os.FileInfo, _ error) error {
-	switch filepath.Ext(path) {
-	case ".png":
-		width, height, err := pngSize(path)
-		if err != nil {
-			return err
+// add adds what was found in a file to the search results
+func (sr *SearchResults) add(path string, entry *indexEntry) {
+	switch {
+	case entry.Wallpaper != nil:
+		sr.wallpapers.Store(path, entry.Wallpaper)
+	case entry.SimpleTimed != nil:
+		sr.simpleTimedWallpapers.Store(path, entry.SimpleTimed)
+	case entry.GnomeTimed != nil:
+		sr.gnomeWallpapers.Store(path, entry.GnomeTimed)
+	case entry.Package != nil:
+		sr.packages.Store(path, entry.Package)
+	case len(entry.Properties) > 0:
+		sr.gnomeProperties.Store(path, entry.Properties)
+	}
+}
+
+// applyPackages sets the collection name, author and license of wallpapers
+// that are part of a KDE Plasma wallpaper package, from the package metadata
+func (sr *SearchResults) applyPackages() {
+	packages := make(map[string]*WallpaperPackage)
+	sr.packages.Range(func(key, value interface{}) bool {
+		pkg, ok := value.(*WallpaperPackage)
+		if !ok {
+			// internal error
+			panic("a value in the packages map is not a pointer to a WallpaperPackage struct")
 		}
-		if !largeEnough(width, height) {
-			return nil
+		// metadata.json is preferred over the older metadata.desktop
+		if _, found := packages[pkg.Path]; !found || filepath.Ext(key.(string)) == ".json" {
+			packages[pkg.Path] = pkg
 		}
-		wp := &Wallpaper{collectionName(path), path, width, height, partOfCollection(path)}
-		sr.wallpapers.Store(path, wp)
-	case ".jpg", ".jpeg":
-		width, height, err := jpegSize(path)
-		if err != nil {
-			return err
+		return true
+	})
+	if len(packages) == 0 {
+		return
+	}
+	sr.wallpapers.Range(func(key, value interface{}) bool {
+		wp, ok := value.(*Wallpaper)
+		if !ok {
+			// internal error
+			panic("a value in the wallpapers map is not a pointer to a Wallpaper struct")
 		}
-		if !largeEnough(width, height) {
-			return nil
+		dir, _ := packageDir(wp.Path)
+		pkg, found := packages[dir]
+		if !found {
+			return true
 		}
-		wp := &Wallpaper{collectionName(path), path, width, height, partOfCollection(path)}
-		sr.wallpapers.Store(path, wp)
-	case ".svg":
-		// TODO: Consider supporting SVG wallpapers in the future
-		return nil
-	case ".xpm", ".xbm":
-		// TODO: Consider supporting XPM and/or XBM wallpapers in the future
+		// Store a copy, since the wallpaper may also be in the wallpaper index
+		packaged := *wp
+		packaged.CollectionName = pkg.Name
+		packaged.PartOfCollection = true
+		packaged.Name, packaged.Author, packaged.License = pkg.Name, pkg.Author, pkg.License
+		sr.wallpapers.Store(key, &packaged)
+		return true
+	})
+}
+
+// visit is called per file that is found, and will be called concurrently by powerwalk.WalkLimit.
+// Files that are in the index and have not changed since they were indexed are not read again.
+func (sr *SearchResults) visit(path string, info os.FileInfo, _ error) error {
+	if entry, ok := sr.index[path]; ok && entry.upToDate(info) {
+		sr.indexed.Store(path, entry)
+		sr.add(path, entry)
 		return nil
-	case ".stw": // Simple Timed Wallpaper
-		stw, err := simpletimed.ParseSTW(path)
-		if err != nil {
-			return err
-		}
-		sr.simpleTimedWallpapers.Store(path, stw)
-	case ".xml":
-		gw, err := gnometimed.ParseXML(path)
-		if err != nil {
-			return err
-		}
-		sr.gnomeWallpapers.Store(path, gw)
 	}
+	entry, err := readIndexEntry(path)
+	if err != nil || entry == nil {
+		return err
+	}
+	if info != nil {
+		entry.ModTime, entry.Size = info.ModTime(), info.Size()
+		sr.indexed.Store(path, entry)
+		sr.indexChanged.Store(true)
+	}
+	sr.add(path, entry)
 	return nil
 }
 
+// saveIndex writes the files that were found to the index, if anything changed
+func (sr *SearchResults) saveIndex() error {
+	files := make(map[string]*indexEntry)
+	sr.indexed.Range(func(key, value interface{}) bool {
+		files[key.(string)] = value.(*indexEntry)
+		return true
+	})
+	if !sr.indexChanged.Load() && len(files) == len(sr.index) {
+		return nil
+	}
+	return saveIndex(files)
+}
+
 // sortWallpapers sorts the found wallpapers
 func (sr *SearchResults) sortWallpapers() {
 	var collected []*Wallpaper
@@ -216,53 +244,90 @@ func (sr *SearchResults) sortSimpleTimedWallpapers() {
 	sr.sortedSimpleTimedWallpapers = collected
 }
 
+// sortGnomeWallpapers sorts the wallpapers from the found GNOME background-properties files
+func (sr *SearchResults) sortGnomeWallpapers() {
+	var collected []*GnomeWallpaper
+	sr.gnomeProperties.Range(func(_, value interface{}) bool {
+		gnomeWallpapers, ok := value.([]*GnomeWallpaper)
+		if !ok {
+			// internal error
+			panic("a value in the gnomeProperties map is not a slice of pointers to GnomeWallpaper structs")
+		}
+		collected = append(collected, gnomeWallpapers...)
+		return true
+	})
+	// Now sort the collected GNOME wallpapers by name
+	sort.Slice(collected, func(i, j int) bool {
+		return collected[i].Name < collected[j].Name
+	})
+	sr.sortedGnomeWallpapers = collected
+}
+
 // FindWallpapers will search for wallpaper collections, simple timed
-// wallpapers and GNOME timed wallpapers in all default wallpaper directories
-// on the system.
+// wallpapers and GNOME timed wallpapers in all the directories that are
+// returned by WallpaperDirectories. The wallpaper index in the cache directory is used for
+// files that have not changed since the previous search.
 func FindWallpapers() (*SearchResults, error) {
+	return findWallpapers(loadIndex())
+}
+
+// RescanWallpapers is like FindWallpapers, but all files are read again,
+// instead of using the wallpaper index. The index is then replaced.
+func RescanWallpapers() (*SearchResults, error) {
+	return findWallpapers(nil)
+}
+
+// findWallpapers searches all wallpaper directories, using the given index,
+// and then updates the index
+func findWallpapers(index map[string]*indexEntry) (*SearchResults, error) {
+	dirs, err := WallpaperDirectories()
+	if err != nil {
+		return nil, err
+	}
 	sr := NewSearchResults()
-	for _, path := range DefaultWallpaperDirectories {
+	sr.index = index
+	if index == nil {
+		sr.indexChanged.Store(true)
+	}
+	for _, path := range dirs {
 		// Search the given path, using the sr.visit function
 		if err := powerwalk.WalkLimit(path, sr.visit, numCPU); err != nil {
 			return nil, err
 		}
 	}
+	// The index is only a cache, so the search results are fine even if it could not be written
+	_ = sr.saveIndex()
+	sr.applyPackages()
 	sr.sortWallpapers()
 	sr.sortSimpleTimedWallpapers()
 	sr.sortGnomeTimedWallpapers()
+	sr.sortGnomeWallpapers()
 	return sr, nil
 }
 
-// FindImagesAt will find images at the given search path.
+// FindImagesAt will find images at the given search path, with one of the
+// given file extensions, or with any of the ImageExtensions if none are given.
 // Set onlyLarge to true if the images should be large enough for the desktop.
-func FindImagesAt(searchPath string, _ []string, onlyLarge bool) ([]string, error) {
+func FindImagesAt(searchPath string, extensions []string, onlyLarge bool) ([]string, error) {
+	if len(extensions) == 0 {
+		extensions = ImageExtensions
+	}
 	found := []string{}
 	// A visit function that will be called for every file found by the WalkLimit function below
 	visit := func(path string, _ os.FileInfo, _ error) error {
-		switch filepath.Ext(path) {
-		case ".png":
-			if onlyLarge {
-				width, height, err := pngSize(path)
-				if err != nil {
-					return err
-				}
-				if !largeEnough(width, height) {
-					return nil
-				}
+		if !hasS(extensions, imageExt(path)) {
+			return nil
+		}
+		if onlyLarge {
+			width, height, err := ImageSize(path)
+			if err != nil {
+				return nil
 			}
-			found = append(found, path)
-		case ".jpg", ".jpeg":
-			if onlyLarge {
-				width, height, err := jpegSize(path)
-				if err != nil {
-					return err
-				}
-				if !largeEnough(width, height) {
-					return nil
-				}
+			if !largeEnough(width, height) {
+				return nil
 			}
-			found = append(found, path)
 		}
+		found = append(found, path)
 		return nil
 	}
 	// Search the given path, using the visit function
@@ -279,9 +344,11 @@ func FindWallpapersAt(path string) (*SearchResults, error) {
 	if err := powerwalk.WalkLimit(path, sr.visit, numCPU); err != nil {
 		return nil, err
 	}
+	sr.applyPackages()
 	sr.sortWallpapers()
 	sr.sortSimpleTimedWallpapers()
 	sr.sortGnomeTimedWallpapers()
+	sr.sortGnomeWallpapers()
 	return sr, nil
 }
 
@@ -299,6 +366,9 @@ func (sr *SearchResults) CollectionNames() []string {
 	for _, stw := range sr.sortedSimpleTimedWallpapers {
 		collectionNames = append(collectionNames, stw.Name)
 	}
+	for _, gw := range sr.sortedGnomeWallpapers {
+		collectionNames = append(collectionNames, gw.Name)
+	}
 	return unique(collectionNames)
 }
 
@@ -317,6 +387,11 @@ func (sr *SearchResults) SimpleTimedWallpapers() []*simpletimed.Wallpaper {
 	return sr.sortedSimpleTimedWallpapers
 }
 
+// GnomeWallpapers returns a sorted slice of all wallpapers from GNOME background-properties files
+func (sr *SearchResults) GnomeWallpapers() []*GnomeWallpaper {
+	return sr.sortedGnomeWallpapers
+}
+
 // WallpapersByName will return simple timed wallpapers that match with the collection name
 func (sr *SearchResults) WallpapersByName(name string) []*Wallpaper {
 	var collection []*Wallpaper
@@ -350,9 +425,20 @@ func (sr *SearchResults) SimpleTimedWallpapersByName(name string) []*simpletimed
 	return collection
 }
 
+// GnomeWallpapersByName will return wallpapers from GNOME background-properties files that match with the given name
+func (sr *SearchResults) GnomeWallpapersByName(name string) []*GnomeWallpaper {
+	var collection []*GnomeWallpaper
+	for _, gw := range sr.sortedGnomeWallpapers {
+		if gw.Name == name {
+			collection = append(collection, gw)
+		}
+	}
+	return collection
+}
+
 // Empty checks if these search results are empty
 func (sr *SearchResults) Empty() bool {
-	return len(sr.sortedSimpleTimedWallpapers) == 0 && len(sr.sortedGnomeTimedWallpapers) == 0 && len(sr.sortedWallpapers) == 0
+	return len(sr.sortedSimpleTimedWallpapers) == 0 && len(sr.sortedGnomeTimedWallpapers) == 0 && len(sr.sortedWallpapers) == 0 && len(sr.sortedGnomeWallpapers) == 0
 }
 
 // NoTimedWallpapers checks if the current search results contains no timed wallpapers
@@ -0,0 +1,252 @@
+package wallutils
+
+import (
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"golang.org/x/image/colornames"
+)
+
+// ColorWM is an interface for backends that can draw a solid color as the
+// wallpaper by themselves, without an image
+type ColorWM interface {
+	WM
+	SetColor(c color.RGBA) error
+}
+
+// ParseColor parses a color like "#223344", "#234" or a color name like "navy"
+func ParseColor(s string) (color.RGBA, error) {
+	s = strings.TrimSpace(s)
+	if c, ok := colornames.Map[strings.ToLower(strings.ReplaceAll(s, " ", ""))]; ok {
+		return c, nil
+	}
+	hex := strings.TrimPrefix(s, "#")
+	if len(hex) == 3 {
+		hex = string([]byte{hex[0], hex[0], hex[1], hex[1], hex[2], hex[2]})
+	}
+	if len(hex) != 6 {
+		return color.RGBA{}, fmt.Errorf("invalid color: %s", s)
+	}
+	n, err := strconv.ParseUint(hex, 16, 32)
+	if err != nil {
+		return color.RGBA{}, fmt.Errorf("invalid color: %s", s)
+	}
+	return color.RGBA{uint8(n >> 16), uint8(n >> 8), uint8(n), 255}, nil
+}
+
+// ColorString returns the given color as a string, like "#223344"
+func ColorString(c color.RGBA) string {
+	return fmt.Sprintf("#%02x%02x%02x", c.R, c.G, c.B)
+}
+
+// parseColors parses a comma separated list of colors, like "#000,#336"
+func parseColors(s string) ([]color.RGBA, error) {
+	var colors []color.RGBA
+	for _, field := range strings.Split(s, ",") {
+		c, err := ParseColor(field)
+		if err != nil {
+			return nil, err
+		}
+		colors = append(colors, c)
+	}
+	return colors, nil
+}
+
+// SolidImage returns an image with the given size, filled with the given color
+func SolidImage(c color.RGBA, width, height uint) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, int(width), int(height)))
+	for i := 0; i < len(img.Pix); i += 4 {
+		img.Pix[i], img.Pix[i+1], img.Pix[i+2], img.Pix[i+3] = c.R, c.G, c.B, 255
+	}
+	return img
+}
+
+// GradientImage returns an image with the given size and a linear gradient
+// between the given colors, which are spread out evenly. The angle is in
+// degrees, where 0 goes from left to right and 90 goes from top to bottom.
+func GradientImage(colors []color.RGBA, angle float64, width, height uint) (*image.RGBA, error) {
+	if len(colors) == 0 {
+		return nil, errors.New("no colors given for the gradient")
+	}
+	if len(colors) == 1 {
+		return SolidImage(colors[0], width, height), nil
+	}
+	img := image.NewRGBA(image.Rect(0, 0, int(width), int(height)))
+	dx, dy := math.Cos(angle*math.Pi/180), math.Sin(angle*math.Pi/180)
+	// The gradient starts and ends at the corners that are furthest apart in the gradient direction
+	lo, hi := math.Inf(1), math.Inf(-1)
+	for _, corner := range [][2]float64{{0, 0}, {float64(width), 0}, {0, float64(height)}, {float64(width), float64(height)}} {
+		p := corner[0]*dx + corner[1]*dy
+		lo, hi = math.Min(lo, p), math.Max(hi, p)
+	}
+	lerp := func(a, b uint8, t float64) uint8 {
+		return uint8(math.Round(float64(a) + (float64(b)-float64(a))*t))
+	}
+	segments := float64(len(colors) - 1)
+	for y := 0; y < int(height); y++ {
+		for x := 0; x < int(width); x++ {
+			t := ((float64(x)+0.5)*dx + (float64(y)+0.5)*dy - lo) / (hi - lo)
+			pos := math.Max(0, math.Min(1, t)) * segments
+			i := min(int(pos), len(colors)-2)
+			a, b, f := colors[i], colors[i+1], pos-float64(i)
+			img.SetRGBA(x, y, color.RGBA{lerp(a.R, b.R, f), lerp(a.G, b.G, f), lerp(a.B, b.B, f), 255})
+		}
+	}
+	return img, nil
+}
+
+// BitmapImage returns an image with the given size, where the given X11
+// bitmap file is repeated from the upper left corner, like "xsetroot -bitmap"
+func BitmapImage(xbmFilename string, fg, bg color.RGBA, width, height uint) (*image.RGBA, error) {
+	f, err := os.Open(xbmFilename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	bitmap, err := DecodeXBM(f, fg, bg)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", xbmFilename, err)
+	}
+	return Render(bitmap, width, height, RenderTile)
+}
+
+// largestMonitorSize returns the resolution of the largest monitor, or
+// 1920x1080 if no monitors could be found
+func largestMonitorSize() (uint, uint) {
+	var width, height uint = 1920, 1080
+	if monitors, err := Monitors(); err == nil && len(monitors) > 0 {
+		width, height = 0, 0
+		for _, mon := range monitors {
+			if mon.Width*mon.Height > width*height {
+				width, height = mon.Width, mon.Height
+			}
+		}
+	}
+	return width, height
+}
+
+// generatedFilename returns a filename in the cache directory for an image
+// that has been generated from the given description, like a gradient
+func generatedFilename(description string) (string, error) {
+	cacheDir, err := renderCacheDir()
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256([]byte(description))
+	return filepath.Join(cacheDir, fmt.Sprintf("generated_%x.png", sum[:8])), nil
+}
+
+// setGeneratedWallpaper saves the image returned by generate in the cache
+// directory, unless it is already there, and sets it as the wallpaper
+func setGeneratedWallpaper(filename string, generate func() (*image.RGBA, error), verbose bool) error {
+	if !cachedRender(filename) {
+		img, err := generate()
+		if err != nil {
+			return err
+		}
+		if err := saveRendered(filename, img); err != nil {
+			return err
+		}
+	}
+	return SetWallpaperCustom(filename, defaultMode, verbose)
+}
+
+// SetWallpaperColor sets a solid color as the wallpaper. If the backend can
+// draw a solid color by itself, like swaybg, that is used. If not, an image
+// with the size of the largest monitor is generated and set as the wallpaper.
+func SetWallpaperColor(c color.RGBA, verbose bool) error {
+	wms, err := backends()
+	if err != nil {
+		return err
+	}
+	for _, wm := range wms {
+		if !usable(wm) {
+			continue
+		}
+		// Only the backend that would be used for setting an image is tried
+		if cwm, ok := wm.(ColorWM); ok {
+			if verbose {
+				fmt.Printf("Using the %s backend.\n", wm.Name())
+			}
+			wm.SetVerbose(verbose)
+			err := cwm.SetColor(c)
+			if err == nil {
+				saveState(&State{Color: ColorString(c)}, verbose)
+				return nil
+			}
+			if verbose {
+				fmt.Fprintf(os.Stderr, "failed: %v\n", err)
+			}
+		}
+		break
+	}
+	width, height := largestMonitorSize()
+	filename, err := generatedFilename(fmt.Sprintf("color:%s:%dx%d", ColorString(c), width, height))
+	if err != nil {
+		return err
+	}
+	if err := setGeneratedWallpaper(filename, func() (*image.RGBA, error) {
+		return SolidImage(c, width, height), nil
+	}, verbose); err != nil {
+		return err
+	}
+	saveState(&State{Color: ColorString(c)}, verbose)
+	return nil
+}
+
+// SetWallpaperGradient sets a linear gradient between the given colors as
+// the wallpaper. The colors are comma separated, like "#000,#336", and the
+// angle is in degrees, where 0 goes from left to right. The gradient is
+// generated for the size of the largest monitor.
+func SetWallpaperGradient(colors string, angle float64, verbose bool) error {
+	parsedColors, err := parseColors(colors)
+	if err != nil {
+		return err
+	}
+	width, height := largestMonitorSize()
+	var names []string
+	for _, c := range parsedColors {
+		names = append(names, ColorString(c))
+	}
+	filename, err := generatedFilename(fmt.Sprintf("gradient:%s:%g:%dx%d", strings.Join(names, ","), angle, width, height))
+	if err != nil {
+		return err
+	}
+	if err := setGeneratedWallpaper(filename, func() (*image.RGBA, error) {
+		return GradientImage(parsedColors, angle, width, height)
+	}, verbose); err != nil {
+		return err
+	}
+	saveState(&State{Image: filename, Mode: defaultMode}, verbose)
+	return nil
+}
+
+// SetWallpaperBitmap sets the given X11 bitmap (XBM) file as the wallpaper,
+// with the given foreground and background colors, repeated over an image
+// with the size of the largest monitor, like "xsetroot -bitmap"
+func SetWallpaperBitmap(xbmFilename string, fg, bg color.RGBA, verbose bool) error {
+	absFilename, err := filepath.Abs(xbmFilename)
+	if err != nil {
+		return err
+	}
+	width, height := largestMonitorSize()
+	filename, err := renderCacheFilename(absFilename, fmt.Sprintf("bitmap_%dx%d_%s_%s", width, height, ColorString(fg)[1:], ColorString(bg)[1:]))
+	if err != nil {
+		return err
+	}
+	if err := setGeneratedWallpaper(filename, func() (*image.RGBA, error) {
+		return BitmapImage(absFilename, fg, bg, width, height)
+	}, verbose); err != nil {
+		return err
+	}
+	saveState(&State{Image: filename, Mode: defaultMode}, verbose)
+	return nil
+}
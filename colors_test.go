@@ -0,0 +1,135 @@
+package wallutils
+
+import (
+	"image/color"
+	"strings"
+	"testing"
+)
+
+func TestParseColor(t *testing.T) {
+	for s, expected := range map[string]color.RGBA{
+		"#223344":  {0x22, 0x33, 0x44, 255},
+		"223344":   {0x22, 0x33, 0x44, 255},
+		"#336":     {0x33, 0x33, 0x66, 255},
+		"navy":     {0, 0, 0x80, 255},
+		"Dark Red": {0x8b, 0, 0, 255},
+	} {
+		c, err := ParseColor(s)
+		if err != nil {
+			t.Errorf("%s: %v", s, err)
+			continue
+		}
+		if c != expected {
+			t.Errorf("%s: expected %v, got %v", s, expected, c)
+		}
+	}
+	for _, s := range []string{"", "#12", "#gggggg", "nosuchcolor"} {
+		if _, err := ParseColor(s); err == nil {
+			t.Errorf("%q: expected an error", s)
+		}
+	}
+	if s := ColorString(color.RGBA{0x22, 0x33, 0x44, 255}); s != "#223344" {
+		t.Errorf("unexpected color string: %s", s)
+	}
+}
+
+func TestGradientImage(t *testing.T) {
+	black := color.RGBA{0, 0, 0, 255}
+	white := color.RGBA{255, 255, 255, 255}
+
+	// From left to right
+	img, err := GradientImage([]color.RGBA{black, white}, 0, 100, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectColor(t, img, 0, 5, color.RGBA{1, 1, 1, 255})
+	expectColor(t, img, 99, 5, color.RGBA{254, 254, 254, 255})
+	if r, _, _, _ := img.At(50, 0).RGBA(); r>>8 < 120 || r>>8 > 135 {
+		t.Errorf("expected gray in the middle, got %v", img.At(50, 0))
+	}
+
+	// From top to bottom, with three colors
+	img, err = GradientImage([]color.RGBA{red, white, blue}, 90, 10, 101)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectColor(t, img, 5, 0, color.RGBA{255, 3, 3, 255})
+	expectColor(t, img, 5, 50, white)
+	expectColor(t, img, 5, 100, color.RGBA{3, 3, 255, 255})
+
+	if _, err := GradientImage(nil, 0, 10, 10); err == nil {
+		t.Error("expected an error when no colors are given")
+	}
+}
+
+func TestDecodeXBM(t *testing.T) {
+	const xbm = `#define test_width 10
+#define test_height 2
+static unsigned char test_bits[] = {
+   0x01, 0x02, 0xff, 0x03 };
+`
+	black := color.RGBA{0, 0, 0, 255}
+	white := color.RGBA{255, 255, 255, 255}
+	img, err := DecodeXBM(strings.NewReader(xbm), black, white)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if img.Bounds().Dx() != 10 || img.Bounds().Dy() != 2 {
+		t.Fatalf("unexpected size: %v", img.Bounds())
+	}
+	// The first row has the first and the tenth pixel set
+	expectColor(t, img, 0, 0, black)
+	expectColor(t, img, 1, 0, white)
+	expectColor(t, img, 8, 0, white)
+	expectColor(t, img, 9, 0, black)
+	// The second row has all pixels set
+	for x := 0; x < 10; x++ {
+		expectColor(t, img, x, 1, black)
+	}
+
+	// X10 bitmaps use shorts, where the low byte has the leftmost pixels,
+	// and each row is padded to 16 bits
+	const x10 = `#define test_width 18
+#define test_height 2
+static short test_bits[] = {
+   0x8001, 0x0002, 0x0100, 0x0000 };
+`
+	img, err = DecodeXBM(strings.NewReader(x10), black, white)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if img.Bounds().Dx() != 18 || img.Bounds().Dy() != 2 {
+		t.Fatalf("unexpected size: %v", img.Bounds())
+	}
+	expectColor(t, img, 0, 0, black)
+	expectColor(t, img, 1, 0, white)
+	expectColor(t, img, 15, 0, black)
+	expectColor(t, img, 16, 0, white)
+	expectColor(t, img, 17, 0, black)
+	expectColor(t, img, 7, 1, white)
+	expectColor(t, img, 8, 1, black)
+
+	if _, err := DecodeXBM(strings.NewReader("#define a_width 8\n#define a_height 1\nstatic char a_bits[] = { 0x100 };"), black, white); err == nil {
+		t.Error("expected an error for a value that does not fit in a char")
+	}
+	if _, err := DecodeXBM(strings.NewReader("#define test_width 8\n"), black, white); err == nil {
+		t.Error("expected an error for an XBM image without a height")
+	}
+	if _, err := DecodeXBM(strings.NewReader("#define a_width 8\n#define a_height 2\nstatic char a_bits[] = { 0x01 };"), black, white); err == nil {
+		t.Error("expected an error for an XBM image with too few bytes")
+	}
+}
+
+func TestColorState(t *testing.T) {
+	t.Setenv("XDG_STATE_HOME", t.TempDir())
+	if err := (&State{Color: "#223344"}).Save(); err != nil {
+		t.Fatal(err)
+	}
+	s, err := LoadState()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.Color != "#223344" || s.Image != "" {
+		t.Errorf("unexpected state: %+v", s)
+	}
+}
@@ -0,0 +1,189 @@
+package wallutils
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ColorScheme is the color scheme that the user prefers, as given by the
+// org.freedesktop.appearance color-scheme setting
+type ColorScheme uint32
+
+const (
+	// NoPreference is used when the user has no preference, which usually means light
+	NoPreference ColorScheme = iota
+	// PreferDark is used when the user prefers a dark color scheme
+	PreferDark
+	// PreferLight is used when the user prefers a light color scheme
+	PreferLight
+)
+
+// The XDG desktop portal and the setting that is read from it
+const (
+	portalDestination    = "org.freedesktop.portal.Desktop"
+	portalPath           = "/org/freedesktop/portal/desktop"
+	portalSettings       = "org.freedesktop.portal.Settings"
+	appearanceNamespace  = "org.freedesktop.appearance"
+	colorSchemeKey       = "color-scheme"
+	colorSchemeMatchRule = "type='signal',interface='" + portalSettings + "',member='SettingChanged',arg0='" + appearanceNamespace + "',arg1='" + colorSchemeKey + "'"
+)
+
+// colorSchemePollInterval is how often gsettings is checked when the XDG
+// desktop portal is not available
+var colorSchemePollInterval = 5 * time.Second
+
+// String returns the color scheme as a string, like "prefer-dark"
+func (cs ColorScheme) String() string {
+	switch cs {
+	case PreferDark:
+		return "prefer-dark"
+	case PreferLight:
+		return "prefer-light"
+	}
+	return "no-preference"
+}
+
+// Dark checks if a dark wallpaper should be used with this color scheme
+func (cs ColorScheme) Dark() bool {
+	return cs == PreferDark
+}
+
+// colorSchemeFromValue converts the value of the color-scheme setting, which
+// may be wrapped in one or more variants, to a ColorScheme
+func colorSchemeFromValue(value interface{}) (ColorScheme, error) {
+	for {
+		v, ok := value.(dbusVariant)
+		if !ok {
+			break
+		}
+		value = v.value
+	}
+	if v, ok := value.(uint32); ok && v <= uint32(PreferLight) {
+		return ColorScheme(v), nil
+	}
+	return NoPreference, fmt.Errorf("unexpected value for %s %s: %v", appearanceNamespace, colorSchemeKey, value)
+}
+
+// portalColorScheme reads the color scheme from the XDG desktop portal.
+// ReadOne is only available in newer versions of the portal, so Read is
+// tried if it fails.
+func portalColorScheme(c *dbusConn) (ColorScheme, error) {
+	body, err := c.call(portalDestination, portalPath, portalSettings, "ReadOne", appearanceNamespace, colorSchemeKey)
+	if err != nil {
+		body, err = c.call(portalDestination, portalPath, portalSettings, "Read", appearanceNamespace, colorSchemeKey)
+		if err != nil {
+			return NoPreference, err
+		}
+	}
+	if len(body) != 1 {
+		return NoPreference, errors.New("unexpected reply from the XDG desktop portal")
+	}
+	return colorSchemeFromValue(body[0])
+}
+
+// gsettingsColorScheme reads the color scheme from the GNOME color-scheme
+// setting, as a stand-in for when the XDG desktop portal is not available
+func gsettingsColorScheme() (ColorScheme, error) {
+	if which("gsettings") == "" {
+		return NoPreference, fmt.Errorf("could not find gsettings: %w", ErrNotAvailable)
+	}
+	switch value := NewGSettings("org.gnome.desktop.interface", false).Get("color-scheme"); value {
+	case "prefer-dark":
+		return PreferDark, nil
+	case "prefer-light":
+		return PreferLight, nil
+	case "default":
+		return NoPreference, nil
+	default:
+		return NoPreference, fmt.Errorf("could not read the color-scheme setting with gsettings: %w", ErrNotAvailable)
+	}
+}
+
+// CurrentColorScheme returns the color scheme that the user prefers, from the
+// XDG desktop portal, or from gsettings if the portal is not available
+func CurrentColorScheme() (ColorScheme, error) {
+	if c, err := dialDBus(); err == nil {
+		cs, err := portalColorScheme(c)
+		c.Close()
+		if err == nil {
+			return cs, nil
+		}
+	}
+	return gsettingsColorScheme()
+}
+
+// WatchColorScheme calls the given function with the current color scheme,
+// and then again every time the color scheme changes. The XDG desktop portal
+// is used if it is available, if not, gsettings is checked regularly.
+// This only returns if there is an error, or if the function returns an error.
+func WatchColorScheme(changed func(ColorScheme) error, verbose bool) error {
+	if c, err := dialDBus(); err == nil {
+		// Subscribe to changes before reading the setting, so that no changes are missed
+		_, err := c.call("org.freedesktop.DBus", "/org/freedesktop/DBus", "org.freedesktop.DBus", "AddMatch", colorSchemeMatchRule)
+		if err == nil {
+			var cs ColorScheme
+			if cs, err = portalColorScheme(c); err == nil {
+				defer c.Close()
+				if verbose {
+					fmt.Println("Watching the color scheme with the XDG desktop portal.")
+				}
+				return watchPortalColorScheme(c, cs, changed)
+			}
+		}
+		c.Close()
+		if verbose {
+			fmt.Printf("Could not use the XDG desktop portal: %v\n", err)
+		}
+	}
+	cs, err := gsettingsColorScheme()
+	if err != nil {
+		return fmt.Errorf("could not read the color scheme from the XDG desktop portal or gsettings: %w", ErrNotAvailable)
+	}
+	if verbose {
+		fmt.Printf("Watching the color scheme with gsettings, every %v.\n", colorSchemePollInterval)
+	}
+	if err := changed(cs); err != nil {
+		return err
+	}
+	for {
+		time.Sleep(colorSchemePollInterval)
+		newColorScheme, err := gsettingsColorScheme()
+		if err != nil || newColorScheme == cs {
+			continue
+		}
+		cs = newColorScheme
+		if err := changed(cs); err != nil {
+			return err
+		}
+	}
+}
+
+// watchPortalColorScheme calls the given function with the given color
+// scheme, and then again every time the XDG desktop portal signals that the
+// color scheme has changed
+func watchPortalColorScheme(c *dbusConn, cs ColorScheme, changed func(ColorScheme) error) error {
+	if err := changed(cs); err != nil {
+		return err
+	}
+	for {
+		msg, err := c.nextSignal()
+		if err != nil {
+			return fmt.Errorf("lost the connection to the D-Bus session bus: %v", err)
+		}
+		if msg.iface != portalSettings || msg.member != "SettingChanged" || len(msg.body) != 3 {
+			continue
+		}
+		if msg.body[0] != appearanceNamespace || msg.body[1] != colorSchemeKey {
+			continue
+		}
+		newColorScheme, err := colorSchemeFromValue(msg.body[2])
+		if err != nil || newColorScheme == cs {
+			continue
+		}
+		cs = newColorScheme
+		if err := changed(cs); err != nil {
+			return err
+		}
+	}
+}
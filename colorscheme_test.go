@@ -0,0 +1,146 @@
+package wallutils
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"net"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// fakePortal is a D-Bus session bus with an XDG desktop portal that only
+// knows the Read method, like older versions of the portal. After the color
+// scheme has been read, the given color schemes are signalled as changes.
+func fakePortal(t *testing.T, cs ColorScheme, changes ...ColorScheme) {
+	t.Helper()
+	address := filepath.Join(t.TempDir(), "bus")
+	l, err := net.Listen("unix", address)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { l.Close() })
+	t.Setenv("DBUS_SESSION_BUS_ADDRESS", "unix:path="+address)
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go serveFakePortal(conn, cs, changes)
+		}
+	}()
+}
+
+func serveFakePortal(conn net.Conn, cs ColorScheme, changes []ColorScheme) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	if line, err := r.ReadString('\n'); err != nil || !strings.HasPrefix(line, "\x00AUTH EXTERNAL ") {
+		return
+	}
+	conn.Write([]byte("OK 0123456789abcdef\r\n"))
+	if line, err := r.ReadString('\n'); err != nil || line != "BEGIN\r\n" {
+		return
+	}
+	var serial uint32 = 100
+	send := func(msgType byte, fields map[byte]interface{}, signature string, body ...interface{}) {
+		serial++
+		data, err := encodeDBusMessage(msgType, serial, fields, signature, body)
+		if err != nil {
+			panic(err)
+		}
+		conn.Write(data)
+	}
+	for {
+		msg, err := readDBusMessage(r)
+		if err != nil {
+			return
+		}
+		reply := map[byte]interface{}{dbusFieldReplySerial: msg.serial}
+		switch msg.member {
+		case "Hello":
+			send(dbusMethodReturn, reply, "s", ":1.42")
+		case "AddMatch":
+			send(dbusMethodReturn, reply, "")
+		case "ReadOne":
+			reply[dbusFieldErrorName] = "org.freedesktop.DBus.Error.UnknownMethod"
+			send(dbusError, reply, "s", "No such method")
+		case "Read":
+			if msg.body[0] != appearanceNamespace || msg.body[1] != colorSchemeKey {
+				return
+			}
+			// Read returns the value wrapped in two variants
+			send(dbusMethodReturn, reply, "v", dbusVariant{"v", dbusVariant{"u", uint32(cs)}})
+			for _, change := range changes {
+				signal := map[byte]interface{}{
+					dbusFieldPath:      portalPath,
+					dbusFieldInterface: portalSettings,
+					dbusFieldMember:    "SettingChanged",
+				}
+				// Other settings are ignored
+				send(dbusSignal, signal, "ssv", "org.gnome.desktop.interface", "font-name", dbusVariant{"s", "Cantarell 11"})
+				send(dbusSignal, signal, "ssv", appearanceNamespace, colorSchemeKey, dbusVariant{"u", uint32(change)})
+			}
+		}
+	}
+}
+
+func TestDBusMessage(t *testing.T) {
+	fields := map[byte]interface{}{
+		dbusFieldPath:        "/org/example",
+		dbusFieldMember:      "Changed",
+		dbusFieldReplySerial: uint32(7),
+	}
+	data, err := encodeDBusMessage(dbusSignal, 3, fields, "sbv", []interface{}{"hello", true, dbusVariant{"u", uint32(42)}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	msg, err := readDBusMessage(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if msg.msgType != dbusSignal || msg.serial != 3 || msg.replySerial != 7 || msg.path != "/org/example" || msg.member != "Changed" || msg.signature != "sbv" {
+		t.Errorf("unexpected message: %+v", msg)
+	}
+	if len(msg.body) != 3 || msg.body[0] != "hello" || msg.body[1] != true || msg.body[2] != (dbusVariant{"u", uint32(42)}) {
+		t.Errorf("unexpected body: %v", msg.body)
+	}
+	if _, err := encodeDBusMessage(dbusSignal, 4, map[byte]interface{}{}, "s", nil); err == nil {
+		t.Error("expected an error for a body that does not match the signature")
+	}
+	if types := dbusSplitSignature("sa{sv}(ii)v"); strings.Join(types, " ") != "s a{sv} (ii) v" {
+		t.Errorf("unexpected types: %v", types)
+	}
+}
+
+func TestCurrentColorScheme(t *testing.T) {
+	fakePortal(t, PreferDark)
+	cs, err := CurrentColorScheme()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cs != PreferDark || !cs.Dark() || cs.String() != "prefer-dark" {
+		t.Errorf("expected prefer-dark, got %s", cs)
+	}
+}
+
+func TestWatchColorScheme(t *testing.T) {
+	fakePortal(t, NoPreference, PreferDark, PreferDark, PreferLight)
+	errDone := errors.New("done")
+	var seen []string
+	err := WatchColorScheme(func(cs ColorScheme) error {
+		seen = append(seen, cs.String())
+		if cs == PreferLight {
+			return errDone
+		}
+		return nil
+	}, false)
+	if !errors.Is(err, errDone) {
+		t.Fatalf("expected the error from the function, got %v", err)
+	}
+	// The second change to prefer-dark is not a change
+	if strings.Join(seen, " ") != "no-preference prefer-dark prefer-light" {
+		t.Errorf("unexpected color schemes: %v", seen)
+	}
+}
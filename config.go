@@ -0,0 +1,267 @@
+package wallutils
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Config is the wallutils configuration file, ~/.config/wallutils/config.toml.
+// Only the keys below are supported, for example:
+//
+//	# Also search these directories for wallpapers
+//	paths = ["~/art", "/mnt/photos/wallpapers"]
+//	# Only search the directories in paths, not the default directories
+//	replace = false
+type Config struct {
+	Paths   []string // directories to search for wallpapers
+	Replace bool     // search only Paths, instead of also searching the default directories
+}
+
+// ConfigFilename returns the path to the configuration file:
+// $XDG_CONFIG_HOME/wallutils/config.toml, or ~/.config/wallutils/config.toml
+// if XDG_CONFIG_HOME is not set
+func ConfigFilename() (string, error) {
+	configDir := os.Getenv("XDG_CONFIG_HOME")
+	if !filepath.IsAbs(configDir) {
+		homeDir, err := os.UserHomeDir()
+		if err != nil {
+			return "", err
+		}
+		configDir = filepath.Join(homeDir, ".config")
+	}
+	return filepath.Join(configDir, "wallutils", "config.toml"), nil
+}
+
+// LoadConfig reads the configuration file. An empty configuration is
+// returned if there is no configuration file.
+func LoadConfig() (*Config, error) {
+	filename, err := ConfigFilename()
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(filename)
+	if errors.Is(err, os.ErrNotExist) {
+		return &Config{}, nil
+	} else if err != nil {
+		return nil, err
+	}
+	config, err := ParseConfig(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", filename, err)
+	}
+	return config, nil
+}
+
+// ParseConfig parses the contents of a configuration file, which is a
+// small subset of TOML: keys with strings, booleans or arrays of strings
+func ParseConfig(data []byte) (*Config, error) {
+	var config Config
+	lines := strings.Split(strings.ReplaceAll(string(data), "\r\n", "\n"), "\n")
+	for i := 0; i < len(lines); i++ {
+		lineNumber := i + 1
+		line := strings.TrimSpace(stripTOMLComment(lines[i]))
+		if line == "" {
+			continue
+		}
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			return nil, fmt.Errorf("line %d: expected a key and a value: %s", lineNumber, line)
+		}
+		key, value = strings.TrimSpace(key), strings.TrimSpace(value)
+		// Arrays may continue on the following lines
+		for strings.HasPrefix(value, "[") && !tomlArrayClosed(value) && i+1 < len(lines) {
+			i++
+			value += " " + strings.TrimSpace(stripTOMLComment(lines[i]))
+		}
+		switch key {
+		case "paths":
+			strs, err := parseTOMLArray(value)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %v", lineNumber, err)
+			}
+			config.Paths = strs
+		case "replace":
+			switch value {
+			case "true", "false":
+				config.Replace = value == "true"
+			default:
+				return nil, fmt.Errorf("line %d: expected true or false: %s", lineNumber, value)
+			}
+		default:
+			return nil, fmt.Errorf("line %d: unknown key: %s", lineNumber, key)
+		}
+	}
+	return &config, nil
+}
+
+// stripTOMLComment removes a comment from the end of the given line,
+// unless the # is part of a string
+func stripTOMLComment(line string) string {
+	var quote rune
+	for i, r := range line {
+		switch {
+		case quote != 0 && r == quote:
+			quote = 0
+		case quote == 0 && (r == '"' || r == '\''):
+			quote = r
+		case quote == 0 && r == '#':
+			return line[:i]
+		}
+	}
+	return line
+}
+
+// tomlArrayClosed checks if the given array ends with a "]" that is not
+// part of a string
+func tomlArrayClosed(value string) bool {
+	var quote rune
+	for _, r := range value {
+		switch {
+		case quote != 0 && r == quote:
+			quote = 0
+		case quote == 0 && (r == '"' || r == '\''):
+			quote = r
+		case quote == 0 && r == ']':
+			return true
+		}
+	}
+	return false
+}
+
+// parseTOMLString parses a basic string, like "~/art", or a literal
+// string, like '~/art'
+func parseTOMLString(value string) (string, error) {
+	switch {
+	case len(value) >= 2 && value[0] == '"' && value[len(value)-1] == '"':
+		return strconv.Unquote(value)
+	case len(value) >= 2 && value[0] == '\'' && value[len(value)-1] == '\'':
+		return value[1 : len(value)-1], nil
+	}
+	return "", fmt.Errorf("expected a string: %s", value)
+}
+
+// parseTOMLArray parses an array of strings, like ["~/art", "/mnt/art"]
+func parseTOMLArray(value string) ([]string, error) {
+	if !strings.HasPrefix(value, "[") || !strings.HasSuffix(value, "]") {
+		return nil, fmt.Errorf("expected an array of strings: %s", value)
+	}
+	var (
+		strs    []string
+		current strings.Builder
+		quote   rune
+	)
+	addCurrent := func() error {
+		item := strings.TrimSpace(current.String())
+		current.Reset()
+		if item == "" {
+			return nil
+		}
+		s, err := parseTOMLString(item)
+		if err != nil {
+			return err
+		}
+		strs = append(strs, s)
+		return nil
+	}
+	for _, r := range value[1 : len(value)-1] {
+		switch {
+		case quote != 0 && r == quote:
+			quote = 0
+		case quote == 0 && (r == '"' || r == '\''):
+			quote = r
+		case quote == 0 && r == ',':
+			if err := addCurrent(); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		current.WriteRune(r)
+	}
+	if err := addCurrent(); err != nil {
+		return nil, err
+	}
+	return strs, nil
+}
+
+// expandHome replaces a leading "~" in the given path with the home directory
+func expandHome(path string) string {
+	if path != "~" && !strings.HasPrefix(path, "~/") {
+		return path
+	}
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return path
+	}
+	return filepath.Join(homeDir, path[1:])
+}
+
+// defaultDirectories returns the directories that are searched for
+// wallpapers by default: DefaultWallpaperDirectories, the backgrounds and
+// gnome-background-properties directories in each of $XDG_DATA_DIRS (which
+// includes Flatpak applications) and the wallpaper directories of the
+// current user
+func defaultDirectories() []string {
+	dirs := append([]string{}, DefaultWallpaperDirectories...)
+	dataDirs := os.Getenv("XDG_DATA_DIRS")
+	if dataDirs == "" {
+		dataDirs = "/usr/local/share:/usr/share"
+	}
+	for _, dir := range filepath.SplitList(dataDirs) {
+		if filepath.IsAbs(dir) {
+			dirs = append(dirs, filepath.Join(dir, "backgrounds"), filepath.Join(dir, "gnome-background-properties"))
+		}
+	}
+	dataHome := os.Getenv("XDG_DATA_HOME")
+	if !filepath.IsAbs(dataHome) {
+		dataHome = expandHome("~/.local/share")
+	}
+	dirs = append(dirs,
+		"/var/lib/flatpak/exports/share/backgrounds",
+		filepath.Join(dataHome, "flatpak", "exports", "share", "backgrounds"),
+		filepath.Join(dataHome, "backgrounds"),
+		filepath.Join(dataHome, "gnome-background-properties"),
+		filepath.Join(dataHome, "wallpapers"),
+		expandHome("~/Pictures/Wallpapers"),
+	)
+	return dirs
+}
+
+// WallpaperDirectories returns the directories that are searched for
+// wallpapers. The directories in the configuration file are searched in
+// addition to the default directories, or instead of them, if "replace" is
+// set. If the WALLUTILS_PATH environment variable is set, it is a colon
+// separated list of directories that are searched instead. An empty entry in
+// WALLUTILS_PATH, like in "~/art:", is replaced with the directories that
+// would otherwise have been searched.
+func WallpaperDirectories() ([]string, error) {
+	config, err := LoadConfig()
+	if err != nil {
+		return nil, err
+	}
+	var dirs []string
+	if !config.Replace {
+		dirs = defaultDirectories()
+	}
+	for _, dir := range config.Paths {
+		dirs = append(dirs, expandHome(dir))
+	}
+	if wallutilsPath := os.Getenv("WALLUTILS_PATH"); wallutilsPath != "" {
+		var envDirs []string
+		for _, dir := range filepath.SplitList(wallutilsPath) {
+			if dir == "" {
+				envDirs = append(envDirs, dirs...)
+			} else {
+				envDirs = append(envDirs, expandHome(dir))
+			}
+		}
+		dirs = envDirs
+	}
+	for i, dir := range dirs {
+		dirs[i] = filepath.Clean(dir)
+	}
+	return unique(dirs), nil
+}
@@ -0,0 +1,115 @@
+package wallutils
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestParseConfig(t *testing.T) {
+	config, err := ParseConfig([]byte(`# Wallpaper directories
+paths = [
+    "~/art", # drawings
+    '/mnt/c#/wallpapers',
+]
+replace = true
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(config.Paths, []string{"~/art", "/mnt/c#/wallpapers"}) || !config.Replace {
+		t.Errorf("unexpected config: %+v", config)
+	}
+
+	for _, data := range []string{
+		"paths = \"~/art\"",
+		"paths = [~/art]",
+		"replace = yes",
+		"colors = [\"#223344\"]",
+		"[wallpapers]",
+	} {
+		if _, err := ParseConfig([]byte(data)); err == nil {
+			t.Errorf("%s: expected an error", data)
+		}
+	}
+}
+
+func TestWallpaperDirectories(t *testing.T) {
+	homeDir := t.TempDir()
+	t.Setenv("HOME", homeDir)
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(homeDir, ".config"))
+	t.Setenv("XDG_DATA_HOME", "")
+	t.Setenv("XDG_DATA_DIRS", "/usr/share:/var/lib/flatpak/exports/share")
+	t.Setenv("WALLUTILS_PATH", "")
+
+	dirs, err := WallpaperDirectories()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, dir := range []string{
+		"/usr/share/backgrounds",
+		"/usr/share/wallpapers",
+		"/var/lib/flatpak/exports/share/backgrounds",
+		filepath.Join(homeDir, ".local/share/backgrounds"),
+		filepath.Join(homeDir, ".local/share/wallpapers"),
+		filepath.Join(homeDir, "Pictures/Wallpapers"),
+	} {
+		if !hasS(dirs, dir) {
+			t.Errorf("expected %s to be searched, got %v", dir, dirs)
+		}
+	}
+	if len(unique(dirs)) != len(dirs) {
+		t.Errorf("expected no duplicate directories, got %v", dirs)
+	}
+
+	// Add a directory in the configuration file
+	configFilename, err := ConfigFilename()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(filepath.Dir(configFilename), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(configFilename, []byte("paths = [\"~/art\"]\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	dirs, err = WallpaperDirectories()
+	if err != nil {
+		t.Fatal(err)
+	}
+	art := filepath.Join(homeDir, "art")
+	if !hasS(dirs, art) || !hasS(dirs, "/usr/share/backgrounds") {
+		t.Errorf("expected both %s and the default directories, got %v", art, dirs)
+	}
+
+	// Replace the default directories
+	if err := os.WriteFile(configFilename, []byte("paths = [\"~/art\"]\nreplace = true\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	dirs, err = WallpaperDirectories()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(dirs, []string{art}) {
+		t.Errorf("expected only %s, got %v", art, dirs)
+	}
+
+	// WALLUTILS_PATH replaces the directories, and an empty entry is the configured directories
+	t.Setenv("WALLUTILS_PATH", "/srv/wallpapers")
+	if dirs, err = WallpaperDirectories(); err != nil || !reflect.DeepEqual(dirs, []string{"/srv/wallpapers"}) {
+		t.Errorf("expected only /srv/wallpapers, got %v (%v)", dirs, err)
+	}
+	t.Setenv("WALLUTILS_PATH", "/srv/wallpapers:")
+	if dirs, err = WallpaperDirectories(); err != nil || !reflect.DeepEqual(dirs, []string{"/srv/wallpapers", art}) {
+		t.Errorf("expected /srv/wallpapers and %s, got %v (%v)", art, dirs, err)
+	}
+
+	// An invalid configuration file is an error
+	if err := os.WriteFile(configFilename, []byte("paths = ~/art\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := WallpaperDirectories(); err == nil {
+		t.Error("expected an error for an invalid configuration file")
+	}
+}
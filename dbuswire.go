@@ -0,0 +1,609 @@
+package wallutils
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// D-Bus message types
+const (
+	dbusMethodCall   = 1
+	dbusMethodReturn = 2
+	dbusError        = 3
+	dbusSignal       = 4
+)
+
+// D-Bus header fields
+const (
+	dbusFieldPath        = 1
+	dbusFieldInterface   = 2
+	dbusFieldMember      = 3
+	dbusFieldErrorName   = 4
+	dbusFieldReplySerial = 5
+	dbusFieldDestination = 6
+	dbusFieldSender      = 7
+	dbusFieldSignature   = 8
+)
+
+// dbusCallTimeout is how long to wait for the reply to a method call
+const dbusCallTimeout = 10 * time.Second
+
+// dbusConn is a minimal client for the D-Bus protocol, that only knows
+// enough to call methods with string arguments and receive signals, for
+// reading settings from the XDG desktop portal. No C libraries are needed.
+type dbusConn struct {
+	conn    net.Conn
+	r       *bufio.Reader
+	serial  uint32
+	signals []*dbusMessage // signals that were received while waiting for a reply
+}
+
+// dbusMessage is a received D-Bus message
+type dbusMessage struct {
+	msgType     byte
+	serial      uint32
+	replySerial uint32
+	path        string
+	iface       string
+	member      string
+	errorName   string
+	sender      string
+	signature   string
+	body        []interface{}
+}
+
+// dbusVariant is a decoded D-Bus variant, with the signature of the value
+type dbusVariant struct {
+	signature string
+	value     interface{}
+}
+
+// dbusSessionAddress returns the network and address of the D-Bus session
+// bus, from DBUS_SESSION_BUS_ADDRESS, like "unix:path=/run/user/1000/bus",
+// or from XDG_RUNTIME_DIR
+func dbusSessionAddress() (string, string, error) {
+	address := os.Getenv("DBUS_SESSION_BUS_ADDRESS")
+	if address == "" {
+		runtimeDir := os.Getenv("XDG_RUNTIME_DIR")
+		if runtimeDir == "" {
+			return "", "", fmt.Errorf("DBUS_SESSION_BUS_ADDRESS and XDG_RUNTIME_DIR are not set: %w", ErrNotAvailable)
+		}
+		return "unix", filepath.Join(runtimeDir, "bus"), nil
+	}
+	// Several addresses may be given, separated by ";"
+	for _, addr := range strings.Split(address, ";") {
+		transport, params, ok := strings.Cut(addr, ":")
+		if !ok || transport != "unix" {
+			continue
+		}
+		for _, param := range strings.Split(params, ",") {
+			key, value, _ := strings.Cut(param, "=")
+			switch key {
+			case "path":
+				return "unix", value, nil
+			case "abstract":
+				return "unix", "@" + value, nil
+			}
+		}
+	}
+	return "", "", fmt.Errorf("unsupported DBUS_SESSION_BUS_ADDRESS: %s", address)
+}
+
+// dialDBus connects and authenticates to the D-Bus session bus
+func dialDBus() (*dbusConn, error) {
+	network, address, err := dbusSessionAddress()
+	if err != nil {
+		return nil, err
+	}
+	conn, err := net.DialTimeout(network, address, dbusCallTimeout)
+	if err != nil {
+		return nil, fmt.Errorf("could not connect to the D-Bus session bus: %w", ErrNotAvailable)
+	}
+	c := &dbusConn{conn: conn, r: bufio.NewReader(conn)}
+	if err := c.auth(); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	// Every connection must say hello to the bus first
+	if _, err := c.call("org.freedesktop.DBus", "/org/freedesktop/DBus", "org.freedesktop.DBus", "Hello"); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return c, nil
+}
+
+// auth authenticates with the EXTERNAL mechanism, using the user ID
+func (c *dbusConn) auth() error {
+	c.conn.SetDeadline(time.Now().Add(dbusCallTimeout))
+	defer c.conn.SetDeadline(time.Time{})
+	uid := hex.EncodeToString([]byte(strconv.Itoa(os.Getuid())))
+	if _, err := c.conn.Write([]byte("\x00AUTH EXTERNAL " + uid + "\r\n")); err != nil {
+		return err
+	}
+	line, err := c.r.ReadString('\n')
+	if err != nil {
+		return fmt.Errorf("D-Bus authentication failed: %v", err)
+	}
+	if !strings.HasPrefix(line, "OK ") {
+		return fmt.Errorf("D-Bus authentication failed: %s", strings.TrimSpace(line))
+	}
+	_, err = c.conn.Write([]byte("BEGIN\r\n"))
+	return err
+}
+
+// Close closes the connection
+func (c *dbusConn) Close() error {
+	return c.conn.Close()
+}
+
+// dbusEncoder encodes D-Bus values, in little endian byte order
+type dbusEncoder struct {
+	buf []byte
+}
+
+func (e *dbusEncoder) align(n int) {
+	for len(e.buf)%n != 0 {
+		e.buf = append(e.buf, 0)
+	}
+}
+
+func (e *dbusEncoder) byte(b byte) {
+	e.buf = append(e.buf, b)
+}
+
+func (e *dbusEncoder) uint32(v uint32) {
+	e.align(4)
+	e.buf = binary.LittleEndian.AppendUint32(e.buf, v)
+}
+
+func (e *dbusEncoder) string(s string) {
+	e.uint32(uint32(len(s)))
+	e.buf = append(append(e.buf, s...), 0)
+}
+
+func (e *dbusEncoder) signature(s string) {
+	e.buf = append(append(append(e.buf, byte(len(s))), s...), 0)
+}
+
+// value encodes a value with one of the given basic types: y, b, u, s, o, g or
+// v, where a variant is given as a dbusVariant
+func (e *dbusEncoder) value(signature string, value interface{}) error {
+	switch signature {
+	case "y":
+		e.byte(value.(byte))
+	case "b":
+		if value.(bool) {
+			e.uint32(1)
+		} else {
+			e.uint32(0)
+		}
+	case "u":
+		e.uint32(value.(uint32))
+	case "s", "o":
+		e.string(value.(string))
+	case "g":
+		e.signature(value.(string))
+	case "v":
+		v := value.(dbusVariant)
+		e.signature(v.signature)
+		return e.value(v.signature, v.value)
+	default:
+		return fmt.Errorf("can not encode D-Bus type: %s", signature)
+	}
+	return nil
+}
+
+// encodeDBusMessage encodes a message with the given header fields, where
+// the values are strings, except for dbusFieldReplySerial, which is an
+// uint32, and the given body
+func encodeDBusMessage(msgType byte, serial uint32, fields map[byte]interface{}, signature string, body []interface{}) ([]byte, error) {
+	var b dbusEncoder
+	types := dbusSplitSignature(signature)
+	if len(types) != len(body) {
+		return nil, fmt.Errorf("the D-Bus signature %q does not match the %d values", signature, len(body))
+	}
+	for i, value := range body {
+		if err := b.value(types[i], value); err != nil {
+			return nil, err
+		}
+	}
+	if signature != "" {
+		fields[dbusFieldSignature] = signature
+	}
+	var e dbusEncoder
+	e.buf = append(e.buf, 'l', msgType, 0, 1)
+	e.uint32(uint32(len(b.buf)))
+	e.uint32(serial)
+	// The header fields are an array of structs with a byte and a variant
+	lengthPos := len(e.buf)
+	e.uint32(0)
+	e.align(8)
+	start := len(e.buf)
+	for code := byte(1); code <= dbusFieldSignature; code++ {
+		value, ok := fields[code]
+		if !ok {
+			continue
+		}
+		e.align(8)
+		e.byte(code)
+		switch code {
+		case dbusFieldPath:
+			e.value("v", dbusVariant{"o", value})
+		case dbusFieldReplySerial:
+			e.value("v", dbusVariant{"u", value})
+		case dbusFieldSignature:
+			e.value("v", dbusVariant{"g", value})
+		default:
+			e.value("v", dbusVariant{"s", value})
+		}
+	}
+	binary.LittleEndian.PutUint32(e.buf[lengthPos:], uint32(len(e.buf)-start))
+	e.align(8)
+	return append(e.buf, b.buf...), nil
+}
+
+// dbusSplitSignature splits a signature into single complete types, like
+// "sa{sv}" into "s" and "a{sv}"
+func dbusSplitSignature(signature string) []string {
+	var types []string
+	for len(signature) > 0 {
+		n := dbusTypeLength(signature)
+		types = append(types, signature[:n])
+		signature = signature[n:]
+	}
+	return types
+}
+
+// dbusTypeLength returns the length of the first complete type in the signature
+func dbusTypeLength(signature string) int {
+	switch signature[0] {
+	case 'a':
+		if len(signature) == 1 {
+			return 1
+		}
+		return 1 + dbusTypeLength(signature[1:])
+	case '(', '{':
+		depth := 0
+		for i, r := range signature {
+			switch r {
+			case '(', '{':
+				depth++
+			case ')', '}':
+				depth--
+				if depth == 0 {
+					return i + 1
+				}
+			}
+		}
+		return len(signature)
+	}
+	return 1
+}
+
+// dbusDecoder decodes D-Bus values. The positions are relative to the start
+// of the message, which is where the alignment is counted from.
+type dbusDecoder struct {
+	data  []byte
+	pos   int
+	order binary.ByteOrder
+}
+
+var errDBusShort = errors.New("D-Bus message is too short")
+
+func (d *dbusDecoder) align(n int) error {
+	for d.pos%n != 0 {
+		d.pos++
+	}
+	if d.pos > len(d.data) {
+		return errDBusShort
+	}
+	return nil
+}
+
+func (d *dbusDecoder) next(n int) ([]byte, error) {
+	if d.pos+n > len(d.data) {
+		return nil, errDBusShort
+	}
+	b := d.data[d.pos : d.pos+n]
+	d.pos += n
+	return b, nil
+}
+
+func (d *dbusDecoder) uint32() (uint32, error) {
+	if err := d.align(4); err != nil {
+		return 0, err
+	}
+	b, err := d.next(4)
+	if err != nil {
+		return 0, err
+	}
+	return d.order.Uint32(b), nil
+}
+
+func (d *dbusDecoder) uint64() (uint64, error) {
+	if err := d.align(8); err != nil {
+		return 0, err
+	}
+	b, err := d.next(8)
+	if err != nil {
+		return 0, err
+	}
+	return d.order.Uint64(b), nil
+}
+
+func (d *dbusDecoder) string() (string, error) {
+	n, err := d.uint32()
+	if err != nil {
+		return "", err
+	}
+	b, err := d.next(int(n) + 1)
+	if err != nil {
+		return "", err
+	}
+	return string(b[:n]), nil
+}
+
+func (d *dbusDecoder) signature() (string, error) {
+	n, err := d.next(1)
+	if err != nil {
+		return "", err
+	}
+	b, err := d.next(int(n[0]) + 1)
+	if err != nil {
+		return "", err
+	}
+	return string(b[:n[0]]), nil
+}
+
+// value decodes a value with the given single complete type. Arrays and
+// structs are decoded as []interface{}, and variants as dbusVariant.
+func (d *dbusDecoder) value(signature string) (interface{}, error) {
+	switch signature[0] {
+	case 'y':
+		b, err := d.next(1)
+		if err != nil {
+			return nil, err
+		}
+		return b[0], nil
+	case 'n', 'q':
+		if err := d.align(2); err != nil {
+			return nil, err
+		}
+		b, err := d.next(2)
+		if err != nil {
+			return nil, err
+		}
+		if signature[0] == 'n' {
+			return int16(d.order.Uint16(b)), nil
+		}
+		return d.order.Uint16(b), nil
+	case 'b':
+		v, err := d.uint32()
+		return v != 0, err
+	case 'i':
+		v, err := d.uint32()
+		return int32(v), err
+	case 'u', 'h':
+		return d.uint32()
+	case 'x':
+		v, err := d.uint64()
+		return int64(v), err
+	case 't':
+		return d.uint64()
+	case 'd':
+		v, err := d.uint64()
+		return math.Float64frombits(v), err
+	case 's', 'o':
+		return d.string()
+	case 'g':
+		return d.signature()
+	case 'v':
+		sig, err := d.signature()
+		if err != nil {
+			return nil, err
+		}
+		if sig == "" || dbusTypeLength(sig) != len(sig) {
+			return nil, fmt.Errorf("invalid D-Bus variant signature: %q", sig)
+		}
+		value, err := d.value(sig)
+		return dbusVariant{sig, value}, err
+	case 'a':
+		n, err := d.uint32()
+		if err != nil {
+			return nil, err
+		}
+		elem := signature[1 : 1+dbusTypeLength(signature[1:])]
+		// The array starts at the alignment of the element type
+		switch elem[0] {
+		case 'x', 't', 'd', '(', '{':
+			if err := d.align(8); err != nil {
+				return nil, err
+			}
+		}
+		end := d.pos + int(n)
+		if end > len(d.data) {
+			return nil, errDBusShort
+		}
+		var values []interface{}
+		for d.pos < end {
+			value, err := d.value(elem)
+			if err != nil {
+				return nil, err
+			}
+			values = append(values, value)
+		}
+		return values, nil
+	case '(', '{':
+		if err := d.align(8); err != nil {
+			return nil, err
+		}
+		var values []interface{}
+		for _, t := range dbusSplitSignature(signature[1 : len(signature)-1]) {
+			value, err := d.value(t)
+			if err != nil {
+				return nil, err
+			}
+			values = append(values, value)
+		}
+		return values, nil
+	}
+	return nil, fmt.Errorf("unsupported D-Bus type: %s", signature)
+}
+
+// readDBusMessage reads and decodes a message
+func readDBusMessage(r io.Reader) (*dbusMessage, error) {
+	fixed := make([]byte, 16)
+	if _, err := io.ReadFull(r, fixed); err != nil {
+		return nil, err
+	}
+	var order binary.ByteOrder
+	switch fixed[0] {
+	case 'l':
+		order = binary.LittleEndian
+	case 'B':
+		order = binary.BigEndian
+	default:
+		return nil, fmt.Errorf("invalid D-Bus message byte order: %q", fixed[0])
+	}
+	bodyLength := int(order.Uint32(fixed[4:]))
+	fieldsLength := int(order.Uint32(fixed[12:]))
+	headerLength := 16 + fieldsLength
+	headerLength += (8 - headerLength%8) % 8
+	if headerLength+bodyLength > 128<<20 {
+		return nil, errors.New("D-Bus message is too long")
+	}
+	data := make([]byte, headerLength+bodyLength)
+	copy(data, fixed)
+	if _, err := io.ReadFull(r, data[16:]); err != nil {
+		return nil, err
+	}
+	msg := &dbusMessage{msgType: fixed[1], serial: order.Uint32(fixed[8:])}
+	d := &dbusDecoder{data: data[:16+fieldsLength], pos: 16, order: order}
+	for d.pos < len(d.data) {
+		if err := d.align(8); err != nil {
+			return nil, err
+		}
+		code, err := d.next(1)
+		if err != nil {
+			return nil, err
+		}
+		value, err := d.value("v")
+		if err != nil {
+			return nil, err
+		}
+		switch v := value.(dbusVariant).value.(type) {
+		case string:
+			switch code[0] {
+			case dbusFieldPath:
+				msg.path = v
+			case dbusFieldInterface:
+				msg.iface = v
+			case dbusFieldMember:
+				msg.member = v
+			case dbusFieldErrorName:
+				msg.errorName = v
+			case dbusFieldSender:
+				msg.sender = v
+			case dbusFieldSignature:
+				msg.signature = v
+			}
+		case uint32:
+			if code[0] == dbusFieldReplySerial {
+				msg.replySerial = v
+			}
+		}
+	}
+	// The body is aligned to 8 bytes, so positions can be counted from the start of the body
+	d = &dbusDecoder{data: data[headerLength:], order: order}
+	for _, t := range dbusSplitSignature(msg.signature) {
+		value, err := d.value(t)
+		if err != nil {
+			return nil, err
+		}
+		msg.body = append(msg.body, value)
+	}
+	return msg, nil
+}
+
+// send sends a method call with string arguments, and returns the serial
+func (c *dbusConn) send(destination, path, iface, member string, args ...string) (uint32, error) {
+	c.serial++
+	fields := map[byte]interface{}{
+		dbusFieldPath:        path,
+		dbusFieldInterface:   iface,
+		dbusFieldMember:      member,
+		dbusFieldDestination: destination,
+	}
+	body := make([]interface{}, len(args))
+	for i, arg := range args {
+		body[i] = arg
+	}
+	data, err := encodeDBusMessage(dbusMethodCall, c.serial, fields, strings.Repeat("s", len(args)), body)
+	if err != nil {
+		return 0, err
+	}
+	_, err = c.conn.Write(data)
+	return c.serial, err
+}
+
+// call calls a method with string arguments, and returns the body of the
+// reply. Signals that are received while waiting are kept for nextSignal.
+func (c *dbusConn) call(destination, path, iface, member string, args ...string) ([]interface{}, error) {
+	serial, err := c.send(destination, path, iface, member, args...)
+	if err != nil {
+		return nil, err
+	}
+	c.conn.SetReadDeadline(time.Now().Add(dbusCallTimeout))
+	defer c.conn.SetReadDeadline(time.Time{})
+	for {
+		msg, err := readDBusMessage(c.r)
+		if err != nil {
+			return nil, err
+		}
+		switch {
+		case msg.msgType == dbusSignal:
+			c.signals = append(c.signals, msg)
+		case msg.replySerial != serial:
+			continue
+		case msg.msgType == dbusError:
+			text := msg.errorName
+			if len(msg.body) > 0 {
+				if s, ok := msg.body[0].(string); ok {
+					text += ": " + s
+				}
+			}
+			return nil, fmt.Errorf("D-Bus call to %s.%s failed: %s", iface, member, text)
+		default:
+			return msg.body, nil
+		}
+	}
+}
+
+// nextSignal waits for the next signal
+func (c *dbusConn) nextSignal() (*dbusMessage, error) {
+	if len(c.signals) > 0 {
+		msg := c.signals[0]
+		c.signals = c.signals[1:]
+		return msg, nil
+	}
+	for {
+		msg, err := readDBusMessage(c.r)
+		if err != nil {
+			return nil, err
+		}
+		if msg.msgType == dbusSignal {
+			return msg, nil
+		}
+	}
+}
@@ -1,7 +1,6 @@
 package wallutils
 
 import (
-	"errors"
 	"fmt"
 
 	"github.com/xyproto/env/v2"
@@ -42,6 +41,17 @@ func (d *Deepin) SetMode(mode string) {
 	d.mode = mode
 }
 
+// Capabilities returns the wallpaper modes that are supported by this backend,
+// if one wallpaper per output can be set and if the wallpaper is kept after
+// logging in again
+func (d *Deepin) Capabilities() Capabilities {
+	return Capabilities{
+		Modes:      []string{"none", "wallpaper", "centered", "scaled", "stretched", "zoom", "spanned", "stretch", "center", "fill", "scale", "tile"},
+		PerOutput:  false,
+		Persistent: true,
+	}
+}
+
 // SetVerbose can be used for setting the verbose field to true or false.
 // This will cause this backend to output information about what is is doing on stdout.
 func (d *Deepin) SetVerbose(verbose bool) {
@@ -82,11 +92,11 @@ func (d *Deepin) SetWallpaper(imageFilename string) error {
 		mode = "wallpaper"
 	default:
 		// Invalid and unrecognized desktop wallpaper mode
-		return fmt.Errorf("invalid desktop wallpaper mode for Deepin: %s", mode)
+		return fmt.Errorf("%w for Deepin: %s", ErrUnsupportedMode, mode)
 	}
 
 	if !d.hasGsettings {
-		return errors.New("could not find gsettings")
+		return fmt.Errorf("could not find gsettings: %w", ErrNotAvailable)
 	}
 
 	// Exit if the monitor configuration will cause artifacts when setting
@@ -0,0 +1,71 @@
+package wallutils
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// drmPath is where the kernel lists the DRM connectors
+const drmPath = "/sys/class/drm"
+
+// drmMonitors returns one Monitor struct per connected DRM connector, by
+// reading the status, modes and edid files for each connector in the given
+// directory. Only the preferred mode is known, which is normally the one
+// that is in use. The DPI is calculated from the EDID, if available.
+// Positions, rotation and scale are not available.
+func drmMonitors(dir string) ([]Monitor, error) {
+	// Connectors are named like "card0-DP-1" and "card1-eDP-1"
+	matches, err := filepath.Glob(filepath.Join(dir, "card*-*"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(matches)
+	var (
+		monitors []Monitor
+		counter  uint
+	)
+	for _, connectorPath := range matches {
+		status, err := os.ReadFile(filepath.Join(connectorPath, "status"))
+		if err != nil || strings.TrimSpace(string(status)) != "connected" {
+			continue
+		}
+		modes, err := os.ReadFile(filepath.Join(connectorPath, "modes"))
+		if err != nil {
+			continue
+		}
+		// The first mode is the preferred one, for example "1920x1080"
+		fields := strings.Fields(string(modes))
+		if len(fields) == 0 {
+			continue
+		}
+		wh := strings.SplitN(strings.TrimRight(fields[0], "i"), "x", 2)
+		if len(wh) != 2 {
+			continue
+		}
+		w, errW := strconv.Atoi(wh[0])
+		h, errH := strconv.Atoi(wh[1])
+		if errW != nil || errH != nil || w <= 0 || h <= 0 {
+			continue
+		}
+		name := filepath.Base(connectorPath)
+		if i := strings.Index(name, "-"); i >= 0 {
+			name = name[i+1:]
+		}
+		mon := Monitor{ID: counter, Name: name, Width: uint(w), Height: uint(h), Scale: 1}
+		if data, err := os.ReadFile(filepath.Join(connectorPath, "edid")); err == nil {
+			if e, err := ParseEDID(data); err == nil {
+				mon.EDID = e
+				if e.PreferredWidth == mon.Width && e.PreferredHeight == mon.Height {
+					mon.Refresh = e.PreferredRefresh
+				}
+				mon.applyEDID()
+			}
+		}
+		monitors = append(monitors, mon)
+		counter++
+	}
+	return monitors, nil
+}
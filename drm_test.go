@@ -0,0 +1,39 @@
+package wallutils
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestDRMMonitors(t *testing.T) {
+	dir := t.TempDir()
+	edid := testEDID(600, 340)
+	for name, files := range map[string]map[string][]byte{
+		"card0-DP-1":     {"status": []byte("connected\n"), "modes": []byte("2560x1440\n1920x1080\n"), "edid": edid},
+		"card0-HDMI-A-1": {"status": []byte("disconnected\n"), "modes": []byte{}},
+		"card1-eDP-1":    {"status": []byte("connected\n"), "modes": []byte("1920x1200\n")},
+	} {
+		if err := os.Mkdir(filepath.Join(dir, name), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		for filename, data := range files {
+			if err := os.WriteFile(filepath.Join(dir, name, filename), data, 0o644); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	monitors, err := drmMonitors(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(monitors) != 2 {
+		t.Fatalf("expected 2 monitors, got %d", len(monitors))
+	}
+	if dp := monitors[0]; dp.Name != "DP-1" || dp.Width != 2560 || dp.Height != 1440 || dp.DPIw != 108 || dp.DPIh != 107 || dp.DPISource != DPIFromEDID || dp.Make != "DEL" || dp.Model != "DELL U2715H" {
+		t.Errorf("unexpected monitor: %+v", dp)
+	}
+	if edp := monitors[1]; edp.ID != 1 || edp.Name != "eDP-1" || edp.Width != 1920 || edp.Height != 1200 || edp.DPISource != DPIUnknown {
+		t.Errorf("unexpected monitor: %+v", edp)
+	}
+}
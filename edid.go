@@ -0,0 +1,208 @@
+package wallutils
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// EDID contains the information that a monitor reports about itself
+type EDID struct {
+	Manufacturer     string  // three letter manufacturer ID, like "DEL"
+	ProductCode      uint16  // manufacturer product code
+	Serial           string  // serial number, if available
+	Model            string  // monitor name, if available
+	Year             int     // year of manufacture, or model year
+	WidthMM          uint    // physical width, in mm, 0 if not available
+	HeightMM         uint    // physical height, in mm, 0 if not available
+	PreferredWidth   uint    // width of the preferred mode, in pixels
+	PreferredHeight  uint    // height of the preferred mode, in pixels
+	PreferredRefresh float64 // refresh rate of the preferred mode, in Hz
+}
+
+var edidHeader = []byte{0x00, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0x00}
+
+// ParseEDID decodes the 128 byte EDID base block. Extension blocks are ignored.
+func ParseEDID(data []byte) (*EDID, error) {
+	if len(data) < 128 {
+		return nil, errors.New("EDID data is too short")
+	}
+	if !bytes.Equal(data[:8], edidHeader) {
+		return nil, errors.New("invalid EDID header")
+	}
+	var sum byte
+	for _, b := range data[:128] {
+		sum += b
+	}
+	if sum != 0 {
+		return nil, errors.New("invalid EDID checksum")
+	}
+	e := &EDID{}
+
+	// The manufacturer ID is three 5-bit letters, where 1 is "A"
+	id := binary.BigEndian.Uint16(data[8:])
+	e.Manufacturer = string([]byte{
+		byte('A' - 1 + (id>>10)&0x1f),
+		byte('A' - 1 + (id>>5)&0x1f),
+		byte('A' - 1 + id&0x1f),
+	})
+	e.ProductCode = binary.LittleEndian.Uint16(data[10:])
+	if serial := binary.LittleEndian.Uint32(data[12:]); serial != 0 {
+		e.Serial = fmt.Sprintf("%d", serial)
+	}
+	e.Year = 1990 + int(data[17])
+
+	// The maximum image size, in cm. Both are 0 for projectors and
+	// when the size is undefined.
+	if data[21] > 0 && data[22] > 0 {
+		e.WidthMM, e.HeightMM = uint(data[21])*10, uint(data[22])*10
+	}
+
+	// Four 18 byte descriptors, where the first one is the preferred timing
+	for i := 0; i < 4; i++ {
+		d := data[54+i*18 : 72+i*18]
+		if d[0] != 0 || d[1] != 0 {
+			// Detailed timing descriptor
+			if i != 0 {
+				continue
+			}
+			clock := float64(binary.LittleEndian.Uint16(d)) * 10000
+			hActive := uint(d[2]) | uint(d[4]&0xf0)<<4
+			hBlank := uint(d[3]) | uint(d[4]&0x0f)<<8
+			vActive := uint(d[5]) | uint(d[7]&0xf0)<<4
+			vBlank := uint(d[6]) | uint(d[7]&0x0f)<<8
+			e.PreferredWidth, e.PreferredHeight = hActive, vActive
+			if total := (hActive + hBlank) * (vActive + vBlank); total > 0 {
+				e.PreferredRefresh = clock / float64(total)
+			}
+			// The image size in mm is more precise than the size in cm,
+			// but some TVs only use it for giving the aspect ratio, like 16x9
+			wMM := uint(d[12]) | uint(d[14]&0xf0)<<4
+			hMM := uint(d[13]) | uint(d[14]&0x0f)<<8
+			if wMM >= 100 && hMM >= 100 {
+				e.WidthMM, e.HeightMM = wMM, hMM
+			}
+			continue
+		}
+		// Display descriptor, the text is terminated by a newline
+		text := strings.TrimSpace(strings.SplitN(string(d[5:]), "\n", 2)[0])
+		switch d[3] {
+		case 0xfc:
+			e.Model = text
+		case 0xff:
+			e.Serial = text
+		}
+	}
+	return e, nil
+}
+
+// DPI returns the horizontal and vertical DPI for the given resolution,
+// or 0, 0 if the physical size is not available
+func (e *EDID) DPI(width, height uint) (uint, uint) {
+	if e.WidthMM == 0 || e.HeightMM == 0 {
+		return 0, 0
+	}
+	return uint(float64(width) / (float64(e.WidthMM) / 25.4)), uint(float64(height) / (float64(e.HeightMM) / 25.4))
+}
+
+// drmEDIDs reads the EDID for all connected DRM connectors in the given
+// directory, and returns them by connector name, like "DP-1"
+func drmEDIDs(dir string) map[string]*EDID {
+	edids := make(map[string]*EDID)
+	matches, err := filepath.Glob(filepath.Join(dir, "card*-*", "edid"))
+	if err != nil {
+		return edids
+	}
+	for _, edidPath := range matches {
+		data, err := os.ReadFile(edidPath)
+		if err != nil {
+			continue
+		}
+		e, err := ParseEDID(data)
+		if err != nil {
+			continue
+		}
+		name := filepath.Base(filepath.Dir(edidPath))
+		if i := strings.Index(name, "-"); i >= 0 {
+			name = name[i+1:]
+		}
+		edids[name] = e
+	}
+	return edids
+}
+
+// plausibleDPI checks if a DPI value could be correct. Projectors and
+// some TVs report physical sizes that gives very low or very high values.
+func plausibleDPI(DPI uint) bool {
+	return DPI >= 20 && DPI <= 1200
+}
+
+// applyEDIDs fills in missing information for the given monitors from their
+// EDID, if available. The EDID for monitors that have none is looked up by
+// output name in the given DRM sysfs directory. Monitors that still have no
+// DPI values get the default of 96.
+func applyEDIDs(monitors []Monitor, dir string) {
+	var edids map[string]*EDID
+	for i := range monitors {
+		mon := &monitors[i]
+		if mon.EDID == nil {
+			if edids == nil {
+				edids = drmEDIDs(dir)
+			}
+			// The X11 modesetting driver uses "HDMI-1" for the "HDMI-A-1" connector
+			if e, ok := edids[mon.Name]; ok {
+				mon.EDID = e
+			} else if e, ok := edids[strings.Replace(mon.Name, "HDMI-", "HDMI-A-", 1)]; ok {
+				mon.EDID = e
+			} else if len(monitors) == 1 && len(edids) == 1 {
+				for _, e := range edids {
+					mon.EDID = e
+				}
+			}
+		}
+		mon.applyEDID()
+		if mon.DPISource == DPIUnknown {
+			mon.DPIw = 96 // default DPI value, if no physical size is given
+			mon.DPIh = 96 // default DPI value, if no physical size is given
+			mon.DPISource = DPIDefault
+		}
+	}
+}
+
+// applyEDID fills in missing information from the EDID, if available.
+// DPI is only calculated if the value from the display server is missing.
+func (m *Monitor) applyEDID() {
+	e := m.EDID
+	if e == nil {
+		return
+	}
+	if m.Make == "" {
+		m.Make = e.Manufacturer
+	}
+	if m.Model == "" {
+		m.Model = e.Model
+	}
+	if m.Serial == "" {
+		m.Serial = e.Serial
+	}
+	if m.DPISource == DPIFromServer && plausibleDPI(m.DPIw) && plausibleDPI(m.DPIh) {
+		return
+	}
+	physW, physH := m.Width, m.Height
+	if m.Rotation == 90 || m.Rotation == 270 {
+		// the physical size is not rotated, but the resolution is
+		physW, physH = physH, physW
+	}
+	w, h := e.DPI(physW, physH)
+	if m.Rotation == 90 || m.Rotation == 270 {
+		w, h = h, w
+	}
+	if plausibleDPI(w) && plausibleDPI(h) {
+		m.DPIw, m.DPIh = w, h
+		m.DPISource = DPIFromEDID
+	}
+}
@@ -0,0 +1,110 @@
+package wallutils
+
+import (
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// testEDID returns a valid EDID base block for a 2560x1440 monitor from
+// Dell, with the given physical size in mm
+func testEDID(widthMM, heightMM uint) []byte {
+	data := make([]byte, 128)
+	copy(data, edidHeader)
+	binary.BigEndian.PutUint16(data[8:], 4<<10|5<<5|12) // "DEL"
+	binary.LittleEndian.PutUint16(data[10:], 0xa0c4)
+	binary.LittleEndian.PutUint32(data[12:], 12345)
+	data[17] = 25 // 2015
+	data[18], data[19] = 1, 4
+	data[21], data[22] = byte(widthMM/10), byte(heightMM/10)
+
+	// Preferred timing, 2560x1440 at 59.95 Hz, with the size in mm
+	d := data[54:72]
+	binary.LittleEndian.PutUint16(d, 24150) // 241.5 MHz
+	d[2], d[3], d[4] = 2560&0xff, 160, (2560>>8)<<4
+	d[5], d[6], d[7] = 1440&0xff, 41, (1440>>8)<<4
+	d[12], d[13], d[14] = byte(widthMM), byte(heightMM), byte(widthMM>>8)<<4|byte(heightMM>>8)
+
+	// Monitor name
+	d = data[72:90]
+	d[3] = 0xfc
+	copy(d[5:], "DELL U2715H\n   ")
+
+	// Serial number
+	d = data[90:108]
+	d[3] = 0xff
+	copy(d[5:], "GH85D5AJ0ZXL\n")
+
+	var sum byte
+	for _, b := range data[:127] {
+		sum += b
+	}
+	data[127] = -sum
+	return data
+}
+
+func TestParseEDID(t *testing.T) {
+	e, err := ParseEDID(testEDID(597, 336))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e.Manufacturer != "DEL" || e.Model != "DELL U2715H" || e.Serial != "GH85D5AJ0ZXL" || e.Year != 2015 {
+		t.Errorf("unexpected EDID: %+v", e)
+	}
+	if e.WidthMM != 597 || e.HeightMM != 336 || e.PreferredWidth != 2560 || e.PreferredHeight != 1440 {
+		t.Errorf("unexpected EDID: %+v", e)
+	}
+	if int(e.PreferredRefresh*100) != 5995 {
+		t.Errorf("unexpected refresh rate: %f", e.PreferredRefresh)
+	}
+	if w, h := e.DPI(2560, 1440); w != 108 || h != 108 {
+		t.Errorf("unexpected DPI: %dx%d", w, h)
+	}
+
+	// A projector without a physical size
+	e, err = ParseEDID(testEDID(0, 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if w, h := e.DPI(2560, 1440); w != 0 || h != 0 {
+		t.Errorf("expected no DPI for a projector, got %dx%d", w, h)
+	}
+
+	data := testEDID(597, 336)
+	data[127]++
+	if _, err := ParseEDID(data); err == nil {
+		t.Error("expected an error for an invalid checksum")
+	}
+	if _, err := ParseEDID(data[:100]); err == nil {
+		t.Error("expected an error for a short EDID")
+	}
+}
+
+func TestApplyEDIDs(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.Mkdir(filepath.Join(dir, "card0-HDMI-A-1"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "card0-HDMI-A-1", "edid"), testEDID(597, 336), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	monitors := []Monitor{
+		// No physical size from the server, and rotated
+		{Name: "HDMI-1", Width: 1440, Height: 2560, Rotation: 90, DPIw: 96, DPIh: 96, DPISource: DPIDefault},
+		// The server value is kept
+		{Name: "eDP-1", Width: 1920, Height: 1080, DPIw: 141, DPIh: 141, DPISource: DPIFromServer, EDID: &EDID{WidthMM: 100, HeightMM: 100}},
+		// No EDID and no physical size, like a DRM connector without an EDID
+		{Name: "DP-2", Width: 1920, Height: 1080},
+	}
+	applyEDIDs(monitors, dir)
+	if m := monitors[0]; m.DPIw != 108 || m.DPIh != 108 || m.DPISource != DPIFromEDID || m.Make != "DEL" || m.Serial != "GH85D5AJ0ZXL" {
+		t.Errorf("unexpected monitor: %+v", m)
+	}
+	if m := monitors[1]; m.DPIw != 141 || m.DPISource != DPIFromServer {
+		t.Errorf("unexpected monitor: %+v", m)
+	}
+	if m := monitors[2]; m.DPIw != 96 || m.DPIh != 96 || m.DPISource != DPIDefault {
+		t.Errorf("unexpected monitor: %+v", m)
+	}
+}
@@ -0,0 +1,104 @@
+package wallutils
+
+import (
+	"errors"
+	"strings"
+)
+
+var (
+	// ErrUnsupportedMode is returned when a backend does not support the
+	// given wallpaper mode. Other backends are not tried, since the running
+	// desktop environment or window manager was found.
+	ErrUnsupportedMode = errors.New("invalid desktop wallpaper mode")
+
+	// ErrNotAvailable is returned when a backend can not be used right now,
+	// for instance because a command or a socket is missing. The next
+	// backend is then tried.
+	ErrNotAvailable = errors.New("not available")
+
+	// ErrPermanent is returned when a backend is running, but can not set
+	// the wallpaper, and it will not help to try other backends
+	ErrPermanent = errors.New("not supported by this backend")
+)
+
+// BackendError is an error from one of the backends
+type BackendError struct {
+	Backend string // the name of the backend, like "Feh"
+	Err     error
+}
+
+func (e *BackendError) Error() string {
+	return e.Backend + ": " + e.Err.Error()
+}
+
+// Unwrap returns the error from the backend, for use with errors.Is
+func (e *BackendError) Unwrap() error {
+	return e.Err
+}
+
+// NoBackendError is returned when none of the backends could set the
+// wallpaper. It contains the error from each backend that was tried.
+type NoBackendError struct {
+	What   string          // what was attempted, like "setting the desktop wallpaper"
+	Errors []*BackendError // one error per backend that was tried
+}
+
+func (e *NoBackendError) Error() string {
+	if len(e.Errors) == 0 {
+		return "found no working method for " + e.What
+	}
+	var sb strings.Builder
+	sb.WriteString("found no working method for " + e.What + ":")
+	for _, err := range e.Errors {
+		sb.WriteString("\n  " + err.Error())
+	}
+	return sb.String()
+}
+
+// Unwrap returns the errors from the backends that were tried, or
+// ErrNotAvailable if no backends could be tried
+func (e *NoBackendError) Unwrap() []error {
+	if len(e.Errors) == 0 {
+		return []error{ErrNotAvailable}
+	}
+	errs := make([]error, len(e.Errors))
+	for i, err := range e.Errors {
+		errs[i] = err
+	}
+	return errs
+}
+
+// Hint returns a hint about how the given error can be avoided, for use by
+// the command line utilities, or an empty string
+func Hint(err error) string {
+	var noBackend *NoBackendError
+	switch {
+	case errors.Is(err, ErrUnsupportedMode):
+		if wm := DetectedBackend(); wm != nil {
+			if capabilities, ok := BackendCapabilities(wm); ok && len(capabilities.Modes) > 0 {
+				return "The " + wm.Name() + " backend supports these modes: " + strings.Join(capabilities.Modes, ", ")
+			}
+		}
+	case errors.Is(err, ErrPermanent):
+		return "Use --backend to select another backend, or lsbackends to list them"
+	case errors.As(err, &noBackend) && len(noBackend.Errors) == 0:
+		return "No backend is running, or the executables they need could not be found. Use lsbackends to list them."
+	}
+	return ""
+}
+
+// RestartHint returns a message about restarting the desktop environment or
+// window manager, if the backend that sets the wallpaper must be restarted
+// before a new wallpaper is shown, like Weston. This is for use by the command
+// line utilities, after the wallpaper has been set. If no restart is needed,
+// an empty string is returned.
+func RestartHint() string {
+	wm := DetectedBackend()
+	if wm == nil {
+		return ""
+	}
+	if capabilities, ok := BackendCapabilities(wm); ok && capabilities.Restart {
+		return "The wallpaper has been set, but " + wm.Name() + " must be restarted before it is shown."
+	}
+	return ""
+}
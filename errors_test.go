@@ -0,0 +1,38 @@
+package wallutils
+
+import (
+	"errors"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestBackendErrors(t *testing.T) {
+	f, err := os.CreateTemp("", "wallutils*.png")
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+	defer os.Remove(f.Name())
+
+	feh := &Feh{}
+	feh.SetMode("nosuchmode")
+	if err := feh.SetWallpaper(f.Name()); !errors.Is(err, ErrUnsupportedMode) {
+		t.Errorf("expected ErrUnsupportedMode, got %v", err)
+	}
+
+	noBackend := &NoBackendError{What: "setting the desktop wallpaper"}
+	if !errors.Is(noBackend, ErrNotAvailable) {
+		t.Error("expected ErrNotAvailable when no backends were tried")
+	}
+	noBackend.Errors = append(noBackend.Errors,
+		&BackendError{Backend: "Gnome3", Err: errors.New("gsettings failed")},
+		&BackendError{Backend: "Feh", Err: ErrNotAvailable},
+	)
+	if !errors.Is(noBackend, ErrNotAvailable) || errors.Is(noBackend, ErrPermanent) {
+		t.Error("expected the errors from the backends to be wrapped")
+	}
+	if msg := noBackend.Error(); !strings.Contains(msg, "\n  Gnome3: gsettings failed") || !strings.Contains(msg, "\n  Feh: not available") {
+		t.Errorf("unexpected error message: %s", msg)
+	}
+}
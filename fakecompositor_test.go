@@ -0,0 +1,218 @@
+package wallutils
+
+import (
+	"encoding/binary"
+	"io"
+	"net"
+	"path/filepath"
+	"sync"
+	"testing"
+)
+
+// fakeOutput is an output that is announced by the fake compositor
+type fakeOutput struct {
+	name, description  string
+	make, model        string
+	x, y               int32
+	physW, physH       int32
+	transform          int32
+	scale              int32
+	width, height      int32
+	refresh            int32
+	logicalX, logicalY int32
+	logicalW, logicalH int32
+}
+
+// fakeCompositor is a minimal Wayland server that only knows about
+// wl_display, wl_registry, wl_output and zxdg_output_manager_v1
+type fakeCompositor struct {
+	outputs  []fakeOutput
+	xdg      bool // announce zxdg_output_manager_v1
+	listener net.Listener
+	wg       sync.WaitGroup
+}
+
+// startFakeCompositor starts a fake compositor that listens on a socket in a
+// temporary directory, and sets WAYLAND_DISPLAY to the path of that socket
+func startFakeCompositor(t *testing.T, xdg bool, outputs ...fakeOutput) *fakeCompositor {
+	t.Helper()
+	dir := t.TempDir()
+	socketPath := filepath.Join(dir, "wayland-wallutils")
+	l, err := net.Listen("unix", socketPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("XDG_RUNTIME_DIR", dir)
+	t.Setenv("WAYLAND_DISPLAY", socketPath)
+	fc := &fakeCompositor{outputs: outputs, xdg: xdg, listener: l}
+	fc.wg.Add(1)
+	go func() {
+		defer fc.wg.Done()
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			fc.wg.Add(1)
+			go func() {
+				defer fc.wg.Done()
+				fc.serve(conn)
+			}()
+		}
+	}()
+	t.Cleanup(fc.close)
+	return fc
+}
+
+func (fc *fakeCompositor) close() {
+	fc.listener.Close()
+	fc.wg.Wait()
+}
+
+// wireMessage is a message that is sent from the fake compositor
+type wireMessage struct {
+	buf []byte
+}
+
+func (m *wireMessage) uint(v uint32) *wireMessage {
+	m.buf = binary.LittleEndian.AppendUint32(m.buf, v)
+	return m
+}
+
+func (m *wireMessage) int(v int32) *wireMessage {
+	return m.uint(uint32(v))
+}
+
+func (m *wireMessage) string(s string) *wireMessage {
+	m.uint(uint32(len(s) + 1))
+	m.buf = append(m.buf, s...)
+	m.buf = append(m.buf, 0)
+	for len(m.buf)%4 != 0 {
+		m.buf = append(m.buf, 0)
+	}
+	return m
+}
+
+func (fc *fakeCompositor) serve(conn net.Conn) {
+	defer conn.Close()
+
+	const (
+		registryOutputBase = 100 // global names for wl_output starts here
+		registryXDG        = 1   // global name of zxdg_output_manager_v1
+	)
+
+	var (
+		objects = map[uint32]string{1: "wl_display"}
+		outputs = map[uint32]int{} // wl_output object ID -> index in fc.outputs
+	)
+
+	send := func(object, opcode uint32, m *wireMessage) {
+		header := make([]byte, 8)
+		binary.LittleEndian.PutUint32(header, object)
+		binary.LittleEndian.PutUint32(header[4:], uint32(8+len(m.buf))<<16|opcode)
+		conn.Write(append(header, m.buf...))
+	}
+
+	header := make([]byte, 8)
+	for {
+		if _, err := io.ReadFull(conn, header); err != nil {
+			return
+		}
+		object := binary.LittleEndian.Uint32(header)
+		opcode := binary.LittleEndian.Uint32(header[4:]) & 0xffff
+		size := binary.LittleEndian.Uint32(header[4:]) >> 16
+		if size < 8 {
+			return
+		}
+		body := make([]byte, size-8)
+		if _, err := io.ReadFull(conn, body); err != nil {
+			return
+		}
+		arg := func(i int) uint32 {
+			return binary.LittleEndian.Uint32(body[i*4:])
+		}
+
+		switch objects[object] {
+		case "wl_display":
+			switch opcode {
+			case 0: // sync
+				callback := arg(0)
+				send(callback, 0, (&wireMessage{}).uint(0))
+				send(1, 1, (&wireMessage{}).uint(callback)) // delete_id
+			case 1: // get_registry
+				registry := arg(0)
+				objects[registry] = "wl_registry"
+				if fc.xdg {
+					send(registry, 0, (&wireMessage{}).uint(registryXDG).string("zxdg_output_manager_v1").uint(3))
+				}
+				for i := range fc.outputs {
+					send(registry, 0, (&wireMessage{}).uint(uint32(registryOutputBase+i)).string("wl_output").uint(4))
+				}
+			}
+		case "wl_registry":
+			if opcode != 0 { // bind
+				continue
+			}
+			// name, interface (string), version, new_id
+			name := arg(0)
+			ifaceLen := int(arg(1))
+			offset := 8 + (ifaceLen+3)/4*4
+			version := binary.LittleEndian.Uint32(body[offset:])
+			id := binary.LittleEndian.Uint32(body[offset+4:])
+			if name == registryXDG {
+				objects[id] = "zxdg_output_manager_v1"
+				continue
+			}
+			i := int(name - registryOutputBase)
+			if i < 0 || i >= len(fc.outputs) {
+				continue
+			}
+			objects[id] = "wl_output"
+			outputs[id] = i
+			o := fc.outputs[i]
+			send(id, 0, (&wireMessage{}).int(o.x).int(o.y).int(o.physW).int(o.physH).int(0).string(o.make).string(o.model).int(o.transform))
+			send(id, 1, (&wireMessage{}).uint(0x1|0x2).int(o.width).int(o.height).int(o.refresh))
+			if version >= 2 {
+				send(id, 3, (&wireMessage{}).int(o.scale))
+			}
+			if version >= 4 {
+				send(id, 4, (&wireMessage{}).string(o.name))
+				send(id, 5, (&wireMessage{}).string(o.description))
+			}
+			if version >= 2 {
+				send(id, 2, &wireMessage{})
+			}
+		case "zxdg_output_manager_v1":
+			if opcode != 1 { // get_xdg_output
+				continue
+			}
+			id, outputID := arg(0), arg(1)
+			i, ok := outputs[outputID]
+			if !ok {
+				continue
+			}
+			objects[id] = "zxdg_output_v1"
+			o := fc.outputs[i]
+			send(id, 0, (&wireMessage{}).int(o.logicalX).int(o.logicalY))
+			send(id, 1, (&wireMessage{}).int(o.logicalW).int(o.logicalH))
+			send(id, 3, (&wireMessage{}).string(o.name))
+			send(id, 2, &wireMessage{})
+		}
+	}
+}
+
+// twoFakeOutputs is a setup with a scaled laptop panel and a rotated monitor
+var twoFakeOutputs = []fakeOutput{
+	{
+		name: "eDP-1", description: "Built-in display", make: "BOE", model: "0x0BCA",
+		physW: 310, physH: 174, scale: 2,
+		width: 2880, height: 1620, refresh: 60000,
+		logicalW: 1920, logicalH: 1080,
+	},
+	{
+		name: "DP-1", description: "Dell Inc. DELL U2715H (DP-1)", make: "Dell Inc.", model: "DELL U2715H",
+		x: 1920, physW: 600, physH: 340, transform: 1, scale: 1,
+		width: 2560, height: 1440, refresh: 59951,
+		logicalX: 1920, logicalW: 1440, logicalH: 2560,
+	},
+}
@@ -5,7 +5,10 @@ package wallutils
 import (
 	"errors"
 	"fmt"
+	"os"
 	"strings"
+
+	"github.com/xyproto/env/v2"
 )
 
 // Feh is a structure containing settings for running the "feh" executable
@@ -35,6 +38,17 @@ func (f *Feh) SetMode(mode string) {
 	f.mode = mode
 }
 
+// Capabilities returns the wallpaper modes that are supported by this backend,
+// if one wallpaper per output can be set and if the wallpaper is kept after
+// logging in again
+func (f *Feh) Capabilities() Capabilities {
+	return Capabilities{
+		Modes:      []string{"fill", "center", "max", "scale", "tile", "zoom", "zoomed", "stretch", "stretched", "scaled", "fit"},
+		PerOutput:  false,
+		Persistent: false,
+	}
+}
+
 // SetVerbose can be used for setting the verbose field to true or false.
 // This will cause this backend to output information about what is is doing on stdout.
 func (f *Feh) SetVerbose(verbose bool) {
@@ -67,7 +81,7 @@ func (f *Feh) SetWallpaper(imageFilename string) error {
 		mode = "max"
 	default:
 		// Invalid and unrecognized desktop wallpaper mode
-		return fmt.Errorf("invalid desktop wallpaper mode for Feh: %s", mode)
+		return fmt.Errorf("%w for Feh: %s", ErrUnsupportedMode, mode)
 	}
 
 	// set the wallpaper with feh
@@ -76,3 +90,61 @@ func (f *Feh) SetWallpaper(imageFilename string) error {
 	}
 	return nil
 }
+
+// CurrentWallpaper returns the current wallpaper, by reading the ~/.fehbg
+// script that feh writes when setting the wallpaper
+func (f *Feh) CurrentWallpaper() (string, error) {
+	data, err := os.ReadFile(env.ExpandUser("~/.fehbg"))
+	if err != nil {
+		return "", err
+	}
+	if imageFilenames := parseFehbg(string(data)); len(imageFilenames) > 0 {
+		return imageFilenames[0], nil
+	}
+	return "", errors.New("could not find an image filename in ~/.fehbg")
+}
+
+// parseFehbg returns the image filenames from the contents of a ~/.fehbg
+// script, which looks like this:
+//
+//	#!/bin/sh
+//	feh --no-fehbg --bg-fill '/path/to/image.jpg'
+//
+// A single quote in a filename is written by ending the quoted string,
+// adding an escaped quote (\') and then starting a new quoted string.
+func parseFehbg(script string) []string {
+	var imageFilenames []string
+	for _, line := range strings.Split(script, "\n") {
+		line = strings.TrimSpace(line)
+		if !strings.HasPrefix(line, "feh ") {
+			continue
+		}
+		var (
+			sb      strings.Builder
+			quoted  bool
+			started bool
+		)
+		for i := 0; i < len(line); i++ {
+			switch {
+			case line[i] == '\'':
+				quoted = !quoted
+				started = true
+			case !quoted && strings.HasPrefix(line[i:], `\'`):
+				sb.WriteByte('\'')
+				i++
+			case !quoted && line[i] == ' ':
+				if started {
+					imageFilenames = append(imageFilenames, sb.String())
+				}
+				sb.Reset()
+				started = false
+			case quoted:
+				sb.WriteByte(line[i])
+			}
+		}
+		if started {
+			imageFilenames = append(imageFilenames, sb.String())
+		}
+	}
+	return imageFilenames
+}
@@ -32,6 +32,17 @@ func (g2 *Gnome2) SetMode(mode string) {
 	g2.mode = mode
 }
 
+// Capabilities returns the wallpaper modes that are supported by this backend,
+// if one wallpaper per output can be set and if the wallpaper is kept after
+// logging in again
+func (g2 *Gnome2) Capabilities() Capabilities {
+	return Capabilities{
+		Modes:      []string{"none", "wallpaper", "centered", "scaled", "stretched", "zoom", "spanned", "stretch", "center", "fill", "scale", "tile"},
+		PerOutput:  false,
+		Persistent: true,
+	}
+}
+
 // SetVerbose can be used for setting the verbose field to true or false.
 // This will cause this backend to output information about what is is doing on stdout.
 func (g2 *Gnome2) SetVerbose(verbose bool) {
@@ -64,7 +75,7 @@ func (g2 *Gnome2) SetWallpaper(imageFilename string) error {
 		mode = "wallpaper"
 	default:
 		// Invalid and unrecognized desktop wallpaper mode
-		return fmt.Errorf("invalid desktop wallpaper mode for GNOME2: %s", mode)
+		return fmt.Errorf("%w for GNOME2: %s", ErrUnsupportedMode, mode)
 	}
 
 	// Set the wallpaper mode
@@ -42,6 +42,17 @@ func (g3 *Gnome3) SetMode(mode string) {
 	g3.mode = mode
 }
 
+// Capabilities returns the wallpaper modes that are supported by this backend,
+// if one wallpaper per output can be set and if the wallpaper is kept after
+// logging in again
+func (g3 *Gnome3) Capabilities() Capabilities {
+	return Capabilities{
+		Modes:      []string{"none", "wallpaper", "centered", "scaled", "stretched", "zoom", "spanned", "stretch", "center", "fill", "scale", "tile"},
+		PerOutput:  false,
+		Persistent: true,
+	}
+}
+
 // SetVerbose can be used for setting the verbose field to true or false.
 // This will cause this backend to output information about what is is doing on stdout.
 func (g3 *Gnome3) SetVerbose(verbose bool) {
@@ -51,9 +62,18 @@ func (g3 *Gnome3) SetVerbose(verbose bool) {
 // SetWallpaper sets the desktop wallpaper, given an image filename.
 // The image must exist and be readable.
 func (g3 *Gnome3) SetWallpaper(imageFilename string) error {
-	// Check if the image exists
-	if !exists(imageFilename) {
-		return fmt.Errorf("no such file: %s", imageFilename)
+	return g3.SetLightDarkWallpaper(imageFilename, imageFilename)
+}
+
+// SetLightDarkWallpaper sets one wallpaper for the light color scheme and one
+// for the dark color scheme. GNOME switches between them by itself when the
+// color scheme changes. The images must exist and be readable.
+func (g3 *Gnome3) SetLightDarkWallpaper(lightFilename, darkFilename string) error {
+	// Check if the images exist
+	for _, imageFilename := range []string{lightFilename, darkFilename} {
+		if !exists(imageFilename) {
+			return fmt.Errorf("no such file: %s", imageFilename)
+		}
 	}
 
 	// Check if dconf or gsettings are there, if we haven't already checked
@@ -82,11 +102,11 @@ func (g3 *Gnome3) SetWallpaper(imageFilename string) error {
 		mode = "wallpaper"
 	default:
 		// Invalid and unrecognized desktop wallpaper mode
-		return fmt.Errorf("invalid desktop wallpaper mode for GNOME3: %s", mode)
+		return fmt.Errorf("%w for GNOME3: %s", ErrUnsupportedMode, mode)
 	}
 
 	if !g3.hasGsettings {
-		return errors.New("could not find gsettings")
+		return fmt.Errorf("could not find gsettings: %w", ErrNotAvailable)
 	}
 
 	// Exit if the monitor configuration will cause artifacts when setting
@@ -103,9 +123,26 @@ func (g3 *Gnome3) SetWallpaper(imageFilename string) error {
 		}
 	}
 
-	// Set the dark desktop wallpaper (also set it if it is already set)
-	_ = g.Set("picture-uri-dark", "file://"+imageFilename)
+	// Set the dark desktop wallpaper (also set it if it is already set).
+	// GNOME before version 42 does not have this setting.
+	_ = g.Set("picture-uri-dark", "file://"+darkFilename)
 
 	// Set the desktop wallpaper (also set it if it is already set)
-	return g.Set("picture-uri", "file://"+imageFilename)
+	return g.Set("picture-uri", "file://"+lightFilename)
+}
+
+// CurrentWallpaper returns the current wallpaper, from the picture-uri
+// setting, or from picture-uri-dark if a dark color scheme is preferred
+func (g3 *Gnome3) CurrentWallpaper() (string, error) {
+	g := NewGSettings("org.gnome.desktop.background", g3.verbose)
+	uri := g.Get("picture-uri")
+	if NewGSettings("org.gnome.desktop.interface", g3.verbose).Get("color-scheme") == "prefer-dark" {
+		if darkURI := g.Get("picture-uri-dark"); darkURI != "" {
+			uri = darkURI
+		}
+	}
+	if uri == "" {
+		return "", errors.New("could not read picture-uri with gsettings")
+	}
+	return uriToFilename(uri), nil
 }
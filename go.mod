@@ -5,10 +5,13 @@ go 1.25.0
 require (
 	github.com/anthonynsimon/bild v0.14.0
 	github.com/fatih/color v1.18.0
+	github.com/srwiley/oksvg v0.0.0-20221011165216-be6e8873101c
+	github.com/srwiley/rasterx v0.0.0-20220730225603-2ab79fcdd4ef
 	github.com/stretchr/powerwalk v0.0.0-20151124150408-bceb9d014549
 	github.com/urfave/cli v1.22.17
 	github.com/xyproto/env/v2 v2.5.3
 	github.com/xyproto/heic v1.0.0
+	golang.org/x/image v0.38.0
 )
 
 require (
@@ -19,7 +22,6 @@ require (
 	github.com/mattn/go-colorable v0.1.14 // indirect
 	github.com/mattn/go-isatty v0.0.20 // indirect
 	github.com/russross/blackfriday/v2 v2.1.0 // indirect
-	golang.org/x/image v0.38.0 // indirect
 	golang.org/x/net v0.48.0 // indirect
 	golang.org/x/sys v0.39.0 // indirect
 	golang.org/x/text v0.35.0 // indirect
@@ -0,0 +1,8 @@
+//go:build cgo && heif
+// +build cgo,heif
+
+package wallutils
+
+// Decode HEIC and AVIF images with libheif, instead of converting them with
+// an external command. Build with "-tags heif" to enable this.
+import _ "github.com/xyproto/heic"
@@ -32,6 +32,17 @@ func (h *Hyprctl) SetMode(mode string) {
 	h.mode = mode
 }
 
+// Capabilities returns the wallpaper modes that are supported by this backend,
+// if one wallpaper per output can be set and if the wallpaper is kept after
+// logging in again
+func (h *Hyprctl) Capabilities() Capabilities {
+	return Capabilities{
+		Modes:      []string{"stretched", "center", "fill", "fit", "scale", "scaled", "stretch", "tile", "zoom", "zoomed"},
+		PerOutput:  false,
+		Persistent: false,
+	}
+}
+
 // SetVerbose can be used for setting the verbose field to true or false.
 // This will cause this backend to output information about what is is doing on stdout.
 func (h *Hyprctl) SetVerbose(verbose bool) {
@@ -56,7 +67,7 @@ func (h *Hyprctl) SetWallpaper(imageFilename string) error {
 		mode = ""
 	default:
 		// Invalid and unrecognized desktop wallpaper mode
-		return fmt.Errorf("invalid desktop wallpaper mode for swaybg: %s", mode)
+		return fmt.Errorf("%w for Hyprctl: %s", ErrUnsupportedMode, mode)
 	}
 
 	// preload the wallpaper image using hyprctl
@@ -59,6 +59,17 @@ func (hp *Hyprpaper) SetMode(mode string) {
 	hp.mode = mode
 }
 
+// Capabilities returns the wallpaper modes that are supported by this backend,
+// if one wallpaper per output can be set and if the wallpaper is kept after
+// logging in again
+func (hp *Hyprpaper) Capabilities() Capabilities {
+	return Capabilities{
+		Modes:      []string{"center", "tile", "fill", "stretch", "scale", "scaled", "zoom", "zoomed", "stretched", "fit"},
+		PerOutput:  true,
+		Persistent: false,
+	}
+}
+
 // SetVerbose can be used for setting the verbose field to true or false.
 // This will cause this backend to output information about what is is doing on stdout.
 func (hp *Hyprpaper) SetVerbose(verbose bool) {
@@ -68,6 +79,13 @@ func (hp *Hyprpaper) SetVerbose(verbose bool) {
 // SetWallpaper sets the desktop wallpaper, given an image filename.
 // The image must exist and be readable.
 func (hp *Hyprpaper) SetWallpaper(imageFilename string) error {
+	return hp.SetWallpaperForOutput("*", imageFilename)
+}
+
+// SetWallpaperForOutput sets the desktop wallpaper for the given monitor
+// (like "DP-1"), or for all monitors if the output name is "" or "*".
+// The image must exist and be readable.
+func (hp *Hyprpaper) SetWallpaperForOutput(output, imageFilename string) error {
 	if !exists(imageFilename) {
 		return fmt.Errorf("no such file: %s", imageFilename)
 	}
@@ -76,7 +94,7 @@ func (hp *Hyprpaper) SetWallpaper(imageFilename string) error {
 	sock, err := net.DialUnix("unix", nil, &net.UnixAddr{Name: hp.sock, Net: "unix"})
 
 	if err != nil {
-		return err
+		return fmt.Errorf("could not connect to hyprpaper: %v: %w", err, ErrNotAvailable)
 	}
 
 	defer sock.Close()
@@ -99,11 +117,15 @@ func (hp *Hyprpaper) SetWallpaper(imageFilename string) error {
 		mode = "contain:"
 	default:
 		// Invalid and unrecognized desktop wallpaper mode
-		return fmt.Errorf("invalid desktop wallpaper mode for swaybg: %s", mode)
+		return fmt.Errorf("%w for Hyprpaper: %s", ErrUnsupportedMode, mode)
 	}
 
 	// Set the wallpaper
-	if err = runHyprCmd(sock, "wallpaper ,"+mode+imageFilename); err != nil {
+	// An empty monitor name means all monitors
+	if output == "*" {
+		output = ""
+	}
+	if err = runHyprCmd(sock, "wallpaper "+output+","+mode+imageFilename); err != nil {
 		return err
 	}
 
@@ -0,0 +1,223 @@
+package wallutils
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"image"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/anthonynsimon/bild/imgio"
+	"github.com/srwiley/oksvg"
+	"github.com/srwiley/rasterx"
+	_ "golang.org/x/image/webp" // register the WebP image format
+)
+
+// ImageExtensions are the file extensions of the image formats that can be
+// used as wallpapers
+var ImageExtensions = []string{".png", ".jpg", ".jpeg", ".webp", ".svg", ".xpm", ".xbm", ".heic", ".heif", ".avif"}
+
+// commonExtensions are the file extensions of the image formats that all
+// backends can use directly
+var commonExtensions = []string{".png", ".jpg", ".jpeg"}
+
+// heifExtensions are the file extensions of HEIF based image formats
+var heifExtensions = []string{".heic", ".heif", ".avif"}
+
+// heifConverters are commands that can convert HEIC and AVIF images to PNG,
+// given the input and output filename. They are used if the image format
+// has not been registered with the image package. Build with "-tags heif"
+// to decode HEIC and AVIF images with libheif instead.
+var heifConverters = []string{"heif-dec", "heif-convert", "avifdec", "magick", "convert"}
+
+// imageExt returns the lowercase file extension of the given filename
+func imageExt(filename string) string {
+	return strings.ToLower(filepath.Ext(filename))
+}
+
+// IsImage checks if the given filename has the extension of one of the
+// image formats that can be used as a wallpaper
+func IsImage(filename string) bool {
+	return hasS(ImageExtensions, imageExt(filename))
+}
+
+// SupportsFormat checks if the given image file can be used directly by the
+// backend. PNG and JPEG images are supported by all backends.
+func (c Capabilities) SupportsFormat(filename string) bool {
+	ext := imageExt(filename)
+	return hasS(commonExtensions, ext) || hasS(c.Formats, ext)
+}
+
+// svgIcon reads the given SVG image, and returns it together with its size
+func svgIcon(filename string) (*oksvg.SvgIcon, float64, float64, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, 0, 0, err
+	}
+	defer f.Close()
+	icon, err := oksvg.ReadIconStream(f)
+	if err != nil {
+		return nil, 0, 0, fmt.Errorf("%s: %v", filename, err)
+	}
+	if icon.ViewBox.W <= 0 || icon.ViewBox.H <= 0 {
+		return nil, 0, 0, fmt.Errorf("%s: the SVG image has no size", filename)
+	}
+	return icon, icon.ViewBox.W, icon.ViewBox.H, nil
+}
+
+// renderSVG rasterizes the given SVG image so that it covers the given
+// width and height, while keeping the aspect ratio
+func renderSVG(filename string, width, height uint) (image.Image, error) {
+	icon, w, h, err := svgIcon(filename)
+	if err != nil {
+		return nil, err
+	}
+	scale := math.Max(float64(width)/w, float64(height)/h)
+	imgW, imgH := int(math.Round(w*scale)), int(math.Round(h*scale))
+	img := image.NewRGBA(image.Rect(0, 0, imgW, imgH))
+	icon.SetTarget(0, 0, float64(imgW), float64(imgH))
+	scanner := rasterx.NewScannerGV(imgW, imgH, img, img.Bounds())
+	icon.Draw(rasterx.NewDasher(imgW, imgH, scanner), 1)
+	return img, nil
+}
+
+// decodeWithCommand converts the given image to PNG with one of the
+// heifConverters, and then decodes it
+func decodeWithCommand(filename string) (image.Image, error) {
+	tempDir, err := os.MkdirTemp("", "wallutils")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(tempDir)
+	pngFilename := filepath.Join(tempDir, "converted.png")
+	var tried []string
+	for _, converter := range heifConverters {
+		if which(converter) == "" || (converter == "avifdec" && imageExt(filename) != ".avif") {
+			continue
+		}
+		tried = append(tried, converter)
+		if err := run(converter, []string{filename, pngFilename}, false); err == nil && exists(pngFilename) {
+			return imgio.Open(pngFilename)
+		}
+	}
+	if len(tried) == 0 {
+		return nil, fmt.Errorf("%s: install heif-dec, heif-convert or ImageMagick, or build with -tags heif, for reading %s images", filename, strings.ToUpper(imageExt(filename)[1:]))
+	}
+	return nil, fmt.Errorf("%s: could not convert the image with %s", filename, strings.Join(tried, " or "))
+}
+
+// OpenImage reads an image file in any of the formats in ImageExtensions.
+// SVG images are rendered so that they cover the given width and height.
+func OpenImage(filename string, width, height uint) (image.Image, error) {
+	if imageExt(filename) == ".svg" {
+		return renderSVG(filename, width, height)
+	}
+	img, err := imgio.Open(filename)
+	if errors.Is(err, image.ErrFormat) && hasS(heifExtensions, imageExt(filename)) {
+		return decodeWithCommand(filename)
+	}
+	return img, err
+}
+
+// heifSize returns the size of the largest image in a HEIF based image file,
+// like HEIC or AVIF, from the "ispe" (image spatial extents) properties
+func heifSize(r io.Reader) (uint, uint, error) {
+	// The properties are in the "meta" box, which is usually at the start of the file
+	data, err := io.ReadAll(io.LimitReader(r, 1<<20))
+	if err != nil {
+		return 0, 0, err
+	}
+	var width, height uint
+	for i := 0; ; {
+		j := bytes.Index(data[i:], []byte("ispe"))
+		if j < 0 {
+			break
+		}
+		pos := i + j + 4 + 4 // skip the box type, version and flags
+		if pos+8 > len(data) {
+			break
+		}
+		w, h := uint(binary.BigEndian.Uint32(data[pos:])), uint(binary.BigEndian.Uint32(data[pos+4:]))
+		if w*h > width*height {
+			width, height = w, h
+		}
+		i = pos
+	}
+	if width == 0 || height == 0 {
+		return 0, 0, errors.New("could not find the image size")
+	}
+	return width, height, nil
+}
+
+// ImageSize returns the width and height of an image file in any of the
+// formats in ImageExtensions, without reading the entire file, if possible
+func ImageSize(filename string) (uint, uint, error) {
+	if imageExt(filename) == ".svg" {
+		_, w, h, err := svgIcon(filename)
+		if err != nil {
+			return 0, 0, err
+		}
+		return uint(math.Round(w)), uint(math.Round(h)), nil
+	}
+	f, err := os.Open(filename)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer f.Close()
+	config, _, err := image.DecodeConfig(f)
+	if errors.Is(err, image.ErrFormat) && hasS(heifExtensions, imageExt(filename)) {
+		if _, err := f.Seek(0, io.SeekStart); err != nil {
+			return 0, 0, err
+		}
+		width, height, err := heifSize(f)
+		if err != nil {
+			return 0, 0, fmt.Errorf("%s: %v", filename, err)
+		}
+		return width, height, nil
+	}
+	if err != nil {
+		return 0, 0, fmt.Errorf("%s: %v", filename, err)
+	}
+	return uint(config.Width), uint(config.Height), nil
+}
+
+// ConvertToPNG returns the filename of a PNG version of the given image, in
+// the cache directory, for backends that can not read the image format.
+// PNG and JPEG images are returned as they are. SVG images are rendered for
+// the resolution of the largest monitor. The converted image is reused until
+// the original image is changed.
+func ConvertToPNG(imageFilename string) (string, error) {
+	if hasS(commonExtensions, imageExt(imageFilename)) {
+		return imageFilename, nil
+	}
+	absFilename, err := filepath.Abs(imageFilename)
+	if err != nil {
+		return "", err
+	}
+	var width, height uint
+	description := "converted"
+	if imageExt(absFilename) == ".svg" {
+		width, height = largestMonitorSize()
+		description = fmt.Sprintf("converted_%dx%d", width, height)
+	}
+	cachedFilename, err := renderCacheFilename(absFilename, description)
+	if err != nil {
+		return "", err
+	}
+	if cachedRender(cachedFilename) {
+		return cachedFilename, nil
+	}
+	img, err := OpenImage(absFilename, width, height)
+	if err != nil {
+		return "", err
+	}
+	if err := saveRendered(cachedFilename, img); err != nil {
+		return "", err
+	}
+	return cachedFilename, nil
+}
@@ -0,0 +1,179 @@
+package wallutils
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/color"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const testXPM = `/* XPM */
+static char * test_xpm[] = {
+"4 2 3 1",
+"  c None",
+". c #FF0000",
+"+ c light blue m white",
+" .++",
+"..  "};
+`
+
+const testSVG = `<svg xmlns="http://www.w3.org/2000/svg" viewBox="0 0 40 20">
+<rect x="0" y="0" width="20" height="20" fill="#ff0000"/>
+<rect x="20" y="0" width="20" height="20" fill="#0000ff"/>
+</svg>
+`
+
+func TestDecodeXPM(t *testing.T) {
+	img, format, err := image.Decode(strings.NewReader(testXPM))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if format != "xpm" {
+		t.Errorf("expected the xpm format, got %s", format)
+	}
+	if img.Bounds().Dx() != 4 || img.Bounds().Dy() != 2 {
+		t.Fatalf("unexpected size: %v", img.Bounds())
+	}
+	if _, _, _, a := img.At(0, 0).RGBA(); a != 0 {
+		t.Errorf("expected a transparent pixel, got %v", img.At(0, 0))
+	}
+	expectColor(t, img, 1, 0, red)
+	expectColor(t, img, 2, 0, color.RGBA{0xad, 0xd8, 0xe6, 255})
+	expectColor(t, img, 0, 1, red)
+
+	config, _, err := image.DecodeConfig(strings.NewReader(testXPM))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if config.Width != 4 || config.Height != 2 {
+		t.Errorf("unexpected size: %dx%d", config.Width, config.Height)
+	}
+
+	if _, err := DecodeXPM(strings.NewReader("/* XPM */\n\"2 1 1 1\",\"a c #000000\",\"ab\"")); err == nil {
+		t.Error("expected an error for an unknown color")
+	}
+}
+
+func TestDecodeXBMImage(t *testing.T) {
+	const xbm = "#define a_width 2\n#define a_height 1\nstatic char a_bits[] = { 0x01 };\n"
+	img, format, err := image.Decode(strings.NewReader(xbm))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if format != "xbm" {
+		t.Errorf("expected the xbm format, got %s", format)
+	}
+	expectColor(t, img, 0, 0, color.RGBA{0, 0, 0, 255})
+	expectColor(t, img, 1, 0, color.RGBA{255, 255, 255, 255})
+}
+
+func TestSVG(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "test.svg")
+	if err := os.WriteFile(filename, []byte(testSVG), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	width, height, err := ImageSize(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if width != 40 || height != 20 {
+		t.Errorf("unexpected size: %dx%d", width, height)
+	}
+	// The image covers 100x100 pixels, and keeps the aspect ratio
+	img, err := OpenImage(filename, 100, 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if img.Bounds().Dx() != 200 || img.Bounds().Dy() != 100 {
+		t.Fatalf("unexpected size: %v", img.Bounds())
+	}
+	expectColor(t, img, 50, 50, red)
+	expectColor(t, img, 150, 50, blue)
+}
+
+// testHEIF returns the start of a HEIF file with a thumbnail and a larger image
+func testHEIF() []byte {
+	var b bytes.Buffer
+	b.WriteString("\x00\x00\x00\x18ftypheic\x00\x00\x00\x00mif1heic")
+	for _, size := range [][2]uint32{{320, 240}, {4032, 3024}} {
+		b.Write([]byte{0, 0, 0, 20})
+		b.WriteString("ispe")
+		b.Write([]byte{0, 0, 0, 0})
+		binary.Write(&b, binary.BigEndian, size)
+	}
+	return b.Bytes()
+}
+
+func TestHEIFSize(t *testing.T) {
+	width, height, err := heifSize(bytes.NewReader(testHEIF()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if width != 4032 || height != 3024 {
+		t.Errorf("unexpected size: %dx%d", width, height)
+	}
+	if _, _, err := heifSize(strings.NewReader("no image here")); err == nil {
+		t.Error("expected an error when there is no size")
+	}
+}
+
+func TestConvertToPNG(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	dir := t.TempDir()
+	filename := filepath.Join(dir, "test.xpm")
+	if err := os.WriteFile(filename, []byte(testXPM), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	converted, err := ConvertToPNG(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if filepath.Ext(converted) != ".png" || !exists(converted) {
+		t.Fatalf("expected a PNG image, got %s", converted)
+	}
+	img, err := OpenImage(converted, 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectColor(t, img, 1, 0, red)
+
+	// PNG and JPEG images are not converted
+	if converted, err := ConvertToPNG("/a.jpg"); err != nil || converted != "/a.jpg" {
+		t.Errorf("expected /a.jpg, got %s (%v)", converted, err)
+	}
+
+	// The X11 backend can read XPM images, Feh can only read PNG and JPEG
+	if !(&X11{}).Capabilities().SupportsFormat(filename) {
+		t.Error("the X11 backend should support XPM images")
+	}
+	if (&Feh{}).Capabilities().SupportsFormat(filename) || !(&Feh{}).Capabilities().SupportsFormat("a.PNG") {
+		t.Error("the Feh backend should only support PNG and JPEG images")
+	}
+}
+
+func TestFindImagesAt(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"a.png", "b.webp", "c.svg", "d.txt"} {
+		if err := os.WriteFile(filepath.Join(dir, name), nil, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	found, err := FindImagesAt(dir, nil, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(found) != 3 {
+		t.Errorf("expected 3 images, got %v", found)
+	}
+	found, err = FindImagesAt(dir, []string{".png"}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(found) != 1 || filepath.Base(found[0]) != "a.png" {
+		t.Errorf("expected a.png, got %v", found)
+	}
+}
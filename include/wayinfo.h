@@ -24,6 +24,7 @@
 
 #pragma once
 
+#include <stdarg.h>
 #include <stdbool.h>
 #include <stdio.h>
 #include <stdlib.h>
@@ -31,19 +32,46 @@
 #include <sys/types.h>
 #include <wayland-client.h>
 
-/* This file uses a buffer that is estimated to be large enough to collect all
- * required information.
- */
+#include "xdg-output.h"
 
-#define BUF buf + strlen(buf)
-#define RETBUF                                                                 \
-    char* str = calloc(strlen(buf) + 1, sizeof(char));                         \
-    strcpy(str, buf);                                                          \
-    return str
+/* A string buffer that grows as needed, for collecting the info string */
+struct strbuf {
+    char* data;
+    size_t len;
+    size_t cap;
+};
 
-static char buf[10240];
+static void bprintf(struct strbuf* sb, const char* format, ...)
+{
+    va_list args;
+    int n;
+
+    va_start(args, format);
+    n = vsnprintf(NULL, 0, format, args);
+    va_end(args);
+    if (n < 0) {
+        return;
+    }
+    if (sb->len + n + 1 > sb->cap) {
+        size_t cap = sb->cap ? sb->cap : 1024;
+        while (sb->len + n + 1 > cap) {
+            cap *= 2;
+        }
+        char* data = realloc(sb->data, cap);
+        if (!data) {
+            return;
+        }
+        sb->data = data;
+        sb->cap = cap;
+    }
+    va_start(args, format);
+    vsnprintf(sb->data + sb->len, sb->cap - sb->len, format, args);
+    va_end(args);
+    sb->len += n;
+}
 
-typedef void (*sprint_info_t)(void* info);
+typedef void (*sprint_info_t)(struct strbuf* sb, void* info);
+typedef void (*destroy_info_t)(void* info);
 
 struct global_info {
     struct wl_list link;
@@ -53,6 +81,7 @@ struct global_info {
     char* interface;
 
     sprint_info_t sprint;
+    destroy_info_t destroy;
 };
 
 struct output_mode {
@@ -77,6 +106,16 @@ struct output_info {
         char* model;
     } geometry;
 
+    int32_t scale;
+    char* name;
+    char* description;
+
+    /* from xdg_output, if available */
+    struct zxdg_output_v1* xdg_output;
+    bool has_logical;
+    int32_t logical_x, logical_y;
+    int32_t logical_width, logical_height;
+
     struct wl_list modes;
 };
 
@@ -103,37 +142,61 @@ struct seat_info {
 struct weston_info {
     struct wl_display* display;
     struct wl_registry* registry;
+    struct zxdg_output_manager_v1* xdg_output_manager;
 
     struct wl_list infos;
     bool roundtrip_needed;
 };
 
-void sprint_global_info(void* data)
+/* wayland_output is a record with information about one output,
+ * that is returned by WaylandOutputs */
+struct wayland_output {
+    int32_t x, y;
+    int32_t physical_width, physical_height;
+    int32_t transform;
+    int32_t scale;
+    int32_t width, height; /* current mode, in pixels */
+    int32_t refresh; /* current mode, in mHz */
+    bool has_logical;
+    int32_t logical_x, logical_y;
+    int32_t logical_width, logical_height;
+    char* name;
+    char* description;
+    char* make;
+    char* model;
+};
+
+static char* strdup_or_null(const char* s) { return s ? strdup(s) : NULL; }
+
+void sprint_global_info(struct strbuf* sb, void* data)
 {
     struct global_info* global = data;
 
-    sprintf(BUF, "interface: '%s', version: %u, name: %u\n", global->interface, global->version,
+    bprintf(sb, "interface: '%s', version: %u, name: %u\n", global->interface, global->version,
         global->id);
 }
 
+static void destroy_global_info(void* data) { }
+
 static void init_global_info(struct weston_info* info, struct global_info* global, uint32_t id,
     const char* interface, uint32_t version)
 {
     global->id = id;
     global->version = version;
     global->interface = strdup(interface);
+    global->destroy = destroy_global_info;
 
     wl_list_insert(info->infos.prev, &global->link);
 }
 
-void sprint_output_info(void* data)
+void sprint_output_info(struct strbuf* sb, void* data)
 {
     struct output_info* output = data;
     struct output_mode* mode;
     const char* subpixel_orientation;
     const char* transform;
 
-    sprint_global_info(data);
+    sprint_global_info(sb, data);
 
     switch (output->geometry.subpixel) {
     case WL_OUTPUT_SUBPIXEL_UNKNOWN:
@@ -191,61 +254,69 @@ void sprint_output_info(void* data)
         break;
     }
 
-    sprintf(BUF, "\tx: %d, y: %d,\n", output->geometry.x, output->geometry.y);
-    sprintf(BUF, "\tphysical_width: %d mm, physical_height: %d mm,\n",
+    bprintf(sb, "\tx: %d, y: %d,\n", output->geometry.x, output->geometry.y);
+    bprintf(sb, "\tphysical_width: %d mm, physical_height: %d mm,\n",
         output->geometry.physical_width, output->geometry.physical_height);
-    sprintf(BUF, "\tmake: '%s', model: '%s',\n", output->geometry.make, output->geometry.model);
-    sprintf(BUF, "\tsubpixel_orientation: %s, output_transform: %s,\n", subpixel_orientation,
+    bprintf(sb, "\tmake: '%s', model: '%s',\n", output->geometry.make, output->geometry.model);
+    bprintf(sb, "\tsubpixel_orientation: %s, output_transform: %s,\n", subpixel_orientation,
         transform);
+    bprintf(sb, "\tname: '%s', description: '%s',\n", output->name ? output->name : "",
+        output->description ? output->description : "");
+    bprintf(sb, "\tscale: %d,\n", output->scale);
+    if (output->has_logical) {
+        bprintf(sb, "\tlogical_x: %d, logical_y: %d,\n", output->logical_x, output->logical_y);
+        bprintf(sb, "\tlogical_width: %d, logical_height: %d,\n", output->logical_width,
+            output->logical_height);
+    }
 
     wl_list_for_each(mode, &output->modes, link)
     {
-        sprintf(BUF, "\tmode:\n");
-        sprintf(BUF, "\t\twidth: %d px, height: %d px, refresh: %.f Hz,\n", mode->width,
+        bprintf(sb, "\tmode:\n");
+        bprintf(sb, "\t\twidth: %d px, height: %d px, refresh: %.2f Hz,\n", mode->width,
             mode->height, (float)mode->refresh / 1000);
-        sprintf(BUF, "\t\tflags:");
+        bprintf(sb, "\t\tflags:");
         if (mode->flags & WL_OUTPUT_MODE_CURRENT) {
-            sprintf(BUF, " current");
+            bprintf(sb, " current");
         }
         if (mode->flags & WL_OUTPUT_MODE_PREFERRED) {
-            sprintf(BUF, " preferred");
+            bprintf(sb, " preferred");
         }
-        sprintf(BUF, "\n");
+        bprintf(sb, "\n");
     }
 }
 
-void sprint_shm_info(void* data)
+void sprint_shm_info(struct strbuf* sb, void* data)
 {
     struct shm_info* shm = data;
     struct shm_format* format;
 
-    sprint_global_info(data);
-    sprintf(BUF, "%s", "\tformats:");
+    sprint_global_info(sb, data);
+    bprintf(sb, "%s", "\tformats:");
 
     wl_list_for_each(format, &shm->formats, link)
-        sprintf(BUF, " %s", (format->format == WL_SHM_FORMAT_ARGB8888) ? "ARGB8888" : "XRGB8888");
+        bprintf(sb, " %s", (format->format == WL_SHM_FORMAT_ARGB8888) ? "ARGB8888" : "XRGB8888");
 
-    sprintf(BUF, "\n");
+    bprintf(sb, "\n");
 }
 
-void sprint_seat_info(void* data)
+void sprint_seat_info(struct strbuf* sb, void* data)
 {
     struct seat_info* seat = data;
 
-    sprint_global_info(data);
-    sprintf(BUF, "%s", "\tcapabilities:");
+    sprint_global_info(sb, data);
+    bprintf(sb, "%s", "\tcapabilities:");
 
     if (seat->capabilities & WL_SEAT_CAPABILITY_POINTER) {
-        sprintf(BUF, " pointer");
+        bprintf(sb, " pointer");
     }
     if (seat->capabilities & WL_SEAT_CAPABILITY_KEYBOARD) {
-        sprintf(BUF, " keyboard");
+        bprintf(sb, " keyboard");
     }
     if (seat->capabilities & WL_SEAT_CAPABILITY_TOUCH) {
-        sprintf(BUF, " touch");
+        bprintf(sb, " touch");
     }
 
-    sprintf(BUF, "\n");
+    bprintf(sb, "\n");
 }
 
 static void seat_handle_capabilities(
@@ -259,12 +330,21 @@ static const struct wl_seat_listener seat_listener = {
     seat_handle_capabilities,
 };
 
+static void destroy_seat_info(void* data)
+{
+    struct seat_info* seat = data;
+
+    wl_seat_destroy(seat->seat);
+}
+
 static void add_seat_info(struct weston_info* info, uint32_t id, uint32_t version)
 {
     struct seat_info* seat = malloc(sizeof *seat);
 
     init_global_info(info, &seat->global, id, "wl_seat", version);
     seat->global.sprint = sprint_seat_info;
+    seat->global.destroy = destroy_seat_info;
+    seat->capabilities = 0;
 
     seat->seat = wl_registry_bind(info->registry, id, &wl_seat_interface, 1);
     wl_seat_add_listener(seat->seat, &seat_listener, seat);
@@ -285,12 +365,26 @@ static const struct wl_shm_listener shm_listener = {
     shm_handle_format,
 };
 
+static void destroy_shm_info(void* data)
+{
+    struct shm_info* shm = data;
+    struct shm_format *format, *tmp;
+
+    wl_list_for_each_safe(format, tmp, &shm->formats, link)
+    {
+        wl_list_remove(&format->link);
+        free(format);
+    }
+    wl_shm_destroy(shm->shm);
+}
+
 static void add_shm_info(struct weston_info* info, uint32_t id, uint32_t version)
 {
     struct shm_info* shm = malloc(sizeof *shm);
 
     init_global_info(info, &shm->global, id, "wl_shm", version);
     shm->global.sprint = sprint_shm_info;
+    shm->global.destroy = destroy_shm_info;
     wl_list_init(&shm->formats);
 
     shm->shm = wl_registry_bind(info->registry, id, &wl_shm_interface, 1);
@@ -310,8 +404,10 @@ static void output_handle_geometry(void* data, struct wl_output* wl_output, int3
     output->geometry.physical_width = physical_width;
     output->geometry.physical_height = physical_height;
     output->geometry.subpixel = subpixel;
-    output->geometry.make = strdup(make);
-    output->geometry.model = strdup(model);
+    free(output->geometry.make);
+    output->geometry.make = strdup_or_null(make);
+    free(output->geometry.model);
+    output->geometry.model = strdup_or_null(model);
     output->geometry.output_transform = output_transform;
 }
 
@@ -329,26 +425,144 @@ static void output_handle_mode(void* data, struct wl_output* wl_output, uint32_t
     wl_list_insert(output->modes.prev, &mode->link);
 }
 
+static void output_handle_done(void* data, struct wl_output* wl_output) { }
+
+static void output_handle_scale(void* data, struct wl_output* wl_output, int32_t scale)
+{
+    struct output_info* output = data;
+
+    output->scale = scale;
+}
+
+static void output_handle_name(void* data, struct wl_output* wl_output, const char* name)
+{
+    struct output_info* output = data;
+
+    free(output->name);
+    output->name = strdup_or_null(name);
+}
+
+static void output_handle_description(
+    void* data, struct wl_output* wl_output, const char* description)
+{
+    struct output_info* output = data;
+
+    free(output->description);
+    output->description = strdup_or_null(description);
+}
+
 static const struct wl_output_listener output_listener = {
     output_handle_geometry,
     output_handle_mode,
+    output_handle_done,
+    output_handle_scale,
+    output_handle_name,
+    output_handle_description,
+};
+
+static void xdg_output_handle_logical_position(
+    void* data, struct zxdg_output_v1* xdg_output, int32_t x, int32_t y)
+{
+    struct output_info* output = data;
+
+    output->has_logical = true;
+    output->logical_x = x;
+    output->logical_y = y;
+}
+
+static void xdg_output_handle_logical_size(
+    void* data, struct zxdg_output_v1* xdg_output, int32_t width, int32_t height)
+{
+    struct output_info* output = data;
+
+    output->has_logical = true;
+    output->logical_width = width;
+    output->logical_height = height;
+}
+
+static void xdg_output_handle_done(void* data, struct zxdg_output_v1* xdg_output) { }
+
+static void xdg_output_handle_name(void* data, struct zxdg_output_v1* xdg_output, const char* name)
+{
+    struct output_info* output = data;
+
+    /* wl_output.name is preferred, but is only available from wl_output version 4 */
+    if (!output->name) {
+        output->name = strdup_or_null(name);
+    }
+}
+
+static void xdg_output_handle_description(
+    void* data, struct zxdg_output_v1* xdg_output, const char* description)
+{
+    struct output_info* output = data;
+
+    if (!output->description) {
+        output->description = strdup_or_null(description);
+    }
+}
+
+static const struct zxdg_output_v1_listener xdg_output_listener = {
+    xdg_output_handle_logical_position,
+    xdg_output_handle_logical_size,
+    xdg_output_handle_done,
+    xdg_output_handle_name,
+    xdg_output_handle_description,
 };
 
+static void destroy_output_info(void* data)
+{
+    struct output_info* output = data;
+    struct output_mode *mode, *tmp;
+
+    wl_list_for_each_safe(mode, tmp, &output->modes, link)
+    {
+        wl_list_remove(&mode->link);
+        free(mode);
+    }
+    if (output->xdg_output) {
+        zxdg_output_v1_destroy(output->xdg_output);
+    }
+    wl_output_destroy(output->output);
+    free(output->geometry.make);
+    free(output->geometry.model);
+    free(output->name);
+    free(output->description);
+}
+
 static void add_output_info(struct weston_info* info, uint32_t id, uint32_t version)
 {
-    struct output_info* output = malloc(sizeof *output);
+    struct output_info* output = calloc(1, sizeof *output);
 
     init_global_info(info, &output->global, id, "wl_output", version);
     output->global.sprint = sprint_output_info;
+    output->global.destroy = destroy_output_info;
 
     wl_list_init(&output->modes);
 
-    output->output = wl_registry_bind(info->registry, id, &wl_output_interface, 1);
+    output->scale = 1;
+
+    /* version 2 adds the scale event, version 4 adds the name and description events */
+    output->output
+        = wl_registry_bind(info->registry, id, &wl_output_interface, version < 4 ? version : 4);
     wl_output_add_listener(output->output, &output_listener, output);
 
     info->roundtrip_needed = true;
 }
 
+static void add_xdg_output_manager_info(struct weston_info* info, uint32_t id, uint32_t version)
+{
+    struct global_info* global = malloc(sizeof *global);
+
+    init_global_info(info, global, id, "zxdg_output_manager_v1", version);
+    global->sprint = sprint_global_info;
+
+    info->xdg_output_manager = wl_registry_bind(
+        info->registry, id, &zxdg_output_manager_v1_interface, version < 3 ? version : 3);
+
+    info->roundtrip_needed = true;
+}
+
 static void add_global_info(
     struct weston_info* info, uint32_t id, const char* interface, uint32_t version)
 {
@@ -369,19 +583,83 @@ static void global_handler(
         add_shm_info(info, id, version);
     else if (!strcmp(interface, "wl_output"))
         add_output_info(info, id, version);
+    else if (!strcmp(interface, "zxdg_output_manager_v1") && !info->xdg_output_manager)
+        add_xdg_output_manager_info(info, id, version);
     else
         add_global_info(info, id, interface, version);
 }
 
-static void global_remove_handler(void* data, struct wl_registry* registry, uint32_t name) {}
+static void global_remove_handler(void* data, struct wl_registry* registry, uint32_t name) { }
 
 static const struct wl_registry_listener registry_listener
     = { global_handler, global_remove_handler };
 
-void sprint_infos(struct wl_list* infos)
+void sprint_infos(struct strbuf* sb, struct wl_list* infos)
 {
     struct global_info* info;
-    wl_list_for_each(info, infos, link) info->sprint(info);
+    wl_list_for_each(info, infos, link) info->sprint(sb, info);
+}
+
+/* collect_infos connects to the Wayland server and collects information
+ * about all globals. Returns false if it was not possible to connect. */
+static bool collect_infos(struct weston_info* info)
+{
+    struct global_info* global;
+
+    info->xdg_output_manager = NULL;
+    wl_list_init(&info->infos);
+
+    info->display = wl_display_connect(NULL);
+    if (!info->display) {
+        return false;
+    }
+
+    info->registry = wl_display_get_registry(info->display);
+    wl_registry_add_listener(info->registry, &registry_listener, info);
+
+    do {
+        info->roundtrip_needed = false;
+        if (wl_display_roundtrip(info->display) < 0) {
+            break;
+        }
+
+        /* Ask for the logical position and size of all outputs, if possible */
+        if (info->xdg_output_manager) {
+            wl_list_for_each(global, &info->infos, link)
+            {
+                struct output_info* output = (struct output_info*)global;
+                if (strcmp(global->interface, "wl_output") || output->xdg_output) {
+                    continue;
+                }
+                output->xdg_output = zxdg_output_manager_v1_get_xdg_output(
+                    info->xdg_output_manager, output->output);
+                zxdg_output_v1_add_listener(output->xdg_output, &xdg_output_listener, output);
+                info->roundtrip_needed = true;
+            }
+        }
+    } while (info->roundtrip_needed);
+
+    return true;
+}
+
+/* destroy_infos frees everything that was allocated by collect_infos, and
+ * disconnects from the Wayland server */
+static void destroy_infos(struct weston_info* info)
+{
+    struct global_info *global, *tmp;
+
+    wl_list_for_each_safe(global, tmp, &info->infos, link)
+    {
+        wl_list_remove(&global->link);
+        global->destroy(global);
+        free(global->interface);
+        free(global);
+    }
+    if (info->xdg_output_manager) {
+        zxdg_output_manager_v1_destroy(info->xdg_output_manager);
+    }
+    wl_registry_destroy(info->registry);
+    wl_display_disconnect(info->display);
 }
 
 bool WaylandRunning()
@@ -396,36 +674,105 @@ bool WaylandRunning()
 
 char* WaylandInfoString()
 {
-    buf[0] = 0;
-    buf[1] = 0;
-
+    struct strbuf sb = { NULL, 0, 0 };
     struct weston_info info;
 
-    info.display = wl_display_connect(NULL);
-    if (!info.display) {
-        sprintf(BUF, "wayland plugin info string: wayland is not in use right now\n");
-        RETBUF;
+    if (!collect_infos(&info)) {
+        bprintf(&sb, "wayland plugin info string: wayland is not in use right now\n");
+        return sb.data;
     }
 
-    wl_list_init(&info.infos);
+    sprint_infos(&sb, &info.infos);
 
-    info.registry = wl_display_get_registry(info.display);
-    wl_registry_add_listener(info.registry, &registry_listener, &info);
+    destroy_infos(&info);
 
-    do {
-        info.roundtrip_needed = false;
-        wl_display_roundtrip(info.display);
-    } while (info.roundtrip_needed);
+    if (!sb.data) {
+        return calloc(1, sizeof(char));
+    }
+    return sb.data;
+}
+
+/* WaylandOutputs connects to the Wayland server and returns the number of
+ * outputs, or -1 if it was not possible to connect. The given pointer is set
+ * to a newly allocated array with one record per output, which must be freed
+ * with WaylandFreeOutputs. */
+int WaylandOutputs(struct wayland_output** outputs)
+{
+    struct weston_info info;
+    struct global_info* global;
+    struct output_mode* mode;
+    int count = 0;
+    int i = 0;
 
-    sprint_infos(&info.infos);
+    *outputs = NULL;
 
-    if (info.display) {
-        wl_display_disconnect(info.display);
+    if (!collect_infos(&info)) {
+        return -1;
     }
 
-    char* str = calloc(strlen(buf) + 1, sizeof(char));
-    strcpy(str, buf);
-    buf[0] = 0;
-    buf[1] = 0;
-    return str;
+    wl_list_for_each(global, &info.infos, link)
+    {
+        if (!strcmp(global->interface, "wl_output")) {
+            count++;
+        }
+    }
+
+    if (count > 0) {
+        *outputs = calloc(count, sizeof(struct wayland_output));
+    }
+
+    wl_list_for_each(global, &info.infos, link)
+    {
+        if (strcmp(global->interface, "wl_output") || !*outputs) {
+            continue;
+        }
+        struct output_info* output = (struct output_info*)global;
+        struct wayland_output* o = &(*outputs)[i++];
+
+        o->x = output->geometry.x;
+        o->y = output->geometry.y;
+        o->physical_width = output->geometry.physical_width;
+        o->physical_height = output->geometry.physical_height;
+        o->transform = output->geometry.output_transform;
+        o->scale = output->scale;
+        o->has_logical = output->has_logical;
+        o->logical_x = output->logical_x;
+        o->logical_y = output->logical_y;
+        o->logical_width = output->logical_width;
+        o->logical_height = output->logical_height;
+        o->name = strdup_or_null(output->name);
+        o->description = strdup_or_null(output->description);
+        o->make = strdup_or_null(output->geometry.make);
+        o->model = strdup_or_null(output->geometry.model);
+
+        wl_list_for_each(mode, &output->modes, link)
+        {
+            if (mode->flags & WL_OUTPUT_MODE_CURRENT) {
+                o->width = mode->width;
+                o->height = mode->height;
+                o->refresh = mode->refresh;
+            }
+        }
+    }
+
+    destroy_infos(&info);
+
+    return count;
+}
+
+/* WaylandFreeOutputs frees an array that was returned by WaylandOutputs */
+void WaylandFreeOutputs(struct wayland_output* outputs, int count)
+{
+    int i;
+
+    if (!outputs) {
+        return;
+    }
+    for (i = 0; i < count; i++) {
+        free(outputs[i].name);
+        free(outputs[i].description);
+        free(outputs[i].make);
+        free(outputs[i].model);
+    }
+    free(outputs);
 }
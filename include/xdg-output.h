@@ -0,0 +1,114 @@
+/*
+ * Client side of the xdg_output_unstable_v1 protocol, written in the same way
+ * as the code that wayland-scanner generates, so that wayland-scanner and the
+ * protocol XML files are not needed when building.
+ *
+ * Copyright © 2017 Red Hat Inc.
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a
+ * copy of this software and associated documentation files (the "Software"),
+ * to deal in the Software without restriction, including without limitation
+ * the rights to use, copy, modify, merge, publish, distribute, sublicense,
+ * and/or sell copies of the Software, and to permit persons to whom the
+ * Software is furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice (including the next
+ * paragraph) shall be included in all copies or substantial portions of the
+ * Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.  IN NO EVENT SHALL
+ * THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+ * FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER
+ * DEALINGS IN THE SOFTWARE.
+ */
+
+#pragma once
+
+#include <stddef.h>
+#include <stdint.h>
+#include <wayland-client.h>
+
+struct zxdg_output_manager_v1;
+struct zxdg_output_v1;
+
+static const struct wl_interface zxdg_output_v1_interface;
+
+static const struct wl_interface* xdg_output_unstable_v1_types[] = {
+    NULL,
+    NULL,
+    &zxdg_output_v1_interface,
+    &wl_output_interface,
+};
+
+static const struct wl_message zxdg_output_manager_v1_requests[] = {
+    { "destroy", "", xdg_output_unstable_v1_types + 0 },
+    { "get_xdg_output", "no", xdg_output_unstable_v1_types + 2 },
+};
+
+static const struct wl_interface zxdg_output_manager_v1_interface = {
+    "zxdg_output_manager_v1", 3, 2, zxdg_output_manager_v1_requests, 0, NULL,
+};
+
+static const struct wl_message zxdg_output_v1_requests[] = {
+    { "destroy", "", xdg_output_unstable_v1_types + 0 },
+};
+
+static const struct wl_message zxdg_output_v1_events[] = {
+    { "logical_position", "ii", xdg_output_unstable_v1_types + 0 },
+    { "logical_size", "ii", xdg_output_unstable_v1_types + 0 },
+    { "done", "", xdg_output_unstable_v1_types + 0 },
+    { "name", "2s", xdg_output_unstable_v1_types + 0 },
+    { "description", "2s", xdg_output_unstable_v1_types + 0 },
+};
+
+static const struct wl_interface zxdg_output_v1_interface = {
+    "zxdg_output_v1", 3, 1, zxdg_output_v1_requests, 5, zxdg_output_v1_events,
+};
+
+#define ZXDG_OUTPUT_MANAGER_V1_DESTROY 0
+#define ZXDG_OUTPUT_MANAGER_V1_GET_XDG_OUTPUT 1
+#define ZXDG_OUTPUT_V1_DESTROY 0
+
+struct zxdg_output_v1_listener {
+    void (*logical_position)(void* data, struct zxdg_output_v1* zxdg_output_v1, int32_t x, int32_t y);
+    void (*logical_size)(
+        void* data, struct zxdg_output_v1* zxdg_output_v1, int32_t width, int32_t height);
+    void (*done)(void* data, struct zxdg_output_v1* zxdg_output_v1);
+    void (*name)(void* data, struct zxdg_output_v1* zxdg_output_v1, const char* name);
+    void (*description)(
+        void* data, struct zxdg_output_v1* zxdg_output_v1, const char* description);
+};
+
+static inline void zxdg_output_manager_v1_destroy(
+    struct zxdg_output_manager_v1* zxdg_output_manager_v1)
+{
+    wl_proxy_marshal((struct wl_proxy*)zxdg_output_manager_v1, ZXDG_OUTPUT_MANAGER_V1_DESTROY);
+    wl_proxy_destroy((struct wl_proxy*)zxdg_output_manager_v1);
+}
+
+static inline struct zxdg_output_v1* zxdg_output_manager_v1_get_xdg_output(
+    struct zxdg_output_manager_v1* zxdg_output_manager_v1, struct wl_output* output)
+{
+    struct wl_proxy* id;
+
+    id = wl_proxy_marshal_constructor((struct wl_proxy*)zxdg_output_manager_v1,
+        ZXDG_OUTPUT_MANAGER_V1_GET_XDG_OUTPUT, &zxdg_output_v1_interface, NULL, output);
+
+    return (struct zxdg_output_v1*)id;
+}
+
+static inline int zxdg_output_v1_add_listener(struct zxdg_output_v1* zxdg_output_v1,
+    const struct zxdg_output_v1_listener* listener, void* data)
+{
+    return wl_proxy_add_listener(
+        (struct wl_proxy*)zxdg_output_v1, (void (**)(void))listener, data);
+}
+
+static inline void zxdg_output_v1_destroy(struct zxdg_output_v1* zxdg_output_v1)
+{
+    wl_proxy_marshal((struct wl_proxy*)zxdg_output_v1, ZXDG_OUTPUT_V1_DESTROY);
+    wl_proxy_destroy((struct wl_proxy*)zxdg_output_v1);
+}
@@ -0,0 +1,173 @@
+package wallutils
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/xyproto/wallutils/pkg/gnometimed"
+	"github.com/xyproto/wallutils/pkg/simpletimed"
+)
+
+// indexVersion is increased whenever the format of the index changes, so
+// that an old index is not used
+const indexVersion = 4
+
+// indexEntry is what was found when reading a file, together with the
+// modification time and size of the file when it was read. Images that are
+// too small or can not be read have no Wallpaper, so that they are not read
+// again until they are changed.
+type indexEntry struct {
+	ModTime     time.Time              `json:"mtime"`
+	Size        int64                  `json:"size"`
+	Wallpaper   *Wallpaper             `json:"wallpaper,omitempty"`
+	SimpleTimed *simpletimed.Wallpaper `json:"stw,omitempty"`
+	GnomeTimed  *gnometimed.Wallpaper  `json:"xml,omitempty"`
+	Package     *WallpaperPackage      `json:"package,omitempty"`
+	Properties  []*GnomeWallpaper      `json:"properties,omitempty"`
+}
+
+// wallpaperIndex is the file that is stored in the cache directory
+type wallpaperIndex struct {
+	Version int                    `json:"version"`
+	Files   map[string]*indexEntry `json:"files"` // full path -> what was found
+}
+
+// upToDate checks if the entry was made for the file as it is now
+func (entry *indexEntry) upToDate(info os.FileInfo) bool {
+	return info != nil && entry.Size == info.Size() && entry.ModTime.Equal(info.ModTime())
+}
+
+// IndexFilename returns the path to the wallpaper index:
+// $XDG_CACHE_HOME/wallutils/index.json, or ~/.cache/wallutils/index.json
+// if XDG_CACHE_HOME is not set
+func IndexFilename() (string, error) {
+	cacheDir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(cacheDir, "wallutils", "index.json"), nil
+}
+
+// loadIndex reads the wallpaper index. An empty index is returned if there
+// is no index, or if it can not be used.
+func loadIndex() map[string]*indexEntry {
+	filename, err := IndexFilename()
+	if err != nil {
+		return nil
+	}
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil
+	}
+	var index wallpaperIndex
+	if err := json.Unmarshal(data, &index); err != nil || index.Version != indexVersion {
+		return nil
+	}
+	return index.Files
+}
+
+// saveIndex writes the wallpaper index. A temporary file is written first,
+// so that a partially written index is never read.
+func saveIndex(files map[string]*indexEntry) error {
+	filename, err := IndexFilename()
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(wallpaperIndex{indexVersion, files})
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(filename), 0o755); err != nil {
+		return err
+	}
+	tmpFilename := filename + ".tmp"
+	if err := os.WriteFile(tmpFilename, data, 0o644); err != nil {
+		os.Remove(tmpFilename)
+		return err
+	}
+	return os.Rename(tmpFilename, filename)
+}
+
+// newWallpaper returns a Wallpaper for the given image file
+func newWallpaper(path string, width, height uint) *Wallpaper {
+	_, dark := packageDir(path)
+	return &Wallpaper{
+		CollectionName:   collectionName(path),
+		Path:             path,
+		Width:            width,
+		Height:           height,
+		PartOfCollection: partOfCollection(path),
+		Dark:             dark,
+	}
+}
+
+// readIndexEntry reads the given file, if it is an image, a Simple Timed
+// Wallpaper, a GNOME timed wallpaper, a GNOME background-properties file or
+// the metadata of a KDE Plasma wallpaper package. The returned entry is nil
+// for other files. Files that can not be read, like truncated images, give an
+// empty entry, so that they are skipped instead of stopping the search, and
+// not read again until they are changed.
+func readIndexEntry(path string) (*indexEntry, error) {
+	if isPackageMetadata(path) {
+		pkg, err := ReadWallpaperPackage(path)
+		if err != nil {
+			// Skip packages with invalid metadata, the images can still be used
+			return &indexEntry{}, nil
+		}
+		return &indexEntry{Package: pkg}, nil
+	}
+	switch filepath.Ext(path) {
+	case ".png", ".jpg", ".jpeg":
+		width, height, err := ImageSize(path)
+		if err != nil {
+			// Skip images that can not be read, like truncated images
+			return &indexEntry{}, nil
+		}
+		if !largeEnough(width, height) {
+			return &indexEntry{}, nil
+		}
+		return &indexEntry{Wallpaper: newWallpaper(path, width, height)}, nil
+	case ".webp", ".svg", ".xpm", ".xbm", ".heic", ".heif", ".avif":
+		width, height, err := ImageSize(path)
+		if err != nil {
+			// Skip images that can not be read, like HEIC images if no converter is installed
+			return &indexEntry{}, nil
+		}
+		if !largeEnough(width, height) {
+			return &indexEntry{}, nil
+		}
+		return &indexEntry{Wallpaper: newWallpaper(path, width, height)}, nil
+	case ".stw": // Simple Timed Wallpaper
+		stw, err := simpletimed.ParseSTW(path)
+		if err != nil {
+			// Skip timed wallpapers that can not be parsed, so that the other wallpapers can still be found
+			return &indexEntry{}, nil
+		}
+		return &indexEntry{SimpleTimed: stw}, nil
+	case ".xml":
+		root, err := xmlRootElement(path)
+		if err != nil {
+			// Skip XML files that can not be parsed
+			return &indexEntry{}, nil
+		}
+		switch root {
+		case "background": // GNOME timed wallpaper
+			gw, err := gnometimed.ParseXML(path)
+			if err != nil {
+				return &indexEntry{}, nil
+			}
+			return &indexEntry{GnomeTimed: gw}, nil
+		case "wallpapers": // GNOME background-properties
+			gnomeWallpapers, err := ParseBackgroundProperties(path)
+			if err != nil {
+				return &indexEntry{}, nil
+			}
+			return &indexEntry{Properties: gnomeWallpapers}, nil
+		}
+		// Skip other XML files
+		return &indexEntry{}, nil
+	}
+	return nil, nil
+}
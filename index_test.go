@@ -0,0 +1,148 @@
+package wallutils
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// useWallpaperDirectory searches only the given directory for wallpapers,
+// for the rest of the test, and uses an empty cache directory
+func useWallpaperDirectory(t *testing.T, dir string) {
+	t.Helper()
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	t.Setenv("WALLUTILS_PATH", dir)
+}
+
+func TestWallpaperIndex(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "backgrounds", "test")
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	filename := filepath.Join(dir, "test_800x600.png")
+	if err := os.Rename(writeRedBlueImage(t, 800, 600), filename); err != nil {
+		t.Fatal(err)
+	}
+	stw, err := os.ReadFile("pkg/simpletimed/testdata/comments.stw")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "test.stw"), stw, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	useWallpaperDirectory(t, dir)
+
+	sr, err := FindWallpapers()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sr.Wallpapers()) != 1 || len(sr.SimpleTimedWallpapers()) != 1 {
+		t.Fatalf("expected one wallpaper and one timed wallpaper, got %v and %v", sr.Wallpapers(), sr.SimpleTimedWallpapers())
+	}
+	if wp := sr.Wallpapers()[0]; wp.CollectionName != "test" || wp.Width != 800 || wp.Height != 600 {
+		t.Errorf("unexpected wallpaper: %+v", wp)
+	}
+
+	// Change the index, to check that it is used instead of reading the image again
+	index := loadIndex()
+	if index[filename] == nil || index[filename].Wallpaper == nil {
+		t.Fatalf("the image is not in the index: %v", index)
+	}
+	index[filename].Wallpaper.Width = 1234
+	if err := saveIndex(index); err != nil {
+		t.Fatal(err)
+	}
+	sr, err = FindWallpapers()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if wp := sr.Wallpapers()[0]; wp.Width != 1234 {
+		t.Errorf("expected the width from the index, got %d", wp.Width)
+	}
+	if len(sr.SimpleTimedWallpapers()) != 1 || len(sr.SimpleTimedWallpapers()[0].Statics) == 0 {
+		t.Errorf("expected the timed wallpaper from the index, got %v", sr.SimpleTimedWallpapers())
+	}
+
+	// A rescan reads all files again
+	sr, err = RescanWallpapers()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if wp := sr.Wallpapers()[0]; wp.Width != 800 {
+		t.Errorf("expected the width from the image, got %d", wp.Width)
+	}
+
+	// Changed files are read again, and removed files are removed from the index
+	index = loadIndex()
+	index[filename].Wallpaper.Width = 1234
+	if err := saveIndex(index); err != nil {
+		t.Fatal(err)
+	}
+	later := time.Now().Add(time.Minute)
+	if err := os.Chtimes(filename, later, later); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Remove(filepath.Join(dir, "test.stw")); err != nil {
+		t.Fatal(err)
+	}
+	sr, err = FindWallpapers()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if wp := sr.Wallpapers()[0]; wp.Width != 800 {
+		t.Errorf("expected the width from the changed image, got %d", wp.Width)
+	}
+	if len(sr.SimpleTimedWallpapers()) != 0 || len(loadIndex()) != 1 {
+		t.Errorf("expected the removed timed wallpaper to be gone, got %v", sr.SimpleTimedWallpapers())
+	}
+
+	// An index with another version is not used
+	indexFilename, err := IndexFilename()
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := json.Marshal(wallpaperIndex{indexVersion + 1, index})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(indexFilename, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if loadIndex() != nil {
+		t.Error("expected an index with another version to be ignored")
+	}
+}
+
+func TestInvalidFiles(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "backgrounds", "test")
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Rename(writeRedBlueImage(t, 800, 600), filepath.Join(dir, "test_800x600.png")); err != nil {
+		t.Fatal(err)
+	}
+	for name, contents := range map[string]string{
+		"invalid.stw":   "stw: 1.2\n@08:00-13:00: morning .. day | spiral\n",
+		"truncated.png": "\x89PNG\r\n",
+		"truncated.jpg": "\xff\xd8",
+		"invalid.xml":   "<background><starttime>",
+		"timed.xml":     "<background><static><duration>x</duration></static>",
+	} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(contents), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	useWallpaperDirectory(t, dir)
+
+	// Images, timed wallpapers and XML files that can not be read are skipped
+	sr, err := FindWallpapers()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sr.Wallpapers()) != 1 || len(sr.SimpleTimedWallpapers()) != 0 {
+		t.Errorf("expected one wallpaper and no timed wallpapers, got %v and %v", sr.Wallpapers(), sr.SimpleTimedWallpapers())
+	}
+}
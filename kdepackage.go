@@ -0,0 +1,123 @@
+package wallutils
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// WallpaperPackage is a KDE Plasma wallpaper package: a directory with a
+// metadata.json or metadata.desktop file, and images in contents/images
+// and, for the dark variant, contents/images_dark
+type WallpaperPackage struct {
+	Name    string // the name of the wallpaper, like "Next"
+	Author  string // the authors, separated by ", "
+	License string // the license, like "CC-BY-SA-4.0"
+	Path    string // the full path to the package directory
+}
+
+// kdeMetadata is the part of a metadata.json file that is used
+type kdeMetadata struct {
+	KPlugin struct {
+		Name    string
+		License string
+		Authors []struct {
+			Name string
+		}
+	}
+}
+
+// isPackageMetadata checks if the given filename is the metadata file of a
+// KDE Plasma wallpaper package
+func isPackageMetadata(filename string) bool {
+	switch filepath.Base(filename) {
+	case "metadata.json", "metadata.desktop":
+		return true
+	}
+	return false
+}
+
+// ReadWallpaperPackage reads the given metadata.json or metadata.desktop file
+// of a KDE Plasma wallpaper package
+func ReadWallpaperPackage(metadataFilename string) (*WallpaperPackage, error) {
+	data, err := os.ReadFile(metadataFilename)
+	if err != nil {
+		return nil, err
+	}
+	pkg := &WallpaperPackage{Path: filepath.Dir(metadataFilename)}
+	if filepath.Ext(metadataFilename) == ".json" {
+		var metadata kdeMetadata
+		if err := json.Unmarshal(data, &metadata); err != nil {
+			return nil, fmt.Errorf("could not read %s: %v", metadataFilename, err)
+		}
+		pkg.Name = metadata.KPlugin.Name
+		pkg.License = metadata.KPlugin.License
+		var authors []string
+		for _, author := range metadata.KPlugin.Authors {
+			if author.Name != "" {
+				authors = append(authors, author.Name)
+			}
+		}
+		pkg.Author = strings.Join(authors, ", ")
+	} else {
+		inDesktopEntry := false
+		for _, line := range strings.Split(string(data), "\n") {
+			line = strings.TrimSpace(line)
+			if strings.HasPrefix(line, "[") {
+				inDesktopEntry = line == "[Desktop Entry]"
+				continue
+			}
+			key, value, ok := strings.Cut(line, "=")
+			if !inDesktopEntry || !ok {
+				continue
+			}
+			switch strings.TrimSpace(key) {
+			case "Name":
+				pkg.Name = strings.TrimSpace(value)
+			case "X-KDE-PluginInfo-Author":
+				pkg.Author = strings.TrimSpace(value)
+			case "X-KDE-PluginInfo-License":
+				pkg.License = strings.TrimSpace(value)
+			}
+		}
+	}
+	if pkg.Name == "" {
+		pkg.Name = filepath.Base(pkg.Path)
+	}
+	return pkg, nil
+}
+
+// packageDir returns the package directory of an image in a KDE Plasma
+// wallpaper package, and true if it is in the images_dark directory.
+// An empty string is returned if the image is not in a package.
+func packageDir(imageFilename string) (string, bool) {
+	imagesDir := filepath.Dir(imageFilename)
+	contentsDir := filepath.Dir(imagesDir)
+	if filepath.Base(contentsDir) != "contents" {
+		return "", false
+	}
+	switch filepath.Base(imagesDir) {
+	case "images":
+		return filepath.Dir(contentsDir), false
+	case "images_dark":
+		return filepath.Dir(contentsDir), true
+	}
+	return "", false
+}
+
+// Variant returns the dark or light variants of the given wallpapers. If
+// there are no wallpapers of the given variant, all wallpapers are returned.
+func Variant(wallpapers []*Wallpaper, dark bool) []*Wallpaper {
+	var variant []*Wallpaper
+	for _, wp := range wallpapers {
+		if wp.Dark == dark {
+			variant = append(variant, wp)
+		}
+	}
+	if len(variant) == 0 {
+		return wallpapers
+	}
+	return variant
+}
@@ -0,0 +1,88 @@
+package wallutils
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// writePackageImage writes an image to the given directory in a wallpaper package
+func writePackageImage(t *testing.T, packageDir, imagesDir string) string {
+	t.Helper()
+	dir := filepath.Join(packageDir, "contents", imagesDir)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	filename := filepath.Join(dir, "640x480.png")
+	if err := os.Rename(writeRedBlueImage(t, 640, 480), filename); err != nil {
+		t.Fatal(err)
+	}
+	return filename
+}
+
+func TestWallpaperPackages(t *testing.T) {
+	wallpapersDir := t.TempDir()
+
+	// A package with metadata.json and a dark variant
+	nextDir := filepath.Join(wallpapersDir, "Next")
+	light := writePackageImage(t, nextDir, "images")
+	dark := writePackageImage(t, nextDir, "images_dark")
+	if err := os.WriteFile(filepath.Join(nextDir, "metadata.json"), []byte(`{
+    "KPlugin": {
+        "Authors": [{"Email": "ken@example.org", "Name": "Ken Vermette"}, {"Name": "Niccolò Venerandi"}],
+        "Id": "Next",
+        "License": "CC-BY-SA-4.0",
+        "Name": "Next Light",
+        "Name[de]": "Nächste"
+    }
+}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	// A package with only metadata.desktop
+	oldDir := filepath.Join(wallpapersDir, "old_package")
+	writePackageImage(t, oldDir, "images")
+	if err := os.WriteFile(filepath.Join(oldDir, "metadata.desktop"), []byte(`[Desktop Entry]
+Name=Old Package
+X-KDE-PluginInfo-Author=Someone
+X-KDE-PluginInfo-License=GPL
+`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	useWallpaperDirectory(t, wallpapersDir)
+	// Search twice, to also use the wallpaper index
+	for i := 0; i < 2; i++ {
+		sr, err := FindWallpapers()
+		if err != nil {
+			t.Fatal(err)
+		}
+		next := sr.WallpapersByName("Next Light")
+		if len(next) != 2 {
+			t.Fatalf("expected two wallpapers in the Next Light collection, got %v", sr.Wallpapers())
+		}
+		for _, wp := range next {
+			if wp.Name != "Next Light" || wp.Author != "Ken Vermette, Niccolò Venerandi" || wp.License != "CC-BY-SA-4.0" {
+				t.Errorf("unexpected wallpaper: %+v", wp)
+			}
+			if wp.Dark != (wp.Path == dark) {
+				t.Errorf("%s: expected Dark to be %v", wp.Path, wp.Path == dark)
+			}
+		}
+		if variant := Variant(next, true); len(variant) != 1 || variant[0].Path != dark {
+			t.Errorf("expected the dark variant, got %v", variant)
+		}
+		if variant := Variant(next, false); len(variant) != 1 || variant[0].Path != light {
+			t.Errorf("expected the light variant, got %v", variant)
+		}
+
+		old := sr.WallpapersByName("Old Package")
+		if len(old) != 1 || old[0].Author != "Someone" || old[0].License != "GPL" || old[0].Dark {
+			t.Errorf("unexpected wallpapers in the Old Package collection: %v", old)
+		}
+		// Only the light variant is available, so that is used for both variants
+		if variant := Variant(old, true); len(variant) != 1 {
+			t.Errorf("expected the light variant, got %v", variant)
+		}
+	}
+}
@@ -0,0 +1,117 @@
+package wallutils
+
+import (
+	"fmt"
+	"os"
+)
+
+// LightDarkWM is an interface for backends that can be given one wallpaper
+// for the light color scheme and one for the dark color scheme, and that
+// switch between them by themselves
+type LightDarkWM interface {
+	WM
+	SetLightDarkWallpaper(lightFilename, darkFilename string) error
+}
+
+// lightOrDark returns the dark image if the color scheme is dark, or else
+// the light image
+func lightOrDark(cs ColorScheme, lightFilename, darkFilename string) string {
+	if cs.Dark() {
+		return darkFilename
+	}
+	return lightFilename
+}
+
+// setLightDarkPair sets both images with the backend that would be used for
+// setting the wallpaper, if it is a LightDarkWM. false is returned if the
+// pair could not be set.
+func setLightDarkPair(lightFilename, darkFilename, mode string, verbose bool) bool {
+	wm := DetectedBackend()
+	ldwm, ok := wm.(LightDarkWM)
+	if !ok {
+		return false
+	}
+	if verbose {
+		fmt.Printf("Using the %s backend.\n", wm.Name())
+	}
+	wm.SetVerbose(verbose)
+	if mode != "" && mode != defaultMode {
+		wm.SetMode(mode)
+	}
+	light, err := convertFor(wm, lightFilename, verbose)
+	if err != nil {
+		return false
+	}
+	dark, err := convertFor(wm, darkFilename, verbose)
+	if err != nil {
+		return false
+	}
+	if err := ldwm.SetLightDarkWallpaper(light, dark); err != nil {
+		if verbose {
+			fmt.Fprintf(os.Stderr, "failed: %v\n", err)
+		}
+		return false
+	}
+	return true
+}
+
+// SetWallpaperLightDark sets one wallpaper for the light color scheme and one
+// for the dark color scheme. If the backend can not switch between them by
+// itself, the wallpaper for the current color scheme is set.
+// See WatchLightDark for also switching when the color scheme changes.
+func SetWallpaperLightDark(lightFilename, darkFilename, mode string, verbose bool) error {
+	for _, imageFilename := range []string{lightFilename, darkFilename} {
+		if !exists(imageFilename) {
+			return fmt.Errorf("no such file: %s", imageFilename)
+		}
+	}
+	if !setLightDarkPair(lightFilename, darkFilename, mode, verbose) {
+		cs, err := CurrentColorScheme()
+		if err != nil && verbose {
+			fmt.Fprintf(os.Stderr, "could not read the color scheme, using the light wallpaper: %v\n", err)
+		}
+		if err := SetWallpaperCustom(lightOrDark(cs, lightFilename, darkFilename), mode, verbose); err != nil {
+			return err
+		}
+	}
+	saveState(&State{Image: lightFilename, Dark: darkFilename, Mode: mode}, verbose)
+	return nil
+}
+
+// WatchLightDark sets one wallpaper for the light color scheme and one for
+// the dark color scheme. If the backend can switch between them by itself,
+// this returns right away. If not, the color scheme is watched, and the
+// wallpaper is changed every time the color scheme changes, which means that
+// this only returns if there is an error.
+func WatchLightDark(lightFilename, darkFilename, mode string, verbose bool) error {
+	for _, imageFilename := range []string{lightFilename, darkFilename} {
+		if !exists(imageFilename) {
+			return fmt.Errorf("no such file: %s", imageFilename)
+		}
+	}
+	if setLightDarkPair(lightFilename, darkFilename, mode, verbose) {
+		saveState(&State{Image: lightFilename, Dark: darkFilename, Mode: mode}, verbose)
+		return nil
+	}
+	first := true
+	return WatchColorScheme(func(cs ColorScheme) error {
+		imageFilename := lightOrDark(cs, lightFilename, darkFilename)
+		if verbose {
+			fmt.Printf("The color scheme is %s, setting %s\n", cs, imageFilename)
+		}
+		err := SetWallpaperCustom(imageFilename, mode, verbose)
+		if first {
+			// Only the first wallpaper must be set for this to succeed
+			first = false
+			if err != nil {
+				return err
+			}
+			saveState(&State{Image: lightFilename, Dark: darkFilename, Mode: mode}, verbose)
+			return nil
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "could not set %s: %v\n", imageFilename, err)
+		}
+		return nil
+	}, verbose)
+}
@@ -1,7 +1,6 @@
 package wallutils
 
 import (
-	"errors"
 	"fmt"
 
 	"github.com/xyproto/env/v2"
@@ -42,6 +41,17 @@ func (m *Mate) SetMode(mode string) {
 	m.mode = mode
 }
 
+// Capabilities returns the wallpaper modes that are supported by this backend,
+// if one wallpaper per output can be set and if the wallpaper is kept after
+// logging in again
+func (m *Mate) Capabilities() Capabilities {
+	return Capabilities{
+		Modes:      []string{"none", "wallpaper", "centered", "scaled", "stretched", "zoom", "spanned", "stretch", "center", "fill", "scale", "tile"},
+		PerOutput:  false,
+		Persistent: true,
+	}
+}
+
 // SetVerbose can be used for setting the verbose field to true or false.
 // This will cause this backend to output information about what is is doing on stdout.
 func (m *Mate) SetVerbose(verbose bool) {
@@ -80,11 +90,11 @@ func (m *Mate) SetWallpaper(imageFilename string) error {
 		mode = "wallpaper"
 	default:
 		// Invalid and unrecognized desktop wallpaper mode
-		return fmt.Errorf("invalid desktop wallpaper mode for MATE: %s", mode)
+		return fmt.Errorf("%w for MATE: %s", ErrUnsupportedMode, mode)
 	}
 
 	if !m.hasGsettings {
-		return errors.New("could not find gsettings")
+		return fmt.Errorf("could not find gsettings: %w", ErrNotAvailable)
 	}
 
 	// Exit if the monitor configuration will cause artifacts when setting
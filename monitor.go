@@ -3,20 +3,118 @@ package wallutils
 import (
 	"errors"
 	"fmt"
+	"strings"
 )
 
-// Monitor contains an ID, the width in pixels and the height in pixels
+// Monitor contains an ID, the width in pixels and the height in pixels,
+// and additional information about the output, if available
 type Monitor struct {
-	ID     uint // monitor number, from 0 and up
-	Width  uint // width, in pixels
-	Height uint // height, in pixels
-	DPIw   uint // DPI, if available (width)
-	DPIh   uint // DPI, if available (height)
+	ID       uint    // monitor number, from 0 and up
+	Width    uint    // width, in pixels
+	Height   uint    // height, in pixels
+	DPIw     uint    // DPI, if available (width)
+	DPIh     uint    // DPI, if available (height)
+	Name     string  // output name, like "DP-1", if available
+	X        int     // horizontal position of the upper left corner, in pixels
+	Y        int     // vertical position of the upper left corner, in pixels
+	Scale    float64 // scale factor, 1 if not available
+	Rotation uint    // rotation, in degrees counter-clockwise (0, 90, 180 or 270)
+	Refresh  float64 // refresh rate, in Hz, if available
+	Make     string  // manufacturer, if available
+	Model    string  // model, if available
+	Primary  bool    // is this the primary monitor
+	Serial   string  // serial number, if available
+
+	DPISource string // where the DPI values came from, like DPIFromEDID
+	EDID      *EDID  // information that the monitor reports about itself, if available
 }
 
+// Sources for the DPI values in a Monitor struct
+const (
+	DPIUnknown    = ""        // the DPI is not available yet, Monitors uses DPIDefault instead
+	DPIFromServer = "server"  // calculated from the size reported by Wayland or X
+	DPIFromEDID   = "EDID"    // calculated from the size in the EDID
+	DPIDefault    = "default" // no physical size was found, 96 is assumed
+)
+
 var errNoWaylandNoX = errors.New("could not detect neither Wayland nor X")
 
 // String returns a string with monitor ID and resolution
 func (m Monitor) String() string {
 	return fmt.Sprintf("[%d] %dx%d", m.ID, m.Width, m.Height)
 }
+
+// Long returns a string with all available information about the monitor
+func (m Monitor) Long() string {
+	s := fmt.Sprintf("[%d] %dx%d+%d+%d", m.ID, m.Width, m.Height, m.X, m.Y)
+	if m.Name != "" {
+		s += " " + m.Name
+	}
+	if m.Primary {
+		s += " primary"
+	}
+	if m.Refresh > 0 {
+		s += fmt.Sprintf(" %.2fHz", m.Refresh)
+	}
+	if m.Scale > 0 && m.Scale != 1 {
+		s += fmt.Sprintf(" scale:%g", m.Scale)
+	}
+	if m.Rotation != 0 {
+		s += fmt.Sprintf(" rotation:%d", m.Rotation)
+	}
+	if m.Make != "" || m.Model != "" {
+		s += fmt.Sprintf(" (%s %s)", m.Make, m.Model)
+	}
+	return s
+}
+
+// rotationFromTransform converts a transform or rotation from Wayland or
+// xrandr, like "90°", "flipped 270°", "left" or "inverted", to degrees
+// counter-clockwise
+func rotationFromTransform(transform string) uint {
+	switch {
+	case transform == "right" || strings.Contains(transform, "270"):
+		return 270
+	case transform == "inverted" || strings.Contains(transform, "180"):
+		return 180
+	case transform == "left" || strings.Contains(transform, "90"):
+		return 90
+	}
+	return 0
+}
+
+// Monitors returns information about all monitors, regardless of if it's under
+// Wayland or X11. If neither is available, the connectors in /sys/class/drm
+// are examined. Will use additional plugins, if available.
+// If no monitors are found, the errors from each way of detecting them are
+// returned together.
+func Monitors() ([]Monitor, error) {
+	errs := []error{errNoWaylandNoX}
+	for _, detect := range monitorDetectors {
+		monitors, err := detect()
+		if err == nil && len(monitors) > 0 {
+			applyEDIDs(monitors, drmPath)
+			return monitors, nil
+		}
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return []Monitor{}, errors.Join(errs...)
+}
+
+// AverageResolution returns the average resolution for all connected monitors.
+func AverageResolution() (*Res, error) {
+	monitors, err := Monitors()
+	if err != nil {
+		return nil, err
+	}
+	var ws, hs uint
+	for _, mon := range monitors {
+		ws += mon.Width
+		hs += mon.Height
+	}
+	ws /= uint(len(monitors))
+	hs /= uint(len(monitors))
+	return NewRes(ws, hs), nil
+}
@@ -0,0 +1,25 @@
+package wallutils
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestMonitorsErrors(t *testing.T) {
+	errWayland := errors.New("could not connect to Wayland")
+	errX := errors.New("could not connect to X")
+	defer func(detectors []func() ([]Monitor, error)) { monitorDetectors = detectors }(monitorDetectors)
+	monitorDetectors = []func() ([]Monitor, error){
+		func() ([]Monitor, error) { return nil, errWayland },
+		func() ([]Monitor, error) { return nil, errX },
+		func() ([]Monitor, error) { return nil, nil }, // no monitors, but no error
+	}
+	_, err := Monitors()
+	if !errors.Is(err, errNoWaylandNoX) || !errors.Is(err, errWayland) || !errors.Is(err, errX) {
+		t.Fatalf("expected all the errors, got %v", err)
+	}
+	if !strings.HasPrefix(err.Error(), errNoWaylandNoX.Error()) {
+		t.Errorf("expected the error to start with %q, got %q", errNoWaylandNoX, err)
+	}
+}
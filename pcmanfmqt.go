@@ -31,6 +31,17 @@ func (pcmq *PCManFMQt) SetMode(mode string) {
 	pcmq.mode = mode
 }
 
+// Capabilities returns the wallpaper modes that are supported by this backend,
+// if one wallpaper per output can be set and if the wallpaper is kept after
+// logging in again
+func (pcmq *PCManFMQt) Capabilities() Capabilities {
+	return Capabilities{
+		Modes:      []string{"none", "color", "center", "centered", "zoom", "stretch", "stretched", "fill", "scale", "scaled", "spanned", "tile", "wallpaper"},
+		PerOutput:  false,
+		Persistent: true,
+	}
+}
+
 // SetVerbose can be used for setting the verbose field to true or false.
 // This will cause this backend to output information about what is is doing on stdout.
 func (pcmq *PCManFMQt) SetVerbose(verbose bool) {
@@ -67,7 +78,7 @@ func (pcmq *PCManFMQt) SetWallpaper(imageFilename string) error {
 		mode = "tile"
 	default:
 		// Invalid and unrecognized desktop wallpaper mode
-		return fmt.Errorf("invalid desktop wallpaper mode for PCManFM-Qt: %s", mode)
+		return fmt.Errorf("%w for PCManFM-Qt: %s", ErrUnsupportedMode, mode)
 	}
 
 	// Set the wallpaper image with the selected mode
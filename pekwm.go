@@ -37,6 +37,17 @@ func (f *Pekwm) SetMode(mode string) {
 	f.mode = mode
 }
 
+// Capabilities returns the wallpaper modes that are supported by this backend,
+// if one wallpaper per output can be set and if the wallpaper is kept after
+// logging in again
+func (f *Pekwm) Capabilities() Capabilities {
+	return Capabilities{
+		Modes:      []string{"stretch", "stretched", "scaled", "fill", "scale", "max", "tile", "tiled"},
+		PerOutput:  false,
+		Persistent: false,
+	}
+}
+
 // SetVerbose can be used for setting the verbose field to true or false.
 // This will cause this backend to output information about what is is doing on stdout.
 func (f *Pekwm) SetVerbose(verbose bool) {
@@ -67,7 +78,7 @@ func (f *Pekwm) SetWallpaper(imageFilename string) error {
 		break
 	default:
 		// Invalid and unrecognized desktop wallpaper mode
-		return fmt.Errorf("invalid desktop wallpaper mode for Pekwm: %s", mode)
+		return fmt.Errorf("%w for Pekwm: %s", ErrUnsupportedMode, mode)
 	}
 
 	// set the wallpaper with pekwm_bg
@@ -1,7 +1,75 @@
 package event
 
+import (
+	"sync"
+	"time"
+)
+
 // NewClockEvent will create a simple event that can be triggered
 // The event will trigger every time the hour and minute matches the one from time.Now()
 func NewClockEvent(h, m int, f func() error) *SimpleEvent {
 	return &SimpleEvent{h, m, false, f}
 }
+
+// Clock is where the current time comes from, and how to wait.
+// The event system and the timed wallpaper event loops use a Clock instead
+// of calling time.Now and time.Sleep directly, so that a FakeClock can be
+// used for testing and simulating them.
+type Clock interface {
+	Now() time.Time
+	Sleep(d time.Duration)
+}
+
+// RealClock is a Clock that uses time.Now and time.Sleep
+type RealClock struct{}
+
+// Now returns the current time
+func (RealClock) Now() time.Time {
+	return time.Now()
+}
+
+// Sleep waits for the given duration
+func (RealClock) Sleep(d time.Duration) {
+	time.Sleep(d)
+}
+
+// DefaultClock is the clock that is used when no clock is given
+var DefaultClock Clock = RealClock{}
+
+// FakeClock is a Clock where time only passes when Sleep, Add or Set is
+// called. Sleep returns right away, after moving the time forward.
+type FakeClock struct {
+	mut sync.Mutex
+	now time.Time
+}
+
+// NewFakeClock creates a new FakeClock that starts at the given time
+func NewFakeClock(t time.Time) *FakeClock {
+	return &FakeClock{now: t}
+}
+
+// Now returns the current time of this clock
+func (c *FakeClock) Now() time.Time {
+	c.mut.Lock()
+	defer c.mut.Unlock()
+	return c.now
+}
+
+// Sleep moves the time forward by the given duration, without waiting
+func (c *FakeClock) Sleep(d time.Duration) {
+	c.Add(d)
+}
+
+// Add moves the time forward by the given duration
+func (c *FakeClock) Add(d time.Duration) {
+	c.mut.Lock()
+	c.now = c.now.Add(d)
+	c.mut.Unlock()
+}
+
+// Set sets the time of this clock
+func (c *FakeClock) Set(t time.Time) {
+	c.mut.Lock()
+	c.now = t
+	c.mut.Unlock()
+}
@@ -10,95 +10,112 @@ import (
 //   - granularity is how long it should wait at each loop in the main loop
 //   - events is a map from Event to a margin of error on each side of the time
 //     when the event should kick in
-//   - coolOffGranularity is how long the system should wait per cool-off
-//     loop iteration
+//   - coolOffDuration is how long an event should not be triggered again,
+//     after it has been triggered
+//   - clock is where the current time comes from
 type EventSys struct {
-	events             []Event
-	granularity        time.Duration
-	coolOffGranularity time.Duration
+	mut             sync.Mutex
+	events          []Event
+	granularity     time.Duration
+	coolOffDuration time.Duration
+	clock           Clock
 }
 
-// CoolOff is a list of all events that should not be triggered just yet
+// CoolOff has all events that should not be triggered just yet, and when
+// they were triggered
 var (
-	coolOff    []Event
+	coolOff    = make(map[Event]time.Time)
 	coolOffMut sync.Mutex
 )
 
-func (sys *EventSys) coolOffLoop() {
-	// Every N seconds, remove the first entry from the coolOff slice
-	for {
-		coolOffMut.Lock()
-		if len(coolOff) > 0 {
-			// If the event should be ran just once, move it to the back of the queue
-			if coolOff[0].JustOnce() {
-				tmp := coolOff[0]
-				coolOff = coolOff[1:]
-				coolOff = append(coolOff, tmp)
-			} else {
-				coolOff = coolOff[1:]
-			}
-		}
-		coolOffMut.Unlock()
-		time.Sleep(sys.coolOffGranularity)
-	}
-}
-
 // NewSystem creates a new event system, where events can be registered
 // and the event loop can be run. loopSleep is how long the event loop
 // should sleep at every iteration.
 func NewSystem(loopSleep time.Duration) *EventSys {
-	events := make([]Event, 0)
-	granularity := loopSleep
-	coolOffDuration := time.Minute * 5
-	return &EventSys{events, granularity, coolOffDuration}
+	return NewSystemWithClock(loopSleep, DefaultClock)
+}
+
+// NewSystemWithClock creates a new event system, like NewSystem, that uses
+// the given clock. Use a FakeClock together with Step for testing.
+func NewSystemWithClock(loopSleep time.Duration, clock Clock) *EventSys {
+	return &EventSys{
+		events:          make([]Event, 0),
+		granularity:     loopSleep,
+		coolOffDuration: time.Minute * 5,
+		clock:           clock,
+	}
+}
+
+// Clock returns the clock that is used by this event system
+func (sys *EventSys) Clock() Clock {
+	return sys.clock
 }
 
 // Register will register an event with the event system.
 func (sys *EventSys) Register(event Event) {
-	// TODO: Not thread safe? Add a mutex?
+	sys.mut.Lock()
 	sys.events = append(sys.events, event)
+	sys.mut.Unlock()
 }
 
-// eventLoop will run the event system endlessly, in the foreground
-func (sys *EventSys) eventLoop(verbose bool) error {
-	for {
-		// Check if any events should kick in at this point in time +- error margin, in seconds
-		now := time.Now()
-	NEXT_EVENT:
-		for _, event := range sys.events {
-			// If the event is in the coolOff slice, skip for now
-			for _, coolOffEvent := range coolOff {
-				if coolOffEvent == event {
-					//if verbose {
-					//log.Println("Skipping event that is in the cool-off period")
-					//}
-					continue NEXT_EVENT
-				}
-			}
-			if now.Hour() == event.Hour() && now.Minute() == event.Minute() {
-				if verbose {
-					log.Printf("Trigger event at %02d:%02d\n", now.Hour(), now.Minute())
-				}
-				if event.Trigger() != nil {
-					// TODO: Do something sensible if the trigger fails?
-					if verbose {
-						log.Println("Event failed")
-					}
-				}
-				// Placing in the CoolOff slice,
-				// which is handled by the cooloff-system
-				coolOffMut.Lock()
-				coolOff = append(coolOff, event)
-				coolOffMut.Unlock()
+// coolingOff checks if the given event was triggered too recently to be
+// triggered again. Events that should only be triggered once are always
+// cooling off, after they have been triggered.
+func (sys *EventSys) coolingOff(event Event, now time.Time) bool {
+	coolOffMut.Lock()
+	defer coolOffMut.Unlock()
+	when, ok := coolOff[event]
+	if !ok {
+		return false
+	}
+	if event.JustOnce() {
+		return true
+	}
+	// The clock may also have been set backwards
+	if now.Sub(when) >= sys.coolOffDuration || now.Before(when) {
+		delete(coolOff, event)
+		return false
+	}
+	return true
+}
+
+// Step triggers the events that should kick in at the current time of the
+// clock, and that are not cooling off. This is one iteration of the event loop.
+func (sys *EventSys) Step(verbose bool) {
+	now := sys.clock.Now()
+	sys.mut.Lock()
+	events := append([]Event{}, sys.events...)
+	sys.mut.Unlock()
+	for _, event := range events {
+		if now.Hour() != event.Hour() || now.Minute() != event.Minute() || sys.coolingOff(event, now) {
+			continue
+		}
+		if verbose {
+			log.Printf("Trigger event at %02d:%02d\n", now.Hour(), now.Minute())
+		}
+		if event.Trigger() != nil {
+			// TODO: Do something sensible if the trigger fails?
+			if verbose {
+				log.Println("Event failed")
 			}
 		}
-		time.Sleep(sys.granularity)
+		// Do not trigger the event again until the cool-off period is over
+		coolOffMut.Lock()
+		coolOff[event] = now
+		coolOffMut.Unlock()
+	}
+}
+
+// eventLoop will run the event system endlessly, in the foreground
+func (sys *EventSys) eventLoop(verbose bool) {
+	for {
+		sys.Step(verbose)
+		sys.clock.Sleep(sys.granularity)
 	}
 }
 
 // RunBackground will start the event system in the background and immediately return
 func (sys *EventSys) RunBackground(verbose bool) {
-	go sys.coolOffLoop()
 	go sys.eventLoop(verbose)
 }
 
@@ -111,20 +128,11 @@ func (sys *EventSys) Run(verbose bool) {
 	wg.Wait()
 }
 
-// Reset will remove a given event from the cool-off queue
-// TODO: This function needs testing!
+// Reset will remove a given event from the cool-off period, so that it can
+// be triggered again
 func Reset(event Event) {
 	coolOffMut.Lock()
-	s := coolOff
-	var i int
-	for i2, e := range coolOff {
-		if e == event {
-			i = i2
-			break
-		}
-	}
-	s[len(s)-1], s[i] = s[i], s[len(s)-1]
-	coolOff = s[:len(s)-1]
+	delete(coolOff, event)
 	coolOffMut.Unlock()
 }
 
@@ -132,7 +140,7 @@ func Reset(event Event) {
 // certain amount of time from now, then may optionally be repeated at every
 // matching hour and minute every 24 hours, if "once" is false.
 func (sys *EventSys) SimpleEvent(in time.Duration, once bool, f func() error) {
-	sys.Register(NewSimpleEvent(in, once, f))
+	sys.Register(newSimpleEvent(sys.clock.Now(), in, once, f))
 }
 
 // ClockEvent creates and registers an event that should happen at every HH:MM
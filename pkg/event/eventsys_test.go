@@ -6,17 +6,75 @@ import (
 	"time"
 )
 
+// midnight is the start of the simulated days in the tests
+var midnight = time.Date(2026, time.March, 1, 0, 0, 0, 0, time.Local)
+
 func TestEveryMinute(t *testing.T) {
-	sys := NewSystem(1 * time.Second)
-	now := time.Now()
-	i := 0
-	n := 1 // Could be any number of events to trigger, with a minute between
-	sys.EveryMinute(now.Hour(), now.Minute(), n, func() error {
-		fmt.Printf("WAIT A MINUTE #%d\n", i+1)
-		i++
+	clock := NewFakeClock(midnight.Add(13*time.Hour + 58*time.Minute))
+	sys := NewSystemWithClock(1*time.Second, clock)
+	var triggered []string
+	n := 3 // Could be any number of events to trigger, with a minute between
+	sys.EveryMinute(13, 59, n, func() error {
+		triggered = append(triggered, fmt.Sprintf("%02d:%02d", clock.Now().Hour(), clock.Now().Minute()))
+		return nil
+	})
+	// Run the event loop for five minutes, one second at a time
+	for i := 0; i < 5*60; i++ {
+		sys.Step(false)
+		clock.Sleep(time.Second)
+	}
+	if fmt.Sprint(triggered) != "[13:59 14:00 14:01]" {
+		t.Errorf("unexpected events: %v", triggered)
+	}
+}
+
+func TestFullDay(t *testing.T) {
+	clock := NewFakeClock(midnight)
+	sys := NewSystemWithClock(time.Minute, clock)
+	var triggered []string
+	for hour := 0; hour < 24; hour++ {
+		for minute := 0; minute < 60; minute++ {
+			hour := hour
+			minute := minute
+			sys.ClockEvent(hour, minute, func() error {
+				triggered = append(triggered, fmt.Sprintf("%02d:%02d", hour, minute))
+				return nil
+			})
+		}
+	}
+	// Two days, checking every 20 seconds
+	for i := 0; i < 2*24*60*3; i++ {
+		sys.Step(false)
+		clock.Sleep(20 * time.Second)
+	}
+	if len(triggered) != 2*24*60 {
+		t.Fatalf("expected every event to be triggered once per day, got %d events", len(triggered))
+	}
+	for i, clockString := range triggered {
+		minutes := i % (24 * 60)
+		if expected := fmt.Sprintf("%02d:%02d", minutes/60, minutes%60); clockString != expected {
+			t.Fatalf("expected event %d to be at %s, got %s", i, expected, clockString)
+		}
+	}
+}
+
+func TestReset(t *testing.T) {
+	clock := NewFakeClock(midnight.Add(8 * time.Hour))
+	sys := NewSystemWithClock(time.Second, clock)
+	counter := 0
+	e := NewClockEvent(8, 0, func() error {
+		counter++
 		return nil
 	})
-	verbose := true
-	sys.Run(verbose)
-	time.Sleep(1 * time.Second)
+	sys.Register(e)
+	sys.Step(false)
+	sys.Step(false)
+	if counter != 1 {
+		t.Fatalf("expected the event to be cooling off, got %d triggers", counter)
+	}
+	Reset(e)
+	sys.Step(false)
+	if counter != 2 {
+		t.Errorf("expected the event to be triggered again after Reset, got %d triggers", counter)
+	}
 }
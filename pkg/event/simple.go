@@ -23,7 +23,12 @@ func NewTestEvent() *SimpleEvent {
 
 // NewSimpleEvent will create a simple event that can be triggered
 func NewSimpleEvent(in time.Duration, once bool, f func() error) *SimpleEvent {
-	now := time.Now()
+	return newSimpleEvent(DefaultClock.Now(), in, once, f)
+}
+
+// newSimpleEvent will create a simple event that triggers at the given
+// duration after now
+func newSimpleEvent(now time.Time, in time.Duration, once bool, f func() error) *SimpleEvent {
 	when := now.Add(in)
 	return &SimpleEvent{when.Hour(), when.Minute(), once, f}
 }
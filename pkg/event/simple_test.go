@@ -6,9 +6,33 @@ import (
 )
 
 func TestNewSimpleEvent(t *testing.T) {
-	sys := NewSystem(1 * time.Second)
-	e := NewTestEvent()
-	sys.Register(e)
-	verbose := true
-	sys.Run(verbose)
+	clock := NewFakeClock(midnight.Add(12*time.Hour + 59*time.Minute + 50*time.Second))
+	sys := NewSystemWithClock(1*time.Second, clock)
+	counter := 0
+	// Trigger once, in 30 seconds
+	sys.SimpleEvent(30*time.Second, true, func() error {
+		counter++
+		return nil
+	})
+	// Run the event loop for two days
+	for i := 0; i < 2*24*60*60; i++ {
+		sys.Step(false)
+		clock.Sleep(time.Second)
+	}
+	if counter != 1 {
+		t.Errorf("expected the event to be triggered once, got %d", counter)
+	}
+}
+
+func TestFakeClock(t *testing.T) {
+	clock := NewFakeClock(midnight)
+	clock.Sleep(time.Hour)
+	clock.Add(time.Minute)
+	if got := clock.Now(); !got.Equal(midnight.Add(time.Hour + time.Minute)) {
+		t.Errorf("unexpected time: %v", got)
+	}
+	clock.Set(midnight)
+	if got := ToDate(midnight.Add(-time.Hour), clock.Now()); !got.Equal(midnight.Add(23 * time.Hour)) {
+		t.Errorf("unexpected time: %v", got)
+	}
 }
@@ -5,12 +5,15 @@ import "time"
 // ToToday moves the date of a given time.Time to today's date.
 // The hour/minute/second is kept as it is.
 func ToToday(d time.Time) time.Time {
+	return ToDate(d, time.Now())
+}
+
+// ToDate moves the date of a given time.Time to the date of the given day.
+// The hour/minute/second is kept as it is.
+func ToDate(d, day time.Time) time.Time {
 	// Get hour, minute and second from the event
 	hour, min, sec := d.Clock()
 
-	// Get the current time and date
-	now := time.Now()
-
 	// Return a new time.Time
-	return time.Date(now.Year(), now.Month(), now.Day(), hour, min, sec, now.Nanosecond(), now.Location())
+	return time.Date(day.Year(), day.Month(), day.Day(), hour, min, sec, day.Nanosecond(), day.Location())
 }
@@ -10,6 +10,42 @@ import (
 
 const simpleTimedWallpaperFormatVersion = "1.0"
 
+// transitionTypesVersion is the version of the Simple Timed Wallpaper format
+// that is needed for other transition types than "overlay"
+const transitionTypesVersion = "1.2"
+
+// transitionTypeAliases are other names for the transition types in
+// Simple Timed Wallpapers, that may be used in GNOME timed wallpapers
+var transitionTypeAliases = map[string]string{
+	"":            "overlay",
+	"fade":        "overlay",
+	"crossfade":   "overlay",
+	"wipe":        "wipe-left",
+	"circle":      "radial",
+	"iris":        "radial",
+	"blur":        "blur-through",
+	"temperature": "color-temperature",
+	"luma":        "luminance",
+}
+
+// TransitionType returns the Simple Timed Wallpaper transition type for the
+// type attribute of a transition in a GNOME timed wallpaper. GNOME only uses
+// "overlay", but the transition types of Simple Timed Wallpapers, like
+// "wipe-left", and some other names for them, like "wipe", are also
+// recognized. Unknown transition types are shown as "overlay".
+func TransitionType(gnomeType string) string {
+	transitionType := strings.ReplaceAll(strings.ToLower(strings.TrimSpace(gnomeType)), "_", "-")
+	if alias, ok := transitionTypeAliases[transitionType]; ok {
+		return alias
+	}
+	for _, supported := range simpletimed.TransitionTypes {
+		if transitionType == supported {
+			return transitionType
+		}
+	}
+	return "overlay"
+}
+
 // GnomeToSimple converts a Gnome Timed Wallpaper to a Simple Timed Wallpaper
 func GnomeToSimple(gtw *Wallpaper) (*simpletimed.Wallpaper, error) {
 	// TODO: Convert from struct to struct, without exercising the serializer and the parser
@@ -31,8 +67,14 @@ func GnomeToSimpleString(gtw *Wallpaper) (string, error) {
 
 	var sb strings.Builder
 
-	// Output the version of the format
-	sb.WriteString("stw: " + simpleTimedWallpaperFormatVersion + "\n")
+	// Output the version of the format, which depends on the transition types
+	version := simpleTimedWallpaperFormatVersion
+	for _, t := range gtw.Config.Transitions {
+		if TransitionType(t.Type) != "overlay" {
+			version = transitionTypesVersion
+		}
+	}
+	sb.WriteString("stw: " + version + "\n")
 
 	// Output the name of the timed wallpaper
 	sb.WriteString("name: " + name + "\n")
@@ -94,10 +136,10 @@ func GnomeToSimpleString(gtw *Wallpaper) (string, error) {
 			from := eventTime
 			upTo := eventTime.Add(window)
 
-			if t.Type == "overlay" || t.Type == "" {
+			if transitionType := TransitionType(t.Type); transitionType == "overlay" {
 				sb.WriteString(fmt.Sprintf("@%s-%s: %s .. %s\n", cFmt(from), cFmt(upTo), Meat(t.FromFilename, commonPrefix, commonSuffix), Meat(t.ToFilename, commonPrefix, commonSuffix)))
 			} else {
-				sb.WriteString(fmt.Sprintf("@%s-%s: %s .. %s | %s\n", cFmt(from), cFmt(upTo), Meat(t.FromFilename, commonPrefix, commonSuffix), Meat(t.ToFilename, commonPrefix, commonSuffix), t.Type))
+				sb.WriteString(fmt.Sprintf("@%s-%s: %s .. %s | %s\n", cFmt(from), cFmt(upTo), Meat(t.FromFilename, commonPrefix, commonSuffix), Meat(t.ToFilename, commonPrefix, commonSuffix), transitionType))
 			}
 
 			// Increase the variable that keeps track of the time
@@ -17,3 +17,35 @@ func TestConvert(t *testing.T) {
 	}
 	fmt.Println(stw)
 }
+
+func TestTransitionType(t *testing.T) {
+	for gnomeType, expected := range map[string]string{
+		"":           "overlay",
+		"overlay":    "overlay",
+		"fade":       "overlay",
+		"wipe-left":  "wipe-left",
+		"Wipe_Right": "wipe-right",
+		"wipe":       "wipe-left",
+		"blur":       "blur-through",
+		"luminance":  "luminance",
+		"spiral":     "overlay",
+	} {
+		if transitionType := TransitionType(gnomeType); transitionType != expected {
+			t.Errorf("expected %q for %q, got %q", expected, gnomeType, transitionType)
+		}
+	}
+
+	// The transition types are kept when converting to a Simple Timed Wallpaper
+	gtw, err := ParseXML("testdata/adwaita-timed.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	gtw.Config.Transitions[0].Type = "wipe"
+	stw, err := GnomeToSimple(gtw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stw.STWVersion != "1.2" || stw.Transitions[0].Type != "wipe-left" || stw.Transitions[1].Type != "overlay" {
+		t.Errorf("unexpected STW:\n%s", stw)
+	}
+}
@@ -7,11 +7,9 @@ import (
 	"path/filepath"
 	"sync"
 	"syscall"
-	"time"
 
-	"github.com/anthonynsimon/bild/blend"
-	"github.com/anthonynsimon/bild/imgio"
 	"github.com/xyproto/wallutils/pkg/event"
+	"github.com/xyproto/wallutils/pkg/simpletimed"
 )
 
 var setmut = &sync.RWMutex{}
@@ -22,11 +20,14 @@ func (gtw *Wallpaper) EventLoop(verbose bool, setWallpaperFunc func(string) erro
 		fmt.Println("Using the GNOME Timed Wallpaper format")
 	}
 
-	// Convert to a SimpleTimedWallpaper, only for setting the initial wallpaper
+	// Convert to a SimpleTimedWallpaper, for setting the initial wallpaper and
+	// for blending the images for the transitions ahead of time
 	stw, err := GnomeToSimple(gtw)
 	if err != nil {
 		return err
 	}
+	stw.Clock = gtw.Clock
+	stw.Fade = gtw.Fade
 
 	// Listen for SIGHUP or SIGUSR1, to refresh the wallpaper.
 	// Can be used after resume from sleep.
@@ -48,15 +49,49 @@ func (gtw *Wallpaper) EventLoop(verbose bool, setWallpaperFunc func(string) erro
 		}
 	}()
 
+	eventloop, err := gtw.eventSystem(stw, verbose, setWallpaperFunc, tempImageFilename)
+	if err != nil {
+		return err
+	}
+
+	// Endless loop! Will wait loopWait duration between each event loop cycle.
+	eventloop.Run(verbose)
+
+	return nil
+}
+
+// EventSystem sets the initial wallpaper and returns an event system where
+// the events of this GNOME Timed Wallpaper are registered. The event system
+// uses gtw.Clock, if it is set. Call Run or Step on the returned event system
+// to trigger the events.
+func (gtw *Wallpaper) EventSystem(verbose bool, setWallpaperFunc func(string) error, tempImageFilename string) (*event.EventSys, error) {
+	// Convert to a SimpleTimedWallpaper, for setting the initial wallpaper and
+	// for blending the images for the transitions ahead of time
+	stw, err := GnomeToSimple(gtw)
+	if err != nil {
+		return nil, err
+	}
+	stw.Clock = gtw.Clock
+	stw.Fade = gtw.Fade
+	return gtw.eventSystem(stw, verbose, setWallpaperFunc, tempImageFilename)
+}
+
+// eventSystem sets the initial wallpaper with the given Simple Timed
+// Wallpaper, and returns an event system where the events are registered
+func (gtw *Wallpaper) eventSystem(stw *simpletimed.Wallpaper, verbose bool, setWallpaperFunc func(string) error, tempImageFilename string) (*event.EventSys, error) {
 	setmut.Lock()
 	if err := stw.SetInitialWallpaper(verbose, setWallpaperFunc, tempImageFilename); err != nil {
 		setmut.Unlock()
-		return err
+		return nil, err
 	}
 	setmut.Unlock()
 
-	// Start the event loop
-	eventloop := event.NewSystem(gtw.LoopWait)
+	// Create the event loop
+	clock := gtw.Clock
+	if clock == nil {
+		clock = event.DefaultClock
+	}
+	eventloop := event.NewSystemWithClock(gtw.LoopWait, clock)
 
 	// Get the start time for the wallpaper collection (which is offset by X
 	// seconds per static wallpaper)
@@ -78,7 +113,7 @@ func (gtw *Wallpaper) EventLoop(verbose bool, setWallpaperFunc func(string) erro
 		// Get an element, by index. This is an interface{} and is expected to be a GStatic or a GTransition
 		eInterface, err := gtw.Config.Get(i)
 		if err != nil {
-			return err
+			return nil, err
 		}
 		if s, ok := eInterface.(GStatic); ok {
 			if verbose {
@@ -131,16 +166,16 @@ func (gtw *Wallpaper) EventLoop(verbose bool, setWallpaperFunc func(string) erro
 			from := eventTime
 			window := t.Duration()
 			upTo := eventTime.Add(window)
-			tType := t.Type
+			tType := TransitionType(t.Type)
 			tFromFilename := t.FromFilename
 			tToFilename := t.ToFilename
 			loopWait := gtw.LoopWait
 			start := from
-			halfway := from.Add(window / 2)
 
 			// Register the start of a transition event
 			eventloop.ClockEvent(start.Hour(), start.Minute(), func() error {
-				progress := mod24(window - event.ToToday(upTo).Sub(event.ToToday(time.Now())))
+				now := clock.Now()
+				progress := mod24(window - event.ToDate(upTo, now).Sub(now))
 				ratio := float64(progress) / float64(window)
 				if verbose {
 					fmt.Printf("Triggered transition event at %s (%d%% complete)\n", cFmt(from), int(ratio*100))
@@ -163,67 +198,55 @@ func (gtw *Wallpaper) EventLoop(verbose bool, setWallpaperFunc func(string) erro
 				return nil
 			})
 
-			// Register a half way event
-			eventloop.ClockEvent(halfway.Hour(), halfway.Minute(), func() error {
-				progress := mod24(window - event.ToToday(upTo).Sub(event.ToToday(time.Now())))
-				ratio := float64(progress) / float64(window)
-				if verbose {
-					fmt.Printf("Triggered transition event at %s (%d%% complete)\n", cFmt(from), int(ratio*100))
-					fmt.Println("Progress:", dFmt(progress))
-					fmt.Println("Up to:", cFmt(upTo))
-					fmt.Println("Loop wait:", dFmt(loopWait))
-					fmt.Println("Transition type:", tType)
-					fmt.Println("From filename", tFromFilename)
-					fmt.Println("To filename", tToFilename)
-					fmt.Println("Crossfading between images.")
-				}
-				// Crossfade and write the new image to the temporary directory
-				tFromImg, err := imgio.Open(tFromFilename)
-				if err != nil {
-					fmt.Fprintln(os.Stderr, err)
-					return err
-				}
-				tToImg, err := imgio.Open(tToFilename)
-				if err != nil {
-					fmt.Fprintln(os.Stderr, err)
-					return err
-				}
-				// Crossfade and write the new image to the temporary directory
-				setmut.Lock()
-				blendedImage := blend.Opacity(tFromImg, tToImg, ratio)
-				err = imgio.Save(tempImageFilename, blendedImage, imgio.JPEGEncoder(100))
-				if err != nil {
-					fmt.Fprintf(os.Stderr, "Could not crossfade images in transition: %v\n", err)
+			// Register an event for each blended image, after the start of the transition
+			for _, offset := range gtw.Fade.Offsets(window) {
+				at := from.Add(offset)
+				ratio := float64(offset) / float64(window)
+				eventloop.ClockEvent(at.Hour(), at.Minute(), func() error {
+					if verbose {
+						fmt.Printf("Triggered transition event at %s (%d%% complete)\n", cFmt(from), int(ratio*100))
+						fmt.Println("Progress:", dFmt(offset))
+						fmt.Println("Up to:", cFmt(upTo))
+						fmt.Println("Loop wait:", dFmt(loopWait))
+						fmt.Println("Transition type:", tType)
+						fmt.Println("From filename", tFromFilename)
+						fmt.Println("To filename", tToFilename)
+						fmt.Println("Blending images.")
+					}
+					// Blend the images, or use the image that was blended ahead of time
+					setmut.Lock()
+					imageFilename, err := gtw.Fade.Frame(tType, tFromFilename, tToFilename, ratio, tempImageFilename)
 					setmut.Unlock()
-					return err
-				}
-				setmut.Unlock()
-				// Double check that the generated file exists
-				if _, err := os.Stat(tempImageFilename); os.IsNotExist(err) {
-					fmt.Fprintf(os.Stderr, "File does not exist: %s\n", tempImageFilename)
-					return err // return from anon func
-				}
-				// Set the desktop wallpaper, if possible
-				if verbose {
-					fmt.Printf("Setting %s.\n", tempImageFilename)
-				}
-				if err := setWallpaperFunc(tempImageFilename); err != nil {
-					fmt.Fprintf(os.Stderr, "Could not set wallpaper: %v\n", err)
-					return err // return from anon func
-				}
-				return nil
-			})
+					if err != nil {
+						fmt.Fprintln(os.Stderr, err)
+						return err
+					}
+					// Set the desktop wallpaper, if possible
+					if verbose {
+						fmt.Printf("Setting %s.\n", imageFilename)
+					}
+					if err := setWallpaperFunc(imageFilename); err != nil {
+						fmt.Fprintf(os.Stderr, "Could not set wallpaper: %v\n", err)
+						return err // return from anon func
+					}
+					return nil
+				})
+			}
 
 			// Increase the variable that keeps track of the time
-			// eventTime = eventTime.Add(window)
+			eventTime = eventTime.Add(window)
 		} else {
 			// This should never happen, it would be an implementation error
 			panic("got an element that is not a GStatic and not a GTransition")
 		}
 	}
 
-	// Endless loop! Will wait loopWait duration between each event loop cycle.
-	eventloop.Run(verbose)
+	// Blend the images for the transitions in the background, ahead of time
+	go func() {
+		if err := stw.Prerender(verbose); err != nil {
+			fmt.Fprintf(os.Stderr, "Could not blend the images for the transitions: %v\n", err)
+		}
+	}()
 
-	return nil
+	return eventloop, nil
 }
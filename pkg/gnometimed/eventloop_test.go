@@ -0,0 +1,89 @@
+package gnometimed
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/xyproto/wallutils/pkg/event"
+)
+
+// writeImages writes small PNG images with the given names to a temporary
+// directory, and returns the directory
+func writeImages(t *testing.T, names ...string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for i, name := range names {
+		img := image.NewRGBA(image.Rect(0, 0, 8, 8))
+		draw.Draw(img, img.Bounds(), &image.Uniform{color.RGBA{0, uint8(i * 40), 0, 255}}, image.Point{}, draw.Src)
+		f, err := os.Create(filepath.Join(dir, name+".png"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := png.Encode(f, img); err != nil {
+			t.Fatal(err)
+		}
+		f.Close()
+	}
+	return dir
+}
+
+func TestFullDay(t *testing.T) {
+	const blended = "blended.jpg"
+	// The Adwaita timed wallpaper, but with images in a temporary directory
+	dir := writeImages(t, "adwaita-morning", "adwaita-day", "adwaita-night")
+	data, err := os.ReadFile("testdata/adwaita-timed.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	data = []byte(strings.ReplaceAll(strings.ReplaceAll(string(data), "/usr/share/backgrounds/gnome", dir), ".jpg", ".png"))
+	filename := filepath.Join(dir, "adwaita-timed.xml")
+	if err := os.WriteFile(filename, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	gtw, err := ParseXML(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	midnight := time.Date(2026, time.March, 1, 0, 0, 0, 0, time.Local)
+	clock := event.NewFakeClock(midnight)
+	gtw.Clock = clock
+	var current string
+	sys, err := gtw.EventSystem(false, func(filename string) error {
+		current = filepath.Base(filename)
+		return nil
+	}, filepath.Join(dir, blended))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		from, upTo int // minutes after midnight, the first is inclusive and the second is exclusive
+		image      string
+	}{
+		{0, 5 * 60, "adwaita-night.png"},
+		{5 * 60, 6 * 60, "adwaita-night.png"}, // the start of the transition to the morning
+		{6 * 60, 7 * 60, blended},             // halfway
+		{7 * 60, 10*60 + 30, "adwaita-morning.png"},
+		{10*60 + 30, 13 * 60, blended},
+		{13 * 60, 21 * 60, "adwaita-day.png"},
+		{21 * 60, 24 * 60, blended},
+	}
+	for _, test := range tests {
+		for minute := test.from; minute < test.upTo; minute++ {
+			clock.Set(midnight.Add(time.Duration(minute) * time.Minute))
+			sys.Step(false)
+			if current != test.image {
+				t.Fatalf("expected %s at %s, got %s", test.image, fmt.Sprintf("%02d:%02d", minute/60, minute%60), current)
+			}
+		}
+	}
+}
@@ -4,6 +4,9 @@ import (
 	"strconv"
 	"strings"
 	"time"
+
+	"github.com/xyproto/wallutils/pkg/event"
+	"github.com/xyproto/wallutils/pkg/simpletimed"
 )
 
 var DefaultEventLoopDelay = 30 * time.Second
@@ -20,10 +23,16 @@ type Wallpaper struct {
 
 	// LoopWait is for how long the event loop should sleep at every iteration
 	LoopWait time.Duration
+
+	// Clock is where the current time comes from, event.DefaultClock is used if it is nil
+	Clock event.Clock `json:"-"`
+
+	// Fade is how transitions are crossfaded, the default is used if it is nil
+	Fade *simpletimed.Fade `json:"-"`
 }
 
 func NewWallpaper(name string, path string, config *GBackground) *Wallpaper {
-	return &Wallpaper{name, path, config, DefaultEventLoopDelay}
+	return &Wallpaper{Name: name, Path: path, Config: config, LoopWait: DefaultEventLoopDelay}
 }
 
 // StartTime returns the timed wallpaper start time, as a time.Time
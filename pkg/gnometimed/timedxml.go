@@ -2,6 +2,7 @@ package gnometimed
 
 import (
 	"bytes"
+	"encoding/json"
 	"encoding/xml"
 	"errors"
 	"fmt"
@@ -85,6 +86,32 @@ func ParseXML(filename string) (*Wallpaper, error) {
 	return NewWallpaper(name, filename, &background), nil
 }
 
+// gbackgroundJSON is a GBackground, including the order of the <static> and
+// <transition> tags, for storing a parsed XML file as JSON
+type gbackgroundJSON struct {
+	StartTime       GStartTime
+	Statics         []GStatic
+	Transitions     []GTransition
+	StaticOrder     StaticMap     `json:",omitempty"`
+	TransitionOrder TransitionMap `json:",omitempty"`
+}
+
+// MarshalJSON encodes the parsed XML as JSON, including the order of the tags
+func (gb *GBackground) MarshalJSON() ([]byte, error) {
+	return json.Marshal(gbackgroundJSON{gb.StartTime, gb.Statics, gb.Transitions, gb.staticOrder, gb.transitionOrder})
+}
+
+// UnmarshalJSON decodes parsed XML that has been encoded with MarshalJSON
+func (gb *GBackground) UnmarshalJSON(data []byte) error {
+	var gj gbackgroundJSON
+	if err := json.Unmarshal(data, &gj); err != nil {
+		return err
+	}
+	gb.StartTime, gb.Statics, gb.Transitions = gj.StartTime, gj.Statics, gj.Transitions
+	gb.staticOrder, gb.transitionOrder = gj.StaticOrder, gj.TransitionOrder
+	return nil
+}
+
 // TransitionOrder finds the total position of a given GTransition position
 func (gb *GBackground) TransitionOrder(i int) (int, error) {
 	pos, ok := gb.transitionOrder[i]
@@ -1,7 +1,9 @@
 package gnometimed
 
 import (
+	"encoding/json"
 	"fmt"
+	"testing"
 )
 
 func ExampleParseXML() {
@@ -37,3 +39,27 @@ func ExampleParseXML() {
 	// 2011
 	// 2018
 }
+
+func TestJSON(t *testing.T) {
+	gtw, err := ParseXML("testdata/example1.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := json.Marshal(gtw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded Wallpaper
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if decoded.Name != gtw.Name || len(decoded.Config.Statics) != len(gtw.Config.Statics) {
+		t.Fatalf("unexpected wallpaper: %+v", decoded)
+	}
+	for i := range gtw.Config.Transitions {
+		expected, _ := gtw.Config.TransitionOrder(i)
+		if pos, err := decoded.Config.TransitionOrder(i); err != nil || pos != expected {
+			t.Errorf("transition %d: expected position %d, got %d (%v)", i, expected, pos, err)
+		}
+	}
+}
@@ -17,7 +17,7 @@ var h24 = time.Hour * 24
 func mod24(d time.Duration) time.Duration {
 	hourDiff := d % h24
 	if hourDiff < 0 {
-		return -hourDiff
+		return hourDiff + h24
 	}
 	return hourDiff
 }
@@ -10,140 +10,114 @@ import (
 	"syscall"
 	"time"
 
-	"github.com/anthonynsimon/bild/blend"
-	"github.com/anthonynsimon/bild/imgio"
 	"github.com/xyproto/wallutils/pkg/event"
 )
 
 var setmut = &sync.RWMutex{}
 
-// UntilNext finds the duration until the next event starts
-func (stw *Wallpaper) UntilNext(et time.Time) (time.Duration, time.Time) {
-	// Gather all start times from the list of transitions and list of static wallpaper commands
-	var startTimes []time.Time
-	for _, t := range stw.Transitions {
-		startTimes = append(startTimes, t.From)
+// occurrence is a point in time when an event starts
+type occurrence struct {
+	when time.Time
+	e    interface{} // *Static or *Transition
+}
+
+// occurrences returns the points in time when the events start, on the day
+// before, on the same day as and on the day after the given time
+func (stw *Wallpaper) occurrences(et time.Time) []occurrence {
+	var occs []occurrence
+	add := func(at time.Time, e interface{}) {
+		today := time.Date(et.Year(), et.Month(), et.Day(), at.Hour(), at.Minute(), 0, 0, et.Location())
+		occs = append(occs, occurrence{today.AddDate(0, 0, -1), e}, occurrence{today, e}, occurrence{today.AddDate(0, 0, 1), e})
 	}
+	// Static images come first, so that they are used if an image
+	// transition starts at the same time
 	for _, s := range stw.Statics {
-		startTimes = append(startTimes, s.At)
+		add(s.At, s)
 	}
-
-	// Using all the collected hours&minutes, create a list of times both today and tomorrow that uses those hours&minutes
-	now := time.Now()
-	var allTimes []time.Time
-	for _, t := range startTimes {
-		today := time.Date(now.Year(), now.Month(), now.Day(), t.Hour(), t.Minute(), 0, 0, now.Location())
-		tomorrow := today.AddDate(0, 0, 1)
-		allTimes = append(allTimes, today, tomorrow)
+	for _, t := range stw.Transitions {
+		add(t.From, t)
 	}
+	return occs
+}
 
-	// Now we have all possible start times, now to find the ones that are both positive and smallest
-	mindiff := h24
-	when := now
-	for _, t := range allTimes {
-		diff := t.Sub(et)
-		if diff > 0 && diff < mindiff {
-			mindiff = diff
-			when = t
-		}
+// UntilNext finds the duration until the next event starts
+func (stw *Wallpaper) UntilNext(et time.Time) (time.Duration, time.Time) {
+	_, when, err := stw.NextEvent(et)
+	if err != nil {
+		return h24, et
 	}
-
-	// Return the smallest time difference and the point in time for when that is
-	return mindiff, when
+	return when.Sub(et), when
 }
 
-// NextEvent finds the next event, given a timestamp.
+// NextEvent finds the next event, that starts after the given timestamp.
 // Returns an interface{} that is either a static or transition event.
 func (stw *Wallpaper) NextEvent(et time.Time) (interface{}, time.Time, error) {
-	// Create a map, from timestamps to wallpaper events
-	events := make(map[time.Time]interface{})
-	for _, t := range stw.Transitions {
-		events[t.From] = t
-	}
-	for _, s := range stw.Statics {
-		events[s.At] = s
+	var (
+		eventHappening interface{}
+		when           time.Time
+	)
+	for _, occ := range stw.occurrences(et) {
+		if occ.when.After(et) && (eventHappening == nil || occ.when.Before(when)) {
+			eventHappening = occ.e
+			when = occ.when
+		}
 	}
-	if len(events) == 0 {
+	if eventHappening == nil {
 		return nil, et, errors.New("can not find next event: got no events")
 	}
-
-	// Using all the collected hours&minutes, create a list of times both today and tomorrow that uses those hours&minutes
-	allTimes := make(map[time.Time]interface{})
-	now := time.Now()
-	for t, e := range events {
-		today := time.Date(now.Year(), now.Month(), now.Day(), t.Hour(), t.Minute(), 0, 0, now.Location())
-		tomorrow := today.AddDate(0, 0, 1)
-		allTimes[today] = e
-		allTimes[tomorrow] = e
-	}
-
-	// Now we have all possible start times, now to find the ones that are both positive and smallest
-	mindiff := h24
-	when := now
-	var eventHappening interface{}
-	for t, e := range allTimes {
-		if eventHappening == nil {
-			eventHappening = e
-		}
-		diff := t.Sub(et)
-		if diff > 0 && diff < mindiff {
-			mindiff = diff
-			when = t
-			eventHappening = e
-		}
-	}
-
 	return eventHappening, when, nil
 }
 
-// PrevEvent finds the previous event, given a timestamp.
+// PrevEvent finds the event that is ongoing at the given timestamp, which is
+// the last event that started at or before it.
 // Returns an interface{} that is either a static or transition event.
 func (stw *Wallpaper) PrevEvent(et time.Time) (interface{}, time.Time, error) {
-	// Create a map, from timestamps to wallpaper events
-	events := make(map[time.Time]interface{})
-	for _, t := range stw.Transitions {
-		events[t.From] = t
+	var (
+		eventHappening interface{}
+		when           time.Time
+	)
+	for _, occ := range stw.occurrences(et) {
+		if !occ.when.After(et) && (eventHappening == nil || occ.when.After(when)) {
+			eventHappening = occ.e
+			when = occ.when
+		}
 	}
-	for _, s := range stw.Statics {
-		events[s.At] = s
+	if eventHappening == nil {
+		return nil, et, errors.New("can not find previous event: got no events")
 	}
-	if len(events) == 0 {
-		return nil, et, errors.New("can not find next event: got no events")
+	return eventHappening, when, nil
+}
+
+// Progress returns the event that is ongoing at the given time, which is
+// either a *Static or a *Transition, and how far a transition has come,
+// from 0 to 1
+func (stw *Wallpaper) Progress(at time.Time) (interface{}, float64, error) {
+	e, when, err := stw.PrevEvent(at)
+	if err != nil {
+		return nil, 0, err
 	}
-	// Using all the collected hours&minutes, create a list of times both today and tomorrow that uses those hours&minutes
-	allTimes := make(map[time.Time]interface{})
-	now := time.Now()
-	for t, e := range events {
-		today := time.Date(now.Year(), now.Month(), now.Day(), t.Hour(), t.Minute(), 0, 0, now.Location())
-		tomorrow := today.AddDate(0, 0, 1)
-		allTimes[today] = e
-		allTimes[tomorrow] = e
+	t, ok := e.(*Transition)
+	if !ok || t.Duration() == 0 {
+		return e, 0, nil
 	}
-
-	// Now we have all possible start times, find the ones that are are below the given et,
-	// but as small as possible.
-
-	mindiff := h24
-	when := now
-	var eventHappening interface{}
-	for t, e := range allTimes {
-		if eventHappening == nil {
-			eventHappening = e
-		}
-		diff := et.Sub(t) // reverse subtraction, to find the time comparison back in time
-		if diff > 0 && diff < mindiff {
-			mindiff = diff
-			when = t
-			eventHappening = e
-		}
+	ratio := float64(at.Sub(when)) / float64(t.Duration())
+	if ratio > 1 {
+		ratio = 1
 	}
+	return e, ratio, nil
+}
 
-	return eventHappening, when, nil
+// clock returns the clock that is used for finding the current time
+func (stw *Wallpaper) clock() event.Clock {
+	if stw.Clock != nil {
+		return stw.Clock
+	}
+	return event.DefaultClock
 }
 
 // SetInitialWallpaper will set the first wallpaper, before starting the event loop
 func (stw *Wallpaper) SetInitialWallpaper(verbose bool, setWallpaperFunc func(string) error, tempImageFilename string) error {
-	now := time.Now()
+	now := stw.clock().Now()
 	e, whenPrev, err := stw.PrevEvent(now)
 	if err != nil {
 		return err
@@ -171,7 +145,7 @@ func (stw *Wallpaper) SetInitialWallpaper(verbose bool, setWallpaperFunc func(st
 		window := eventLength
 		cooldown := eventLength
 
-		imageFilename := s.Filename
+		imageFilename := s.Image(stw.UseDark())
 
 		if verbose {
 			fmt.Printf("Attaching to ongoing static wallpaper event that started at %s\n", cFmt(whenPrev))
@@ -214,7 +188,7 @@ func (stw *Wallpaper) SetInitialWallpaper(verbose bool, setWallpaperFunc func(st
 		upTo := whenNext
 
 		tType := t.Type
-		tFromFilename := t.FromFilename
+		tFromFilename, _ := t.Filenames(stw.UseDark())
 		loopWait := stw.LoopWait
 
 		if verbose {
@@ -243,6 +217,15 @@ func (stw *Wallpaper) SetInitialWallpaper(verbose bool, setWallpaperFunc func(st
 	return nil
 }
 
+// Refresh sets the wallpaper that should be shown right now again, while the
+// event loop is running. This can be used after resuming from sleep, or when
+// the color scheme has changed, see FollowsColorScheme.
+func (stw *Wallpaper) Refresh(verbose bool, setWallpaperFunc func(string) error, tempImageFilename string) error {
+	setmut.Lock()
+	defer setmut.Unlock()
+	return stw.SetInitialWallpaper(verbose, setWallpaperFunc, tempImageFilename)
+}
+
 // EventLoop will start the event loop for this Simple Timed Wallpaper
 func (stw *Wallpaper) EventLoop(verbose bool, setWallpaperFunc func(string) error, tempImageFilename string) error {
 	if verbose {
@@ -261,23 +244,38 @@ func (stw *Wallpaper) EventLoop(verbose bool, setWallpaperFunc func(string) erro
 			fmt.Println("Received", sig)
 			// Launch a goroutine for setting the wallpaper
 			go func() {
-				setmut.Lock()
-				if err := stw.SetInitialWallpaper(verbose, setWallpaperFunc, tempImageFilename); err != nil {
+				if err := stw.Refresh(verbose, setWallpaperFunc, tempImageFilename); err != nil {
 					fmt.Fprintln(os.Stderr, "Error:", err)
 				}
-				setmut.Unlock()
 			}()
 		}
 	}()
 
+	eventloop, err := stw.EventSystem(verbose, setWallpaperFunc, tempImageFilename)
+	if err != nil {
+		return err
+	}
+
+	// Endless loop! Will wait LoopWait duration between each event loop cycle.
+	eventloop.Run(verbose)
+
+	return nil
+}
+
+// EventSystem sets the initial wallpaper and returns an event system where
+// the events of this Simple Timed Wallpaper are registered. The event system
+// uses stw.Clock, if it is set. Call Run or Step on the returned event system
+// to trigger the events.
+func (stw *Wallpaper) EventSystem(verbose bool, setWallpaperFunc func(string) error, tempImageFilename string) (*event.EventSys, error) {
 	setmut.Lock()
 	if err := stw.SetInitialWallpaper(verbose, setWallpaperFunc, tempImageFilename); err != nil {
 		setmut.Unlock()
-		return err
+		return nil, err
 	}
 	setmut.Unlock()
 
-	eventloop := event.NewSystem(stw.LoopWait)
+	clock := stw.clock()
+	eventloop := event.NewSystemWithClock(stw.LoopWait, clock)
 
 	for _, s := range stw.Statics {
 		if verbose {
@@ -291,10 +289,12 @@ func (stw *Wallpaper) EventLoop(verbose bool, setWallpaperFunc func(string) erro
 
 		window := mod24(nextEventDuration) // duration until next event start
 		cooldown := window
-		imageFilename := s.Filename
+		s := s
 
 		// Register a static event
 		eventloop.ClockEvent(from.Hour(), from.Minute(), func() error {
+			// Use the variant that matches the color scheme when the event is triggered
+			imageFilename := s.Image(stw.UseDark())
 			if verbose {
 				fmt.Printf("Triggered static wallpaper event at %s\n", cFmt(from))
 				fmt.Println("Window:", dFmt(window))
@@ -334,14 +334,14 @@ func (stw *Wallpaper) EventLoop(verbose bool, setWallpaperFunc func(string) erro
 		window := t.Duration()
 		upTo := from.Add(window)
 		tType := t.Type
-		tFromFilename := t.FromFilename
-		tToFilename := t.ToFilename
+		t := t
 		loopWait := stw.LoopWait
-		halfway := from.Add(window / 2)
 
 		// Register the start of a transition event
 		eventloop.ClockEvent(from.Hour(), from.Minute(), func() error {
-			progress := mod24(window - event.ToToday(upTo).Sub(event.ToToday(time.Now())))
+			tFromFilename, _ := t.Filenames(stw.UseDark())
+			now := clock.Now()
+			progress := mod24(window - event.ToDate(upTo, now).Sub(now))
 			ratio := float64(progress) / float64(window)
 			if verbose {
 				fmt.Printf("Triggered transition event at %s (%d%% complete)\n", cFmt(from), int(ratio*100))
@@ -367,71 +367,54 @@ func (stw *Wallpaper) EventLoop(verbose bool, setWallpaperFunc func(string) erro
 			return nil
 		})
 
-		// Register a halfway transition event
-		eventloop.ClockEvent(halfway.Hour(), halfway.Minute(), func() error {
-			progress := mod24(window - event.ToToday(upTo).Sub(event.ToToday(time.Now())))
-			ratio := float64(progress) / float64(window)
-			if verbose {
-				fmt.Printf("Triggered transition event at %s (%d%% complete)\n", cFmt(from), int(ratio*100))
-				fmt.Println("Progress:", dFmt(progress))
-				fmt.Println("Up to:", cFmt(upTo))
-				fmt.Println("Window:", dFmt(window))
-				// fmt.Println("Cooldown:", dFmt(cooldown))
-				fmt.Println("Loop wait:", dFmt(loopWait))
-				fmt.Println("Transition type:", tType)
-				fmt.Println("From filename", tFromFilename)
-				fmt.Println("To filename", tToFilename)
-				fmt.Println("Crossfading between images.")
-			}
-			// Crossfade and write the new image to the temporary directory
-			tFromImg, err := imgio.Open(tFromFilename)
-			if err != nil {
-				fmt.Fprintln(os.Stderr, err)
-				return err
-			}
-			tToImg, err := imgio.Open(tToFilename)
-			if err != nil {
-				fmt.Fprintln(os.Stderr, err)
-				return err
-			}
-			// Crossfade and write the new image to the temporary directory
-			setmut.Lock()
-			blendedImage := blend.Opacity(tFromImg, tToImg, ratio)
-			err = imgio.Save(tempImageFilename, blendedImage, imgio.JPEGEncoder(100))
-			if err != nil {
-				fmt.Fprintf(os.Stderr, "Could not crossfade images in transition: %v\n", err)
+		// Register an event for each blended image, after the start of the transition
+		for _, offset := range stw.Fade.Offsets(window) {
+			at := from.Add(offset)
+			ratio := float64(offset) / float64(window)
+			eventloop.ClockEvent(at.Hour(), at.Minute(), func() error {
+				tFromFilename, tToFilename := t.Filenames(stw.UseDark())
+				if verbose {
+					fmt.Printf("Triggered transition event at %s (%d%% complete)\n", cFmt(from), int(ratio*100))
+					fmt.Println("Progress:", dFmt(offset))
+					fmt.Println("Up to:", cFmt(upTo))
+					fmt.Println("Window:", dFmt(window))
+					fmt.Println("Loop wait:", dFmt(loopWait))
+					fmt.Println("Transition type:", tType)
+					fmt.Println("From filename", tFromFilename)
+					fmt.Println("To filename", tToFilename)
+					fmt.Println("Blending images.")
+				}
+				// Blend the images, or use the image that was blended ahead of time
+				setmut.Lock()
+				imageFilename, err := stw.Fade.Frame(tType, tFromFilename, tToFilename, ratio, tempImageFilename)
 				setmut.Unlock()
-				return err
-			}
-			setmut.Unlock()
-			// Double check that the generated file exists
-			if _, err := os.Stat(tempImageFilename); os.IsNotExist(err) {
-				fmt.Fprintf(os.Stderr, "File does not exist: %s\n", tempImageFilename)
-				return err // return from anon func
-			}
-			// Set the desktop wallpaper, if possible
-			if verbose {
-				fmt.Printf("Setting %s.\n", tempImageFilename)
-			}
-			setmut.Lock()
-			if err := setWallpaperFunc(tempImageFilename); err != nil {
+				if err != nil {
+					fmt.Fprintln(os.Stderr, err)
+					return err
+				}
+				// Set the desktop wallpaper, if possible
+				if verbose {
+					fmt.Printf("Setting %s.\n", imageFilename)
+				}
+				setmut.Lock()
+				if err := setWallpaperFunc(imageFilename); err != nil {
+					setmut.Unlock()
+					fmt.Fprintf(os.Stderr, "Could not set wallpaper: %v\n", err)
+					return err // return from anon func
+				}
 				setmut.Unlock()
-				fmt.Fprintf(os.Stderr, "Could not set wallpaper: %v\n", err)
-				return err // return from anon func
-			}
-			setmut.Unlock()
-			return nil
-		})
+				return nil
+			})
+		}
 
 	}
 
-	// Endless loop! Will wait LoopWait duration between each event loop cycle.
-	eventloop.Run(verbose)
-
-	// eventloop.Run returns immediately, so start an endless loop
-	for {
-		time.Sleep(1 * time.Second)
-	}
+	// Blend the images for the transitions in the background, ahead of time
+	go func() {
+		if err := stw.Prerender(verbose); err != nil {
+			fmt.Fprintf(os.Stderr, "Could not blend the images for the transitions: %v\n", err)
+		}
+	}()
 
-	return nil
+	return eventloop, nil
 }
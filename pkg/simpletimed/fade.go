@@ -0,0 +1,240 @@
+package simpletimed
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/anthonynsimon/bild/imgio"
+	"github.com/xyproto/wallutils/pkg/event"
+)
+
+// Fade is how transitions are crossfaded. A nil *Fade can be used, and
+// gives the default, which is to set the first image at the start of a
+// transition and one blended image halfway.
+type Fade struct {
+	// Interval is how often a new blended image is set during a
+	// transition. The events happen at whole minutes, so a shorter interval
+	// than one minute is the same as one minute.
+	Interval time.Duration
+
+	// Steps is the number of images a transition is shown as, including
+	// the first image. It is only used if Interval is 0.
+	Steps int
+
+	// CacheDir is where the blended images are stored, so that they are
+	// only blended once, and can be blended ahead of time. If it is empty,
+	// the blended images are written to the temporary image file when they
+	// are needed. See ForWallpaper for one cache directory per wallpaper.
+	CacheDir string
+
+	// Prune is for removing the blended images that are not used by the
+	// timed wallpaper from CacheDir, after Prerender has blended the images
+	// that are used, so that the cache directory does not keep growing
+	Prune bool
+}
+
+// ForWallpaper returns a copy of f where the cache directory is a
+// subdirectory of CacheDir for the timed wallpaper with the given path, so
+// that pruning the blended images for one timed wallpaper does not remove
+// the blended images for another one
+func (f *Fade) ForWallpaper(path string) *Fade {
+	if f == nil || f.CacheDir == "" {
+		return f
+	}
+	if absPath, err := filepath.Abs(path); err == nil {
+		path = absPath
+	}
+	fw := *f
+	fw.CacheDir = filepath.Join(f.CacheDir, fmt.Sprintf("%x", sha256.Sum256([]byte(path)))[:16])
+	return &fw
+}
+
+// DefaultFadeSteps is the number of images a transition is shown as, if
+// neither an interval nor a number of steps is given
+const DefaultFadeSteps = 2
+
+// Offsets returns when a new blended image should be set, as the time
+// since the start of a transition of the given length. The first image is
+// set at the start of the transition, so 0 is not included. The offsets are
+// at least one minute apart.
+func (f *Fade) Offsets(window time.Duration) []time.Duration {
+	if window <= 0 {
+		return nil
+	}
+	interval := window / DefaultFadeSteps
+	if f != nil && f.Interval > 0 {
+		interval = f.Interval
+	} else if f != nil && f.Steps > 0 {
+		interval = window / time.Duration(f.Steps)
+	}
+	if interval < time.Minute {
+		interval = time.Minute
+	}
+	var offsets []time.Duration
+	for offset := interval; offset < window; offset += interval {
+		offsets = append(offsets, offset)
+	}
+	return offsets
+}
+
+// cacheFilename returns where the given images blended with the given
+// ratio are stored in the cache. The size and modification time of the
+// images are part of the name, so that changed images are blended again.
+func (f *Fade) cacheFilename(transitionType, fromFilename, toFilename string, ratio float64) (string, error) {
+	h := sha256.New()
+	for _, filename := range []string{fromFilename, toFilename} {
+		absFilename, err := filepath.Abs(filename)
+		if err != nil {
+			return "", err
+		}
+		fi, err := os.Stat(absFilename)
+		if err != nil {
+			return "", err
+		}
+		fmt.Fprintf(h, "%s\x00%d\x00%d\x00", absFilename, fi.Size(), fi.ModTime().UnixNano())
+	}
+	fmt.Fprintf(h, "%s\x00%.6f", transitionType, ratio)
+	return filepath.Join(f.CacheDir, fmt.Sprintf("%x.jpg", h.Sum(nil)[:16])), nil
+}
+
+// Frame returns the filename of an image that is the given ratio through a
+// transition of the given type, from one image to another. The image is read
+// from the cache if it has been blended before. If there is no cache
+// directory, the image is written to tempImageFilename.
+func (f *Fade) Frame(transitionType, fromFilename, toFilename string, ratio float64, tempImageFilename string) (string, error) {
+	if f == nil || f.CacheDir == "" {
+		blendedImage, err := BlendFiles(transitionType, fromFilename, toFilename, ratio)
+		if err != nil {
+			return "", err
+		}
+		if err := imgio.Save(tempImageFilename, blendedImage, imgio.JPEGEncoder(100)); err != nil {
+			return "", fmt.Errorf("could not blend images in transition: %v", err)
+		}
+		return tempImageFilename, nil
+	}
+	filename, err := f.cacheFilename(transitionType, fromFilename, toFilename, ratio)
+	if err != nil {
+		return "", err
+	}
+	if exists(filename) {
+		return filename, nil
+	}
+	blendedImage, err := BlendFiles(transitionType, fromFilename, toFilename, ratio)
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(f.CacheDir, 0o755); err != nil {
+		return "", err
+	}
+	// Write to a temporary file first, since the same image may be blended
+	// by the event loop and the background worker at the same time
+	tmpFile, err := os.CreateTemp(f.CacheDir, ".frame-*.jpg")
+	if err != nil {
+		return "", err
+	}
+	tmpFile.Close()
+	if err := imgio.Save(tmpFile.Name(), blendedImage, imgio.JPEGEncoder(100)); err != nil {
+		os.Remove(tmpFile.Name())
+		return "", fmt.Errorf("could not blend images in transition: %v", err)
+	}
+	if err := os.Rename(tmpFile.Name(), filename); err != nil {
+		os.Remove(tmpFile.Name())
+		return "", err
+	}
+	return filename, nil
+}
+
+// Prerender blends the images for all the transitions ahead of time, and
+// stores them in the cache directory of stw.Fade. The transitions that are
+// coming up next are blended first. Dark variants are blended too, since the
+// color scheme may change. Afterwards, the blended images that are not used
+// by this timed wallpaper are removed from the cache directory, if
+// stw.Fade.Prune is true. Nothing is done if there is no cache directory.
+func (stw *Wallpaper) Prerender(verbose bool) error {
+	if stw.Fade == nil || stw.Fade.CacheDir == "" {
+		return nil
+	}
+	now := stw.clock().Now()
+	// Find when each transition ends next, which is soonest for an ongoing transition
+	type upcoming struct {
+		t   *Transition
+		end time.Time
+	}
+	var transitions []upcoming
+	for _, t := range stw.Transitions {
+		end := event.ToDate(t.From, now).AddDate(0, 0, -1).Add(t.Duration())
+		for !end.After(now) {
+			end = end.AddDate(0, 0, 1)
+		}
+		transitions = append(transitions, upcoming{t, end})
+	}
+	sort.SliceStable(transitions, func(i, j int) bool {
+		return transitions[i].end.Before(transitions[j].end)
+	})
+	used := make(map[string]bool)
+	for _, u := range transitions {
+		window := u.t.Duration()
+		for _, dark := range []bool{false, true} {
+			fromFilename, toFilename := u.t.Filenames(dark)
+			if dark && fromFilename == u.t.FromFilename && toFilename == u.t.ToFilename {
+				// There are no dark variants
+				continue
+			}
+			for _, offset := range stw.Fade.Offsets(window) {
+				filename, err := stw.Fade.Frame(u.t.Type, fromFilename, toFilename, float64(offset)/float64(window), "")
+				if err != nil {
+					return err
+				}
+				used[filename] = true
+			}
+		}
+	}
+	if verbose {
+		fmt.Printf("%d blended images for transitions are ready in %s\n", len(used), stw.Fade.CacheDir)
+	}
+	if !stw.Fade.Prune {
+		return nil
+	}
+	removed, err := stw.Fade.prune(used)
+	if err != nil {
+		return err
+	}
+	if verbose && removed > 0 {
+		fmt.Printf("%d unused blended images were removed from %s\n", removed, stw.Fade.CacheDir)
+	}
+	return nil
+}
+
+// prune removes the blended images in the cache directory that are not in
+// the given set of filenames, and returns how many were removed. Temporary
+// files that may still be written to by Frame are kept, unless they are old.
+func (f *Fade) prune(used map[string]bool) (int, error) {
+	entries, err := os.ReadDir(f.CacheDir)
+	if os.IsNotExist(err) {
+		return 0, nil
+	} else if err != nil {
+		return 0, err
+	}
+	removed := 0
+	for _, entry := range entries {
+		filename := filepath.Join(f.CacheDir, entry.Name())
+		if entry.IsDir() || filepath.Ext(filename) != ".jpg" || used[filename] {
+			continue
+		}
+		if strings.HasPrefix(entry.Name(), ".") {
+			if fi, err := entry.Info(); err != nil || time.Since(fi.ModTime()) < time.Hour {
+				continue
+			}
+		}
+		if err := os.Remove(filename); err != nil && !os.IsNotExist(err) {
+			return removed, err
+		}
+		removed++
+	}
+	return removed, nil
+}
@@ -0,0 +1,73 @@
+package simpletimed
+
+import (
+	"errors"
+	"image"
+	"time"
+
+	"github.com/anthonynsimon/bild/imgio"
+)
+
+// BlendFiles blends the two given images with Blend, for the given
+// transition type and ratio. This is what the event loops use for transitions.
+func BlendFiles(transitionType, fromFilename, toFilename string, ratio float64) (image.Image, error) {
+	fromImg, err := imgio.Open(fromFilename)
+	if err != nil {
+		return nil, err
+	}
+	toImg, err := imgio.Open(toFilename)
+	if err != nil {
+		return nil, err
+	}
+	return Blend(transitionType, fromImg, toImg, ratio)
+}
+
+// Shown returns the images that the event loop has set at the given time,
+// and the ratio they are crossfaded with. For static images and at the
+// start of a transition, only fromFilename is used and the ratio is 0.
+func (stw *Wallpaper) Shown(at time.Time) (fromFilename, toFilename string, ratio float64, err error) {
+	e, when, err := stw.PrevEvent(at)
+	if err != nil {
+		return "", "", 0, err
+	}
+	switch v := e.(type) {
+	case *Static:
+		return v.Image(stw.UseDark()), "", 0, nil
+	case *Transition:
+		fromFilename, toFilename = v.Filenames(stw.UseDark())
+		window := v.Duration()
+		if window == 0 {
+			return fromFilename, "", 0, nil
+		}
+		// The last blended image that was set before the given time is shown,
+		// and each image is set at the start of a minute
+		for _, offset := range stw.Fade.Offsets(window) {
+			if when.Add(offset).Truncate(time.Minute).After(at) {
+				break
+			}
+			ratio = float64(offset) / float64(window)
+		}
+		if ratio == 0 {
+			return fromFilename, "", 0, nil
+		}
+		return fromFilename, toFilename, ratio, nil
+	}
+	return "", "", 0, errors.New("no event at the given time")
+}
+
+// Render returns the wallpaper image that the event loop has set at the
+// given time
+func (stw *Wallpaper) Render(at time.Time) (image.Image, error) {
+	fromFilename, toFilename, ratio, err := stw.Shown(at)
+	if err != nil {
+		return nil, err
+	}
+	if toFilename == "" {
+		return imgio.Open(fromFilename)
+	}
+	e, _, err := stw.PrevEvent(at)
+	if err != nil {
+		return nil, err
+	}
+	return BlendFiles(e.(*Transition).Type, fromFilename, toFilename, ratio)
+}
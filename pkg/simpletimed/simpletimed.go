@@ -7,66 +7,136 @@ import (
 	"sort"
 	"strings"
 	"time"
+
+	"github.com/xyproto/wallutils/pkg/event"
 )
 
 type Wallpaper struct {
 	STWVersion  string
 	Name        string
 	Format      string
+	Theme       string // "auto", "light" or "dark", for which variant to use. "auto" is the default.
 	Path        string // not part of the file data, but handy when parsing
 	Statics     []*Static
 	Transitions []*Transition
 	LoopWait    time.Duration // how long the main event loop should sleep
+	PreferDark  func() bool   `json:"-"` // checks if the current color scheme is dark, when the theme is "auto"
+	Clock       event.Clock   `json:"-"` // where the current time comes from, event.DefaultClock is used if it is nil
+	Fade        *Fade         `json:"-"` // how transitions are crossfaded, the default is used if it is nil
 }
 
 type Static struct {
-	At       time.Time
-	Filename string
+	At           time.Time
+	Filename     string
+	DarkFilename string // the dark variant, if any
 }
 
 type Transition struct {
-	From         time.Time
-	UpTo         time.Time
-	FromFilename string
-	ToFilename   string
-	Type         string
+	From             time.Time
+	UpTo             time.Time
+	FromFilename     string
+	ToFilename       string
+	Type             string
+	DarkFromFilename string // the dark variant of FromFilename, if any
+	DarkToFilename   string // the dark variant of ToFilename, if any
 }
 
+// themes are the valid values for the theme field
+var themes = []string{"auto", "light", "dark"}
+
 var DefaultLoopTime = 30 * time.Second
 
 func (t *Transition) Duration() time.Duration {
 	return mod24(t.UpTo.Sub(t.From))
 }
 
-func (t *Transition) String(format string) string {
-	if !strings.Contains(format, "%s") {
-		// Return the verbose version, where type is always included and the filename is not reduced with a common string format
-		if t.Type == "overlay" {
-			return fmt.Sprintf("@%s-%s: %s .. %s", cFmt(t.From), cFmt(t.UpTo), t.FromFilename, t.ToFilename)
-		}
-		return fmt.Sprintf("@%s-%s: %s .. %s | %s", cFmt(t.From), cFmt(t.UpTo), t.FromFilename, t.ToFilename, t.Type)
+// Filenames returns the filenames to transition from and to, using the dark
+// variants if dark is true and there are dark variants
+func (t *Transition) Filenames(dark bool) (string, string) {
+	if dark && t.DarkFromFilename != "" && t.DarkToFilename != "" {
+		return t.DarkFromFilename, t.DarkToFilename
 	}
-	fields := strings.SplitN(format, "%s", 2)
-	prefix := fields[0]
-	suffix := fields[1]
+	return t.FromFilename, t.ToFilename
+}
+
+func (t *Transition) String(format string) string {
+	var s string
 	if t.Type == "overlay" {
-		return fmt.Sprintf("@%s-%s: %s .. %s", cFmt(t.From), cFmt(t.UpTo), t.FromFilename[len(prefix):len(t.FromFilename)-len(suffix)], t.ToFilename[len(prefix):len(t.ToFilename)-len(suffix)])
+		s = fmt.Sprintf("@%s-%s: %s .. %s", cFmt(t.From), cFmt(t.UpTo), unformat(format, t.FromFilename), unformat(format, t.ToFilename))
+	} else {
+		s = fmt.Sprintf("@%s-%s: %s .. %s | %s", cFmt(t.From), cFmt(t.UpTo), unformat(format, t.FromFilename), unformat(format, t.ToFilename), t.Type)
+	}
+	if t.DarkFromFilename != "" && t.DarkToFilename != "" {
+		s += fmt.Sprintf("\ndark: %s .. %s", unformat(format, t.DarkFromFilename), unformat(format, t.DarkToFilename))
 	}
-	return fmt.Sprintf("@%s-%s: %s .. %s | %s", cFmt(t.From), cFmt(t.UpTo), t.FromFilename[len(prefix):len(t.FromFilename)-len(suffix)], t.ToFilename[len(prefix):len(t.ToFilename)-len(suffix)], t.Type)
+	return s
+}
+
+// Image returns the image filename, using the dark variant if dark is true
+// and there is a dark variant
+func (s *Static) Image(dark bool) string {
+	if dark && s.DarkFilename != "" {
+		return s.DarkFilename
+	}
+	return s.Filename
 }
 
 func (s *Static) String(format string) string {
-	if !strings.Contains(format, "%s") {
-		// Return the verbose version, where type is always included and the filename is not reduced with a common string format
-		return fmt.Sprintf("@%s: %s", cFmt(s.At), s.Filename)
+	line := fmt.Sprintf("@%s: %s", cFmt(s.At), unformat(format, s.Filename))
+	if s.DarkFilename != "" {
+		line += "\ndark: " + unformat(format, s.DarkFilename)
+	}
+	return line
+}
+
+// HasTransitionTypes checks if any of the transitions have another
+// transition type than "overlay"
+func (stw *Wallpaper) HasTransitionTypes() bool {
+	for _, t := range stw.Transitions {
+		if t.Type != "overlay" && t.Type != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// HasDarkVariants checks if any of the events have dark variants
+func (stw *Wallpaper) HasDarkVariants() bool {
+	for _, s := range stw.Statics {
+		if s.DarkFilename != "" {
+			return true
+		}
+	}
+	for _, t := range stw.Transitions {
+		if t.DarkFromFilename != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// FollowsColorScheme checks if the wallpaper changes with the color scheme,
+// which is when there are dark variants and the theme is "auto"
+func (stw *Wallpaper) FollowsColorScheme() bool {
+	return stw.Theme != "dark" && stw.Theme != "light" && stw.HasDarkVariants()
+}
+
+// UseDark checks if the dark variants should be used, given the theme field
+// and the current color scheme
+func (stw *Wallpaper) UseDark() bool {
+	switch stw.Theme {
+	case "dark":
+		return true
+	case "light":
+		return false
 	}
-	fields := strings.SplitN(format, "%s", 2)
-	prefix := fields[0]
-	suffix := fields[1]
-	return fmt.Sprintf("@%s: %s", cFmt(s.At), s.Filename[len(prefix):len(s.Filename)-len(suffix)])
+	return stw.PreferDark != nil && stw.PreferDark()
 }
 
-// String outputs a valid STW file, where the timestamps are in a sorted order
+// String outputs a valid STW file, where the timestamps are in a sorted order.
+// Version 1.1 is used if there are dark variants or a theme, and version 1.2
+// if there are other transition types than "overlay", since the earlier
+// versions do not support them.
 func (stw *Wallpaper) String() string {
 	var lines []string
 	for _, s := range stw.Statics {
@@ -75,8 +145,20 @@ func (stw *Wallpaper) String() string {
 	for _, t := range stw.Transitions {
 		lines = append(lines, t.String(stw.Format))
 	}
+	// The dark variants follow the timestamps, so they are sorted together
 	sort.Strings(lines)
-	return fmt.Sprintf("stw: %s\nname: %s\nformat: %s\n", stw.STWVersion, stw.Name, stw.Format) + strings.Join(lines, "\n")
+	version := stw.STWVersion
+	if version == "1.0" && (stw.Theme != "" || stw.HasDarkVariants()) {
+		version = "1.1"
+	}
+	if (version == "1.0" || version == "1.1") && stw.HasTransitionTypes() {
+		version = "1.2"
+	}
+	header := fmt.Sprintf("stw: %s\nname: %s\nformat: %s\n", version, stw.Name, stw.Format)
+	if stw.Theme != "" {
+		header += fmt.Sprintf("theme: %s\n", stw.Theme)
+	}
+	return header + strings.Join(lines, "\n")
 }
 
 func NewWallpaper(version, name, format string) *Wallpaper {
@@ -84,17 +166,21 @@ func NewWallpaper(version, name, format string) *Wallpaper {
 		statics     []*Static
 		transitions []*Transition
 	)
-	return &Wallpaper{version, name, format, "", statics, transitions, DefaultLoopTime}
+	return &Wallpaper{STWVersion: version, Name: name, Format: format, Statics: statics, Transitions: transitions, LoopWait: DefaultLoopTime}
+}
+
+// formatFilename applies the format string, if there is one, to the given filename
+func (stw *Wallpaper) formatFilename(filename string) string {
+	if len(stw.Format) > 0 {
+		return fmt.Sprintf(stw.Format, filename)
+	}
+	return filename
 }
 
 func (stw *Wallpaper) AddStatic(at time.Time, filename string) {
 	var s Static
 	s.At = at
-	if len(stw.Format) > 0 {
-		s.Filename = fmt.Sprintf(stw.Format, filename)
-	} else {
-		s.Filename = filename
-	}
+	s.Filename = stw.formatFilename(filename)
 	stw.Statics = append(stw.Statics, &s)
 }
 
@@ -102,13 +188,8 @@ func (stw *Wallpaper) AddTransition(from, upto time.Time, fromFilename, toFilena
 	var t Transition
 	t.From = from
 	t.UpTo = upto
-	if len(stw.Format) > 0 {
-		t.FromFilename = fmt.Sprintf(stw.Format, fromFilename)
-		t.ToFilename = fmt.Sprintf(stw.Format, toFilename)
-	} else {
-		t.FromFilename = fromFilename
-		t.ToFilename = toFilename
-	}
+	t.FromFilename = stw.formatFilename(fromFilename)
+	t.ToFilename = stw.formatFilename(toFilename)
 	if len(transitionType) == 0 {
 		t.Type = "overlay"
 	} else {
@@ -131,6 +212,7 @@ func ParseSTW(filename string) (*Wallpaper, error) {
 func DataToSimple(path string, data []byte) (*Wallpaper, error) {
 	var ts []*Transition
 	var ss []*Static
+	var last interface{} // the previous event, for the dark variants
 	parsed := make(map[string]string)
 	for lineCount, byteLine := range bytes.Split(data, []byte("\n")) {
 		trimmed := strings.TrimSpace(string(byteLine))
@@ -177,7 +259,9 @@ func DataToSimple(path string, data []byte) (*Wallpaper, error) {
 				if err != nil {
 					return nil, fmt.Errorf("could not parse %s (time), line %d: %s", path, lineCount, trimmed)
 				}
-				ts = append(ts, &Transition{t1, t2, filename1, filename2, transitionType})
+				t := &Transition{From: t1, UpTo: t2, FromFilename: filename1, ToFilename: filename2, Type: transitionType}
+				ts = append(ts, t)
+				last = t
 			} else {
 				if strings.Count(trimmed, ":") < 2 {
 					return nil, fmt.Errorf("could not parse %s (missing colon), line %d: %s", path, lineCount, trimmed)
@@ -190,7 +274,9 @@ func DataToSimple(path string, data []byte) (*Wallpaper, error) {
 				if err != nil {
 					return nil, fmt.Errorf("could not parse %s (time), line %d: %s", path, lineCount, trimmed)
 				}
-				ss = append(ss, &Static{t1, filename})
+				s := &Static{At: t1, Filename: filename}
+				ss = append(ss, s)
+				last = s
 			}
 		} else if strings.Contains(trimmed, ":") {
 			// fmt.Println("FIELD", trimmed)
@@ -200,6 +286,23 @@ func DataToSimple(path string, data []byte) (*Wallpaper, error) {
 			fields := strings.SplitN(trimmed, ":", 2)
 			key := strings.TrimSpace(fields[0])
 			value := strings.TrimSpace(fields[1])
+			if key == "dark" {
+				// A dark variant for the previous event (STW 1.1)
+				switch e := last.(type) {
+				case *Static:
+					e.DarkFilename = value
+				case *Transition:
+					if !strings.Contains(value, "..") {
+						return nil, fmt.Errorf("could not parse %s (missing \"..\"), line %d: %s", path, lineCount, trimmed)
+					}
+					fields := strings.SplitN(value, "..", 2)
+					e.DarkFromFilename = strings.TrimSpace(fields[0])
+					e.DarkToFilename = strings.TrimSpace(fields[1])
+				default:
+					return nil, fmt.Errorf("could not parse %s (dark variant before any event), line %d: %s", path, lineCount, trimmed)
+				}
+				continue
+			}
 			parsed[key] = value
 		} else {
 			return nil, fmt.Errorf("could not parse %s (invalid syntax), line %d: %s", path, lineCount, trimmed)
@@ -211,17 +314,42 @@ func DataToSimple(path string, data []byte) (*Wallpaper, error) {
 	}
 	name, _ := parsed["name"]     // optional
 	format, _ := parsed["format"] // optional
+	theme, _ := parsed["theme"]   // optional
 	// pacman, _ := parsed["ILoveCandy"] // optional
+	if theme != "" && !has(themes, theme) {
+		return nil, fmt.Errorf("invalid theme in %s: %s (must be one of %s)", path, theme, strings.Join(themes, ", "))
+	}
+	for _, t := range ts {
+		if has(TransitionTypes, t.Type) {
+			continue
+		}
+		// Transition types were added in STW 1.2. Files that use an earlier
+		// version are read the same way as before, with only crossfades.
+		if version == "1.0" || version == "1.1" {
+			t.Type = "overlay"
+			continue
+		}
+		return nil, fmt.Errorf("invalid transition type in %s: %s (must be one of %s)", path, t.Type, strings.Join(TransitionTypes, ", "))
+	}
 
 	stw := NewWallpaper(version, name, format)
+	stw.Theme = theme
 	stw.Path = path
 	for _, t := range ts {
 		// Adding transitions in a way that make sure the format string is used when interpreting the filenames
 		stw.AddTransition(t.From, t.UpTo, t.FromFilename, t.ToFilename, t.Type)
+		if t.DarkFromFilename != "" {
+			added := stw.Transitions[len(stw.Transitions)-1]
+			added.DarkFromFilename = stw.formatFilename(t.DarkFromFilename)
+			added.DarkToFilename = stw.formatFilename(t.DarkToFilename)
+		}
 	}
 	for _, s := range ss {
 		// Adding static images in a way that make sure the format string is used when interpreting the filenames
 		stw.AddStatic(s.At, s.Filename)
+		if s.DarkFilename != "" {
+			stw.Statics[len(stw.Statics)-1].DarkFilename = stw.formatFilename(s.DarkFilename)
+		}
 	}
 	// fmt.Println(stw)
 	return stw, nil
@@ -2,6 +2,17 @@ package simpletimed
 
 import (
 	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/xyproto/wallutils/pkg/event"
 )
 
 func ExampleParseSTW() {
@@ -21,3 +32,431 @@ func ExampleParseSTW() {
 	// adwaita-timed
 	// comments
 }
+
+func TestDarkVariants(t *testing.T) {
+	stw, err := ParseSTW("testdata/adwaita-dark.stw")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stw.STWVersion != "1.1" || stw.Theme != "auto" || !stw.HasDarkVariants() {
+		t.Errorf("unexpected wallpaper: %+v", stw)
+	}
+	morning := stw.Statics[0]
+	if morning.Image(false) != "/usr/share/backgrounds/gnome/adwaita-morning.jpg" || morning.Image(true) != "/usr/share/backgrounds/gnome/adwaita-morning-dark.jpg" {
+		t.Errorf("unexpected images: %s and %s", morning.Image(false), morning.Image(true))
+	}
+	// Events without a dark variant use the light image
+	if day := stw.Statics[1]; day.Image(true) != day.Filename {
+		t.Errorf("expected the light image, got %s", day.Image(true))
+	}
+	if from, to := stw.Transitions[0].Filenames(true); from != "/usr/share/backgrounds/gnome/adwaita-morning-dark.jpg" || to != "/usr/share/backgrounds/gnome/adwaita-day-dark.jpg" {
+		t.Errorf("unexpected dark transition: %s .. %s", from, to)
+	}
+
+	// The variant follows the color scheme, unless the theme says otherwise
+	dark := false
+	stw.PreferDark = func() bool { return dark }
+	if stw.UseDark() {
+		t.Error("expected the light variant")
+	}
+	dark = true
+	if !stw.UseDark() {
+		t.Error("expected the dark variant")
+	}
+	if !stw.FollowsColorScheme() {
+		t.Error("expected the wallpaper to follow the color scheme")
+	}
+	stw.Theme = "light"
+	if stw.UseDark() || stw.FollowsColorScheme() {
+		t.Error("expected the light variant, because of the theme")
+	}
+	stw.Theme = "auto"
+
+	// String and DataToSimple must round-trip the dark variants
+	data, err := os.ReadFile("testdata/adwaita-dark.stw")
+	if err != nil {
+		t.Fatal(err)
+	}
+	stw2, err := DataToSimple(stw.Path, []byte(stw.String()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stw2.String() != stw.String() {
+		t.Errorf("the wallpaper does not round-trip:\n%s\n\n%s", stw, stw2)
+	}
+	for _, line := range strings.Split(stw.String(), "\n") {
+		if !strings.Contains(string(data), line) {
+			t.Errorf("unexpected line: %s", line)
+		}
+	}
+
+	// Version 1.0 files have no dark variants
+	stw, err = ParseSTW("testdata/adwaita-timed2.stw")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stw.HasDarkVariants() || stw.UseDark() || !strings.HasPrefix(stw.String(), "stw: 1.0\n") {
+		t.Errorf("unexpected dark variants in %s", stw.Path)
+	}
+
+	if _, err := DataToSimple("invalid.stw", []byte("stw: 1.1\ndark: night\n@00:00: night\n")); err == nil {
+		t.Error("expected an error for a dark variant before any event")
+	}
+	if _, err := DataToSimple("invalid.stw", []byte("stw: 1.1\ntheme: purple\n@00:00: night\n")); err == nil {
+		t.Error("expected an error for an invalid theme")
+	}
+}
+
+// writeImages writes small PNG images with the given names to a temporary
+// directory, and returns the directory
+func writeImages(t *testing.T, names ...string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for i, name := range names {
+		img := image.NewRGBA(image.Rect(0, 0, 8, 8))
+		draw.Draw(img, img.Bounds(), &image.Uniform{color.RGBA{uint8(i * 40), 0, 0, 255}}, image.Point{}, draw.Src)
+		f, err := os.Create(filepath.Join(dir, name+".png"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := png.Encode(f, img); err != nil {
+			t.Fatal(err)
+		}
+		f.Close()
+	}
+	return dir
+}
+
+// midnight is the start of the simulated days in the tests
+var midnight = time.Date(2026, time.March, 1, 0, 0, 0, 0, time.Local)
+
+// clockTime returns the given HH:MM time on the simulated day, where
+// "24:00" is the end of the day
+func clockTime(t *testing.T, hhmm string) time.Time {
+	t.Helper()
+	var hour, minute int
+	if _, err := fmt.Sscanf(hhmm, "%d:%d", &hour, &minute); err != nil {
+		t.Fatal(err)
+	}
+	return midnight.Add(time.Duration(hour)*time.Hour + time.Duration(minute)*time.Minute)
+}
+
+const fullDaySTW = `stw: 1.1
+name: full-day
+format: %s/%%s.png
+@07:00: morning
+dark: morning-dark
+@08:00-13:00: morning .. day
+dark: morning-dark .. day
+@13:00: day
+@18:00-00:00: day .. night
+@00:00: night
+@05:00-07:00: night .. morning
+`
+
+func TestFullDay(t *testing.T) {
+	const blended = "blended.jpg"
+	type span struct {
+		from, upTo string // HH:MM, the first is inclusive and the second is exclusive
+		image      string
+	}
+	tests := []struct {
+		name       string
+		preferDark bool
+		spans      []span
+	}{
+		{"light", false, []span{
+			{"00:00", "05:00", "night.png"},
+			{"05:00", "06:00", "night.png"}, // the start of the transition to the morning
+			{"06:00", "07:00", blended},     // halfway
+			{"07:00", "10:30", "morning.png"},
+			{"10:30", "13:00", blended},
+			{"13:00", "21:00", "day.png"},
+			{"21:00", "24:00", blended},
+		}},
+		{"dark", true, []span{
+			{"00:00", "06:00", "night.png"},
+			{"06:00", "07:00", blended},
+			{"07:00", "10:30", "morning-dark.png"},
+			{"10:30", "13:00", blended},
+			{"13:00", "21:00", "day.png"},
+			{"21:00", "24:00", blended},
+		}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			dir := writeImages(t, "morning", "morning-dark", "day", "night")
+			stw, err := DataToSimple("full-day.stw", []byte(fmt.Sprintf(fullDaySTW, dir)))
+			if err != nil {
+				t.Fatal(err)
+			}
+			clock := event.NewFakeClock(midnight)
+			stw.Clock = clock
+			stw.PreferDark = func() bool { return test.preferDark }
+			var current string
+			sys, err := stw.EventSystem(false, func(filename string) error {
+				current = filepath.Base(filename)
+				return nil
+			}, filepath.Join(dir, blended))
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, s := range test.spans {
+				for at, upTo := clockTime(t, s.from), clockTime(t, s.upTo); at.Before(upTo); at = at.Add(time.Minute) {
+					clock.Set(at)
+					sys.Step(false)
+					if current != s.image {
+						t.Fatalf("expected %s at %s, got %s", s.image, cFmt(at), current)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestRefresh(t *testing.T) {
+	dir := writeImages(t, "morning", "morning-dark", "day", "night")
+	stw, err := DataToSimple("full-day.stw", []byte(fmt.Sprintf(fullDaySTW, dir)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	clock := event.NewFakeClock(clockTime(t, "08:00"))
+	stw.Clock = clock
+	dark := false
+	stw.PreferDark = func() bool { return dark }
+	var current string
+	setWallpaper := func(filename string) error {
+		current = filepath.Base(filename)
+		return nil
+	}
+	if _, err := stw.EventSystem(false, setWallpaper, filepath.Join(dir, "blended.jpg")); err != nil {
+		t.Fatal(err)
+	}
+	if current != "morning.png" {
+		t.Fatalf("expected morning.png, got %s", current)
+	}
+	// The dark variant is set right away when the color scheme changes,
+	// not only when the next event is triggered
+	dark = true
+	if err := stw.Refresh(false, setWallpaper, filepath.Join(dir, "blended.jpg")); err != nil {
+		t.Fatal(err)
+	}
+	if current != "morning-dark.png" {
+		t.Errorf("expected morning-dark.png, got %s", current)
+	}
+}
+
+func TestPrevNextEvent(t *testing.T) {
+	stw, err := DataToSimple("full-day.stw", []byte(fmt.Sprintf(fullDaySTW, "/tmp")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		at, prev, next string
+	}{
+		{"00:00", "00:00", "05:00"},
+		{"04:59", "00:00", "05:00"},
+		{"07:30", "07:00", "08:00"},
+		{"13:00", "13:00", "18:00"},
+		{"23:59", "18:00", "00:00"},
+	}
+	for _, test := range tests {
+		at := clockTime(t, test.at)
+		_, prev, err := stw.PrevEvent(at)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, next, err := stw.NextEvent(at)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if cFmt(prev) != test.prev || cFmt(next) != test.next || !next.After(at) || prev.After(at) {
+			t.Errorf("at %s: expected the events at %s and %s, got %s and %s", test.at, test.prev, test.next, prev, next)
+		}
+		if d, when := stw.UntilNext(at); !when.Equal(next) || d != next.Sub(at) {
+			t.Errorf("at %s: unexpected duration until the next event: %s", test.at, d)
+		}
+	}
+	for _, test := range []struct {
+		at    string
+		ratio float64
+	}{
+		{"07:30", 0},   // static
+		{"08:00", 0},   // the start of a transition
+		{"10:30", 0.5}, // halfway
+		{"21:00", 0.5}, // halfway, across midnight
+	} {
+		_, ratio, err := stw.Progress(clockTime(t, test.at))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ratio != test.ratio {
+			t.Errorf("at %s: expected the progress to be %v, got %v", test.at, test.ratio, ratio)
+		}
+	}
+	if _, _, err := NewWallpaper("1.0", "empty", "").NextEvent(midnight); err == nil {
+		t.Error("expected an error when there are no events")
+	}
+}
+
+func TestRender(t *testing.T) {
+	dir := writeImages(t, "morning", "morning-dark", "day", "night")
+	stw, err := DataToSimple("full-day.stw", []byte(fmt.Sprintf(fullDaySTW, dir)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	clock := event.NewFakeClock(midnight)
+	stw.Clock = clock
+	var current string
+	sys, err := stw.EventSystem(false, func(filename string) error {
+		current = filepath.Base(filename)
+		return nil
+	}, filepath.Join(dir, "blended.jpg"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// What is rendered must be what the event loop has set, at every minute of the day
+	for at := midnight; at.Before(clockTime(t, "24:00")); at = at.Add(time.Minute) {
+		clock.Set(at)
+		sys.Step(false)
+		fromFilename, toFilename, _, err := stw.Shown(at)
+		if err != nil {
+			t.Fatal(err)
+		}
+		shown := filepath.Base(fromFilename)
+		if toFilename != "" {
+			shown = "blended.jpg"
+		}
+		if shown != current {
+			t.Fatalf("expected %s at %s, got %s", current, cFmt(at), shown)
+		}
+	}
+	for _, test := range []struct {
+		at  string
+		red uint8
+	}{
+		{"03:00", 120}, // night
+		{"05:30", 120}, // the start of the transition to the morning
+		{"06:00", 60},  // halfway from night to morning
+		{"13:00", 80},  // day
+		{"23:00", 100}, // halfway from day to night
+	} {
+		img, err := stw.Render(clockTime(t, test.at))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if r, g, b, _ := img.At(4, 4).RGBA(); uint8(r>>8) != test.red || g != 0 || b != 0 {
+			t.Errorf("at %s: expected the red value %d, got %d, %d, %d", test.at, test.red, r>>8, g>>8, b>>8)
+		}
+	}
+}
+
+func TestFade(t *testing.T) {
+	for _, test := range []struct {
+		fade    *Fade
+		window  time.Duration
+		offsets string
+	}{
+		{nil, 5 * time.Hour, "[2h30m0s]"},
+		{&Fade{Steps: 4}, time.Hour, "[15m0s 30m0s 45m0s]"},
+		{&Fade{Interval: 20 * time.Minute, Steps: 4}, time.Hour, "[20m0s 40m0s]"},
+		{&Fade{Interval: time.Second}, 3 * time.Minute, "[1m0s 2m0s]"},
+		{&Fade{Steps: 100}, 2 * time.Minute, "[1m0s]"},
+	} {
+		if offsets := fmt.Sprint(test.fade.Offsets(test.window)); offsets != test.offsets {
+			t.Errorf("%+v: expected %s for %s, got %s", test.fade, test.offsets, test.window, offsets)
+		}
+	}
+
+	dir := writeImages(t, "morning", "morning-dark", "day", "night")
+	stw, err := DataToSimple("full-day.stw", []byte(fmt.Sprintf(fullDaySTW, dir)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	base := &Fade{Steps: 5, CacheDir: filepath.Join(t.TempDir(), "transitions")}
+	stw.Fade = base.ForWallpaper(stw.Path)
+	cacheDir := stw.Fade.CacheDir
+
+	// Each timed wallpaper has a cache directory of its own
+	if filepath.Dir(cacheDir) != base.CacheDir || base.ForWallpaper("other.stw").CacheDir == cacheDir || base.ForWallpaper(stw.Path).CacheDir != cacheDir {
+		t.Errorf("unexpected cache directory: %s", cacheDir)
+	}
+	clock := event.NewFakeClock(clockTime(t, "08:00"))
+	stw.Clock = clock
+
+	// Every image for every transition, and the dark variants, are blended ahead of time
+	if err := stw.Prerender(false); err != nil {
+		t.Fatal(err)
+	}
+	frames, err := filepath.Glob(filepath.Join(cacheDir, "*.jpg"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(frames) != 4*4 {
+		t.Fatalf("expected 16 blended images, got %d", len(frames))
+	}
+	modTimes := make(map[string]time.Time)
+	for _, frame := range frames {
+		fi, err := os.Stat(frame)
+		if err != nil {
+			t.Fatal(err)
+		}
+		modTimes[frame] = fi.ModTime()
+	}
+
+	// Blended images that are not used by this wallpaper are only removed
+	// when pruning, and the others are kept
+	stale := filepath.Join(cacheDir, "0123456789abcdef0123456789abcdef.jpg")
+	if err := os.WriteFile(stale, []byte{}, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := stw.Prerender(false); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(stale); err != nil {
+		t.Error("expected the unused image to be kept when not pruning")
+	}
+	stw.Fade.Prune = true
+	if err := stw.Prerender(false); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(stale); !os.IsNotExist(err) {
+		t.Error("expected the unused image to be removed")
+	}
+	if frames2, err := filepath.Glob(filepath.Join(cacheDir, "*.jpg")); err != nil || len(frames2) != len(frames) {
+		t.Errorf("expected %d blended images to be kept, got %d", len(frames), len(frames2))
+	}
+
+	// The event loop uses the cached images, and sets one every hour of the transition from 08:00 to 13:00
+	var set []string
+	sys, err := stw.EventSystem(false, func(filename string) error {
+		set = append(set, filename)
+		return nil
+	}, filepath.Join(dir, "blended.jpg"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for at := clockTime(t, "08:00"); at.Before(clockTime(t, "13:00")); at = at.Add(time.Minute) {
+		clock.Set(at)
+		sys.Step(false)
+		fromFilename, toFilename, ratio, err := stw.Shown(at)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if expected := float64(at.Hour()-8) / 5; ratio != expected || (ratio > 0) != (toFilename != "") || filepath.Base(fromFilename) != "morning.png" {
+			t.Fatalf("at %s: expected the ratio %v, got %v for %s and %s", cFmt(at), expected, ratio, fromFilename, toFilename)
+		}
+	}
+	if len(set) != 6 || filepath.Base(set[1]) != "morning.png" {
+		t.Fatalf("expected the initial image, the first image and 4 blended images, got %v", set)
+	}
+	for _, filename := range set[2:] {
+		modTime, ok := modTimes[filename]
+		if !ok {
+			t.Fatalf("expected a cached image, got %s", filename)
+		}
+		if fi, err := os.Stat(filename); err != nil || !fi.ModTime().Equal(modTime) {
+			t.Errorf("expected %s to be blended only once", filename)
+		}
+	}
+}
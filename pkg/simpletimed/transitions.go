@@ -0,0 +1,193 @@
+package simpletimed
+
+import (
+	"fmt"
+	"image"
+	"image/draw"
+	"math"
+
+	"github.com/anthonynsimon/bild/blend"
+	"github.com/anthonynsimon/bild/parallel"
+	"github.com/anthonynsimon/bild/transform"
+)
+
+// TransitionTypes are the supported transition types. "overlay" is the
+// default, and is a crossfade. The others were added in STW 1.2.
+var TransitionTypes = []string{"overlay", "wipe-left", "wipe-right", "radial", "blur-through", "color-temperature", "luminance"}
+
+// luminanceSoftness is how much of the transition each pixel uses for
+// fading in, with the "luminance" transition type
+const luminanceSoftness = 0.25
+
+// Blend returns an image that is the given ratio through a transition of
+// the given type, from one image to another. A ratio of 0 is only the first
+// image and a ratio of 1 is only the second image.
+//
+//   - "overlay" crossfades the images.
+//   - "wipe-left" reveals the second image from the right edge, moving left.
+//   - "wipe-right" reveals the second image from the left edge, moving right.
+//   - "radial" reveals the second image in a circle that grows from the center.
+//   - "blur-through" blurs the first image, crossfades to the blurred second
+//     image and then makes it sharp. The blur is strongest halfway.
+//   - "color-temperature" shifts the colors of the first image towards the
+//     average color of the second image, while the second image fades in,
+//     slowly at first.
+//   - "luminance" fades in the brightest parts of the second image first.
+func Blend(transitionType string, from, to image.Image, ratio float64) (image.Image, error) {
+	ratio = math.Max(0, math.Min(1, ratio))
+	if transitionType == "overlay" || transitionType == "" {
+		return blend.Opacity(from, to, ratio), nil
+	}
+	b := from.Bounds()
+	w, h := b.Dx(), b.Dy()
+	fromImg, toImg := toRGBA(from, w, h), toRGBA(to, w, h)
+	switch transitionType {
+	case "wipe-left":
+		edge := int(math.Round(float64(w) * (1 - ratio)))
+		return mix(fromImg, toImg, func(x, _ int) float64 {
+			if x >= edge {
+				return 1
+			}
+			return 0
+		}), nil
+	case "wipe-right":
+		edge := int(math.Round(float64(w) * ratio))
+		return mix(fromImg, toImg, func(x, _ int) float64 {
+			if x < edge {
+				return 1
+			}
+			return 0
+		}), nil
+	case "radial":
+		cx, cy := float64(w)/2, float64(h)/2
+		radius := ratio * math.Hypot(cx, cy)
+		return mix(fromImg, toImg, func(x, y int) float64 {
+			if math.Hypot(float64(x)+0.5-cx, float64(y)+0.5-cy) < radius {
+				return 1
+			}
+			return 0
+		}), nil
+	case "blur-through":
+		maxRadius := math.Max(1, float64(min(w, h))/20)
+		radius := int(math.Round(maxRadius * (1 - math.Abs(2*ratio-1))))
+		return mix(boxBlur(fromImg, radius), boxBlur(toImg, radius), func(_, _ int) float64 {
+			return ratio
+		}), nil
+	case "color-temperature":
+		fromMean, toMean := meanColor(fromImg), meanColor(toImg)
+		shifted := image.NewRGBA(fromImg.Rect)
+		for i := range fromImg.Pix {
+			c := i % 4
+			if c == 3 {
+				shifted.Pix[i] = fromImg.Pix[i]
+				continue
+			}
+			shifted.Pix[i] = clampUint8(float64(fromImg.Pix[i]) + ratio*(toMean[c]-fromMean[c]))
+		}
+		return mix(shifted, toImg, func(_, _ int) float64 {
+			return ratio * ratio
+		}), nil
+	case "luminance":
+		return mix(fromImg, toImg, func(x, y int) float64 {
+			i := toImg.PixOffset(x, y)
+			lum := (0.2126*float64(toImg.Pix[i]) + 0.7152*float64(toImg.Pix[i+1]) + 0.0722*float64(toImg.Pix[i+2])) / 255
+			return math.Max(0, math.Min(1, (ratio*(1+luminanceSoftness)-(1-lum))/luminanceSoftness))
+		}), nil
+	}
+	return nil, fmt.Errorf("unknown transition type: %s", transitionType)
+}
+
+// toRGBA returns the given image as an RGBA image with the given size,
+// that starts at (0, 0). The image is scaled if it has a different size.
+func toRGBA(img image.Image, w, h int) *image.RGBA {
+	b := img.Bounds()
+	if b.Dx() != w || b.Dy() != h {
+		return transform.Resize(img, w, h, transform.Linear)
+	}
+	rgba := image.NewRGBA(image.Rect(0, 0, w, h))
+	draw.Draw(rgba, rgba.Rect, img, b.Min, draw.Src)
+	return rgba
+}
+
+// clampUint8 rounds the given value and clamps it to 0..255
+func clampUint8(v float64) uint8 {
+	return uint8(math.Max(0, math.Min(255, math.Round(v))))
+}
+
+// mix blends two images of the same size, where the given function returns
+// how much of the second image to use for each pixel, from 0 to 1
+func mix(from, to *image.RGBA, amount func(x, y int) float64) *image.RGBA {
+	dst := image.NewRGBA(from.Rect)
+	w := from.Rect.Dx()
+	parallel.Line(from.Rect.Dy(), func(start, end int) {
+		for y := start; y < end; y++ {
+			for x := 0; x < w; x++ {
+				a := amount(x, y)
+				i := dst.PixOffset(x, y)
+				for c := 0; c < 4; c++ {
+					dst.Pix[i+c] = clampUint8(float64(from.Pix[i+c])*(1-a) + float64(to.Pix[i+c])*a)
+				}
+			}
+		}
+	})
+	return dst
+}
+
+// meanColor returns the average red, green and blue values of an image
+func meanColor(img *image.RGBA) [3]float64 {
+	var sum [3]float64
+	for i := 0; i < len(img.Pix); i += 4 {
+		for c := 0; c < 3; c++ {
+			sum[c] += float64(img.Pix[i+c])
+		}
+	}
+	if n := float64(len(img.Pix) / 4); n > 0 {
+		for c := range sum {
+			sum[c] /= n
+		}
+	}
+	return sum
+}
+
+// boxBlur blurs an image by averaging each pixel with the pixels within the
+// given radius, first horizontally and then vertically. The edge pixels are
+// repeated outside of the image. A running sum is used, so that the time it
+// takes does not depend on the radius.
+func boxBlur(img *image.RGBA, radius int) *image.RGBA {
+	if radius < 1 {
+		return img
+	}
+	w, h := img.Rect.Dx(), img.Rect.Dy()
+	n := float64(2*radius + 1)
+	blurLine := func(src, dst *image.RGBA, length int, offset func(line, pos int) int, lines int) {
+		parallel.Line(lines, func(start, end int) {
+			for line := start; line < end; line++ {
+				// The sum of the pixels within the radius of the first pixel
+				var sum [4]int
+				for d := -radius; d <= radius; d++ {
+					i := offset(line, min(max(d, 0), length-1))
+					for c := 0; c < 4; c++ {
+						sum[c] += int(src.Pix[i+c])
+					}
+				}
+				for pos := 0; pos < length; pos++ {
+					i := offset(line, pos)
+					for c := 0; c < 4; c++ {
+						dst.Pix[i+c] = clampUint8(float64(sum[c]) / n)
+					}
+					// Move the window one pixel ahead
+					added := offset(line, min(pos+radius+1, length-1))
+					removed := offset(line, max(pos-radius, 0))
+					for c := 0; c < 4; c++ {
+						sum[c] += int(src.Pix[added+c]) - int(src.Pix[removed+c])
+					}
+				}
+			}
+		})
+	}
+	horizontal := image.NewRGBA(img.Rect)
+	blurLine(img, horizontal, w, func(y, x int) int { return img.PixOffset(x, y) }, h)
+	blurred := image.NewRGBA(img.Rect)
+	blurLine(horizontal, blurred, h, func(x, y int) int { return img.PixOffset(x, y) }, w)
+	return blurred
+}
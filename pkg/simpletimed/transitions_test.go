@@ -0,0 +1,162 @@
+package simpletimed
+
+import (
+	"image"
+	"image/color"
+	"strings"
+	"testing"
+)
+
+// testImage returns an image of the given size, where each pixel has the
+// color that the given function returns for it
+func testImage(w, h int, c func(x, y int) color.RGBA) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			img.SetRGBA(x, y, c(x, y))
+		}
+	}
+	return img
+}
+
+// uniform returns a function that gives the same color for every pixel
+func uniform(r, g, b uint8) func(x, y int) color.RGBA {
+	return func(_, _ int) color.RGBA {
+		return color.RGBA{r, g, b, 255}
+	}
+}
+
+func TestBlend(t *testing.T) {
+	red := testImage(8, 8, uniform(200, 0, 0))
+	blue := testImage(8, 8, uniform(0, 0, 200))
+	// Black on the left half and white on the right half
+	halves := testImage(40, 40, func(x, _ int) color.RGBA {
+		if x < 20 {
+			return color.RGBA{0, 0, 0, 255}
+		}
+		return color.RGBA{255, 255, 255, 255}
+	})
+	// White on the left half and dark gray on the right half
+	bright := testImage(8, 8, func(x, _ int) color.RGBA {
+		if x < 4 {
+			return color.RGBA{255, 255, 255, 255}
+		}
+		return color.RGBA{51, 51, 51, 255}
+	})
+	black := testImage(8, 8, uniform(0, 0, 0))
+
+	tests := []struct {
+		transitionType string
+		from, to       image.Image
+		ratio          float64
+		x, y           int
+		expected       color.RGBA
+	}{
+		{"overlay", red, blue, 0.5, 0, 0, color.RGBA{100, 0, 100, 255}},
+		{"wipe-left", red, blue, 0, 7, 0, color.RGBA{200, 0, 0, 255}},
+		{"wipe-left", red, blue, 0.25, 5, 0, color.RGBA{200, 0, 0, 255}},
+		{"wipe-left", red, blue, 0.25, 6, 0, color.RGBA{0, 0, 200, 255}},
+		{"wipe-left", red, blue, 1, 0, 0, color.RGBA{0, 0, 200, 255}},
+		{"wipe-right", red, blue, 0.25, 1, 0, color.RGBA{0, 0, 200, 255}},
+		{"wipe-right", red, blue, 0.25, 2, 0, color.RGBA{200, 0, 0, 255}},
+		{"wipe-right", red, blue, 1, 7, 0, color.RGBA{0, 0, 200, 255}},
+		{"radial", red, blue, 0, 4, 4, color.RGBA{200, 0, 0, 255}},
+		{"radial", red, blue, 0.5, 3, 3, color.RGBA{0, 0, 200, 255}},
+		{"radial", red, blue, 0.5, 0, 0, color.RGBA{200, 0, 0, 255}},
+		{"radial", red, blue, 1, 0, 0, color.RGBA{0, 0, 200, 255}},
+		{"blur-through", halves, halves, 0, 19, 20, color.RGBA{0, 0, 0, 255}},
+		{"blur-through", halves, halves, 0.5, 19, 20, color.RGBA{102, 102, 102, 255}}, // 2 of 5 pixels are white
+		{"blur-through", halves, halves, 1, 20, 20, color.RGBA{255, 255, 255, 255}},
+		{"blur-through", red, blue, 0.5, 4, 4, color.RGBA{100, 0, 100, 255}},
+		{"color-temperature", red, blue, 0, 0, 0, color.RGBA{200, 0, 0, 255}},
+		{"color-temperature", red, blue, 0.5, 0, 0, color.RGBA{75, 0, 125, 255}}, // shifted to (100, 0, 100), and a quarter blue
+		{"color-temperature", red, blue, 1, 0, 0, color.RGBA{0, 0, 200, 255}},
+		{"luminance", black, bright, 0.2, 0, 0, color.RGBA{255, 255, 255, 255}},
+		{"luminance", black, bright, 0.2, 7, 0, color.RGBA{0, 0, 0, 255}},
+		{"luminance", black, bright, 0.8, 7, 0, color.RGBA{41, 41, 41, 255}},
+		{"luminance", black, bright, 1, 7, 0, color.RGBA{51, 51, 51, 255}},
+	}
+	for _, test := range tests {
+		img, err := Blend(test.transitionType, test.from, test.to, test.ratio)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := color.RGBAModel.Convert(img.At(test.x, test.y)).(color.RGBA); got != test.expected {
+			t.Errorf("%s at %v: expected %v at (%d, %d), got %v", test.transitionType, test.ratio, test.expected, test.x, test.y, got)
+		}
+	}
+
+	// The second image is scaled to the size of the first image
+	img, err := Blend("wipe-right", red, testImage(4, 4, uniform(0, 0, 200)), 0.5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if img.Bounds() != red.Bounds() || img.At(3, 7) != (color.RGBA{0, 0, 200, 255}) {
+		t.Errorf("expected an 8x8 image, where the left half is blue, got %v", img.Bounds())
+	}
+
+	if _, err := Blend("spiral", red, blue, 0.5); err == nil {
+		t.Error("expected an error for an unknown transition type")
+	}
+}
+
+func TestBoxBlur(t *testing.T) {
+	img := testImage(13, 7, func(x, y int) color.RGBA {
+		return color.RGBA{uint8(x * 19), uint8(y * 37), uint8((x * y * 11) % 256), 255}
+	})
+	// Average each pixel with the pixels within the radius, horizontally and
+	// then vertically, in the slowest way
+	average := func(img *image.RGBA, radius int, at func(d int) (x, y int)) color.RGBA {
+		var sum [4]float64
+		for d := -radius; d <= radius; d++ {
+			c := img.RGBAAt(at(d))
+			sum[0], sum[1], sum[2], sum[3] = sum[0]+float64(c.R), sum[1]+float64(c.G), sum[2]+float64(c.B), sum[3]+float64(c.A)
+		}
+		n := float64(2*radius + 1)
+		return color.RGBA{clampUint8(sum[0] / n), clampUint8(sum[1] / n), clampUint8(sum[2] / n), clampUint8(sum[3] / n)}
+	}
+	w, h := img.Rect.Dx(), img.Rect.Dy()
+	for _, radius := range []int{1, 3, 20} {
+		horizontal := image.NewRGBA(img.Rect)
+		for y := 0; y < h; y++ {
+			for x := 0; x < w; x++ {
+				horizontal.SetRGBA(x, y, average(img, radius, func(d int) (int, int) { return min(max(x+d, 0), w-1), y }))
+			}
+		}
+		blurred := boxBlur(img, radius)
+		for y := 0; y < h; y++ {
+			for x := 0; x < w; x++ {
+				expected := average(horizontal, radius, func(d int) (int, int) { return x, min(max(y+d, 0), h-1) })
+				if c := blurred.RGBAAt(x, y); c != expected {
+					t.Fatalf("radius %d, at (%d, %d): expected %v, got %v", radius, x, y, expected, c)
+				}
+			}
+		}
+	}
+}
+
+func TestTransitionTypes(t *testing.T) {
+	stw, err := DataToSimple("types.stw", []byte("stw: 1.0\n@08:00-13:00: morning .. day | wipe-left\n@18:00-00:00: day .. night\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stw.Transitions[0].Type != "wipe-left" || stw.Transitions[1].Type != "overlay" || !stw.HasTransitionTypes() {
+		t.Errorf("unexpected transition types: %s and %s", stw.Transitions[0].Type, stw.Transitions[1].Type)
+	}
+	// Version 1.2 is needed for other transition types than "overlay"
+	s := stw.String()
+	if !strings.HasPrefix(s, "stw: 1.2\n") || !strings.Contains(s, "@08:00-13:00: morning .. day | wipe-left\n") || !strings.HasSuffix(s, "\n@18:00-00:00: day .. night") {
+		t.Errorf("unexpected STW:\n%s", s)
+	}
+	if _, err := DataToSimple("invalid.stw", []byte("stw: 1.2\n@08:00-13:00: morning .. day | spiral\n")); err == nil {
+		t.Error("expected an error for an unknown transition type")
+	}
+	// Earlier versions did not check the transition type, so a crossfade is used
+	stw, err = DataToSimple("old.stw", []byte("stw: 1.1\n@08:00-13:00: morning .. day | spiral\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stw.Transitions[0].Type != "overlay" || stw.HasTransitionTypes() {
+		t.Errorf("expected the overlay transition type, got %s", stw.Transitions[0].Type)
+	}
+}
@@ -32,6 +32,33 @@ func dFmt(d time.Duration) string {
 	return strings.TrimSpace(s)
 }
 
+// unformat removes the prefix and suffix of the given format string from
+// the given filename, the opposite of using fmt.Sprintf with the format
+// string. The filename is returned as it is if the format string has no
+// %s marker, or if the filename does not match it.
+func unformat(format, filename string) string {
+	if !strings.Contains(format, "%s") {
+		return filename
+	}
+	fields := strings.SplitN(format, "%s", 2)
+	prefix := fields[0]
+	suffix := fields[1]
+	if len(filename) < len(prefix)+len(suffix) || !strings.HasPrefix(filename, prefix) || !strings.HasSuffix(filename, suffix) {
+		return filename
+	}
+	return filename[len(prefix) : len(filename)-len(suffix)]
+}
+
+// has checks if the given string slice contains the given string
+func has(xs []string, x string) bool {
+	for _, e := range xs {
+		if e == x {
+			return true
+		}
+	}
+	return false
+}
+
 // exists checks if the given path exists
 func exists(path string) bool {
 	_, err := os.Stat(path)
@@ -45,7 +72,7 @@ func exists(path string) bool {
 func mod24(d time.Duration) time.Duration {
 	hourDiff := d % h24
 	if hourDiff < 0 {
-		return -hourDiff
+		return hourDiff + h24
 	}
 	return hourDiff
 }
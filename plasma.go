@@ -1,7 +1,10 @@
 package wallutils
 
 import (
+	"errors"
 	"fmt"
+	"strconv"
+	"strings"
 
 	"github.com/xyproto/env/v2"
 )
@@ -32,6 +35,17 @@ func (p *Plasma) SetMode(mode string) {
 	p.mode = mode
 }
 
+// Capabilities returns the wallpaper modes that are supported by this backend,
+// if one wallpaper per output can be set and if the wallpaper is kept after
+// logging in again
+func (p *Plasma) Capabilities() Capabilities {
+	return Capabilities{
+		Modes:      []string{"stretch", "stretched", "fill", "fit", "scale", "scaled", "zoom", "zoomed", "crop", "cropped", "tile", "tiled", "hfill", "vtile", "vfill", "htile", "center", "centered"},
+		PerOutput:  true,
+		Persistent: true,
+	}
+}
+
 // SetVerbose can be used for setting the verbose field to true or false.
 // This will cause this backend to output information about what is is doing on stdout.
 func (p *Plasma) SetVerbose(verbose bool) {
@@ -41,10 +55,28 @@ func (p *Plasma) SetVerbose(verbose bool) {
 // SetWallpaper sets the desktop wallpaper, given an image filename.
 // The image must exist and be readable.
 func (p *Plasma) SetWallpaper(imageFilename string) error {
+	return p.SetWallpaperForOutput("*", imageFilename)
+}
+
+// SetWallpaperForOutput sets the desktop wallpaper for the given output, or
+// for all outputs if the output name is "*". The Plasma scripting API only
+// knows about screen numbers, so the output must be a number, like "0" or "1".
+// The image must exist and be readable.
+func (p *Plasma) SetWallpaperForOutput(output, imageFilename string) error {
 	if !exists(imageFilename) {
 		return fmt.Errorf("no such file: %s", imageFilename)
 	}
 
+	// -1 means all screens
+	screen := -1
+	if output != "*" {
+		n, err := strconv.Atoi(output)
+		if err != nil || n < 0 {
+			return fmt.Errorf("Plasma only supports selecting outputs by screen number, not by name: %s", output)
+		}
+		screen = n
+	}
+
 	// initialize the mode setting (stretched/tiled etc)
 	mode := defaultMode
 	if p.mode != "" {
@@ -81,7 +113,7 @@ func (p *Plasma) SetWallpaper(imageFilename string) error {
 			fillMode = "6"
 		default:
 			// Invalid and unrecognized desktop wallpaper mode
-			return fmt.Errorf("invalid desktop wallpaper mode for Plasma: %s", p.mode)
+			return fmt.Errorf("%w for Plasma: %s", ErrUnsupportedMode, p.mode)
 		}
 	}
 
@@ -89,6 +121,9 @@ func (p *Plasma) SetWallpaper(imageFilename string) error {
     var Desktops = desktops();
     for (i=0;i<Desktops.length;i++) {
             d = Desktops[i];
+            if (` + strconv.Itoa(screen) + ` >= 0 && d.screen != ` + strconv.Itoa(screen) + `) {
+                continue;
+            }
             d.wallpaperPlugin = "org.kde.image";
             d.currentConfigGroup = Array("Wallpaper",
                                          "org.kde.image",
@@ -105,3 +140,45 @@ func (p *Plasma) SetWallpaper(imageFilename string) error {
 		dbusScript,
 	}, p.verbose)
 }
+
+// CurrentWallpaper returns the current wallpaper of the first desktop, by
+// reading the Image setting with the Plasma scripting API
+func (p *Plasma) CurrentWallpaper() (string, error) {
+	dbusScript := `string:
+    var d = desktops()[0];
+    d.currentConfigGroup = Array("Wallpaper",
+                                 "org.kde.image",
+                                 "General");
+    print(d.readConfig("Image"));`
+	reply := output("dbus-send", []string{
+		"--session",
+		"--print-reply",
+		"--dest=org.kde.plasmashell",
+		"--type=method_call",
+		"/PlasmaShell",
+		"org.kde.PlasmaShell.evaluateScript",
+		dbusScript,
+	}, p.verbose)
+	uri := dbusReplyString(reply)
+	if uri == "" {
+		return "", errors.New("could not read the wallpaper from Plasma")
+	}
+	return uriToFilename(uri), nil
+}
+
+// dbusReplyString returns the string in the output of dbus-send --print-reply,
+// or an empty string if there is none. The string may span several lines,
+// since print() in the Plasma scripting API adds a newline.
+func dbusReplyString(reply string) string {
+	const prefix = `string "`
+	start := strings.Index(reply, prefix)
+	if start < 0 {
+		return ""
+	}
+	rest := reply[start+len(prefix):]
+	end := strings.LastIndex(rest, `"`)
+	if end < 0 {
+		return ""
+	}
+	return strings.TrimSpace(rest[:end])
+}
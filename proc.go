@@ -1,8 +1,11 @@
 package wallutils
 
 import (
+	"errors"
 	"os"
 	"os/exec"
+	"path/filepath"
+	"sort"
 	"strings"
 )
 
@@ -55,3 +58,131 @@ func checkProcFSForArgs(command, args string) (bool, error) {
 	}
 	return false, nil
 }
+
+// processArgs returns the arguments of all running processes where the
+// base name of the executable is the given command, by reading /proc
+func processArgs(command string) ([][]string, error) {
+	entries, err := os.ReadDir("/proc")
+	if err != nil {
+		return nil, err
+	}
+	var found [][]string
+	for _, entry := range entries {
+		pid := entry.Name()
+		if !entry.IsDir() || len(pid) == 0 || pid[0] < '0' || pid[0] > '9' {
+			continue
+		}
+		cmdline, err := os.ReadFile("/proc/" + pid + "/cmdline")
+		if err != nil || len(cmdline) == 0 {
+			continue
+		}
+		// cmdline uses null bytes as separators, and ends with one
+		cmdArgs := strings.Split(strings.TrimSuffix(string(cmdline), "\x00"), "\x00")
+		if filepath.Base(cmdArgs[0]) == command {
+			found = append(found, cmdArgs[1:])
+		}
+	}
+	return found, nil
+}
+
+// swaybgOutput is the image filename and wallpaper mode that swaybg was
+// started with, for one output
+type swaybgOutput struct {
+	image string
+	mode  string
+}
+
+// swaybgImage returns the image filename from the given swaybg arguments.
+// The image for all outputs ("*") is preferred, if there are several.
+func swaybgImage(args []string) string {
+	outputs := swaybgOutputs(args)
+	if o, ok := outputs["*"]; ok {
+		return o.image
+	}
+	// "*" sorts before the output names, so use the first sorted output
+	var names []string
+	for name := range outputs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	if len(names) == 0 {
+		return ""
+	}
+	return outputs[names[0]].image
+}
+
+// swaybgOutputs returns the image filename and mode for each output (or "*")
+// that has an image in the given swaybg arguments
+func swaybgOutputs(args []string) map[string]swaybgOutput {
+	found := make(map[string]swaybgOutput)
+	outputName := "*"
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		value := ""
+		if i+1 < len(args) {
+			value = args[i+1]
+		}
+		o := found[outputName]
+		switch {
+		case arg == "-o" || arg == "--output":
+			outputName = value
+			i++
+			continue
+		case strings.HasPrefix(arg, "--output="):
+			outputName = strings.TrimPrefix(arg, "--output=")
+			continue
+		case arg == "-i" || arg == "--image":
+			o.image = value
+			i++
+		case strings.HasPrefix(arg, "--image="):
+			o.image = strings.TrimPrefix(arg, "--image=")
+		case arg == "-m" || arg == "--mode":
+			o.mode = value
+			i++
+		case strings.HasPrefix(arg, "--mode="):
+			o.mode = strings.TrimPrefix(arg, "--mode=")
+		default:
+			continue
+		}
+		found[outputName] = o
+	}
+	// Only keep the outputs that have an image, and not just a mode
+	outputs := make(map[string]swaybgOutput)
+	for name, o := range found {
+		if o.image != "" {
+			outputs[name] = o
+		}
+	}
+	return outputs
+}
+
+// runningSwaybgOutputs returns the image filename and mode for each output
+// (or "*") of the running swaybg processes
+func runningSwaybgOutputs() map[string]swaybgOutput {
+	outputs := make(map[string]swaybgOutput)
+	allArgs, err := processArgs("swaybg")
+	if err != nil {
+		return outputs
+	}
+	for _, args := range allArgs {
+		for name, o := range swaybgOutputs(args) {
+			outputs[name] = o
+		}
+	}
+	return outputs
+}
+
+// currentSwaybgWallpaper returns the image that the running swaybg process
+// was started with
+func currentSwaybgWallpaper() (string, error) {
+	allArgs, err := processArgs("swaybg")
+	if err != nil {
+		return "", err
+	}
+	for _, args := range allArgs {
+		if imageFilename := swaybgImage(args); imageFilename != "" {
+			return imageFilename, nil
+		}
+	}
+	return "", errors.New("could not find a running swaybg process with an image")
+}
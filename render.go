@@ -0,0 +1,301 @@
+package wallutils
+
+import (
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/anthonynsimon/bild/imgio"
+	"github.com/anthonynsimon/bild/transform"
+)
+
+// Modes that can be rendered by wallutils, regardless of the backend
+const (
+	RenderFill    = "fill"    // scale to cover the monitor, then crop the center
+	RenderFit     = "fit"     // scale to fit the monitor, then add black borders
+	RenderStretch = "stretch" // scale to the monitor size, ignoring the aspect ratio
+	RenderCenter  = "center"  // keep the size, then crop or add black borders
+	RenderTile    = "tile"    // keep the size, then repeat from the upper left corner
+)
+
+// renderModeAliases maps the various mode names that are used by the
+// backends to one of the render modes
+var renderModeAliases = map[string]string{
+	"fill":      RenderFill,
+	"zoom":      RenderFill,
+	"zoomed":    RenderFill,
+	"fit":       RenderFit,
+	"scale":     RenderFit,
+	"scaled":    RenderFit,
+	"max":       RenderFit,
+	"stretch":   RenderStretch,
+	"stretched": RenderStretch,
+	"center":    RenderCenter,
+	"centered":  RenderCenter,
+	"tile":      RenderTile,
+	"wallpaper": RenderTile,
+}
+
+// RenderMode returns the render mode for the given wallpaper mode,
+// like "fill" for "zoom", or an error if the mode can not be rendered
+func RenderMode(mode string) (string, error) {
+	if renderMode, ok := renderModeAliases[mode]; ok {
+		return renderMode, nil
+	}
+	return "", fmt.Errorf("%w for rendering: %s", ErrUnsupportedMode, mode)
+}
+
+// Render returns a new image with exactly the given width and height,
+// by scaling, cropping, adding borders or tiling the given image, depending
+// on the wallpaper mode.
+func Render(img image.Image, width, height uint, mode string) (*image.RGBA, error) {
+	renderMode, err := RenderMode(mode)
+	if err != nil {
+		return nil, err
+	}
+	if width == 0 || height == 0 {
+		return nil, errors.New("can not render an image with no width or height")
+	}
+	b := img.Bounds()
+	srcW, srcH := b.Dx(), b.Dy()
+	if srcW == 0 || srcH == 0 {
+		return nil, errors.New("can not render an empty image")
+	}
+	w, h := int(width), int(height)
+	dst := image.NewRGBA(image.Rect(0, 0, w, h))
+	draw.Draw(dst, dst.Bounds(), image.NewUniform(color.Black), image.Point{}, draw.Src)
+
+	switch renderMode {
+	case RenderStretch:
+		return transform.Resize(img, w, h, transform.Lanczos), nil
+	case RenderFill, RenderFit:
+		// Find the scaled size that covers (fill) or fits within (fit) the target
+		scaledW, scaledH := w, srcH*w/srcW
+		if (renderMode == RenderFill) == (scaledH < h) {
+			scaledW, scaledH = srcW*h/srcH, h
+		}
+		scaledW, scaledH = max(scaledW, 1), max(scaledH, 1)
+		scaled := transform.Resize(img, scaledW, scaledH, transform.Lanczos)
+		offset := image.Pt((w-scaledW)/2, (h-scaledH)/2)
+		draw.Draw(dst, scaled.Bounds().Add(offset), scaled, image.Point{}, draw.Src)
+	case RenderCenter:
+		offset := image.Pt((w-srcW)/2, (h-srcH)/2)
+		draw.Draw(dst, b.Sub(b.Min).Add(offset), img, b.Min, draw.Src)
+	case RenderTile:
+		for y := 0; y < h; y += srcH {
+			for x := 0; x < w; x += srcW {
+				draw.Draw(dst, b.Sub(b.Min).Add(image.Pt(x, y)), img, b.Min, draw.Src)
+			}
+		}
+	}
+	return dst, nil
+}
+
+// renderCacheMaxAge is how long a rendered image is kept in the cache
+// after it was last used
+const renderCacheMaxAge = 30 * 24 * time.Hour
+
+// renderCacheDir returns the directory where rendered images are cached
+func renderCacheDir() (string, error) {
+	cacheDir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(cacheDir, "wallutils", "render"), nil
+}
+
+// renderCacheFilename returns a filename in the cache directory for a
+// rendered version of the given image. The hash in the filename depends on
+// the path of the source image, when it was modified and the given
+// description, which should contain the target size and mode.
+func renderCacheFilename(absFilename, description string) (string, error) {
+	fi, err := os.Stat(absFilename)
+	if err != nil {
+		return "", err
+	}
+	cacheDir, err := renderCacheDir()
+	if err != nil {
+		return "", err
+	}
+	key := fmt.Sprintf("%s:%d:%d:%s", absFilename, fi.ModTime().UnixNano(), fi.Size(), description)
+	sum := sha256.Sum256([]byte(key))
+	return filepath.Join(cacheDir, fmt.Sprintf("%s_%x.png", firstname(filepath.Base(absFilename)), sum[:8])), nil
+}
+
+// cachedRender checks if the given rendered image is in the cache, and if it
+// is, marks it as used, so that it is not pruned from the cache
+func cachedRender(filename string) bool {
+	if !exists(filename) {
+		return false
+	}
+	now := time.Now()
+	os.Chtimes(filename, now, now)
+	return true
+}
+
+// pruneRenderCache removes the files in the given cache directory that have
+// not been used for longer than renderCacheMaxAge, and returns how many
+// files were removed
+func pruneRenderCache(cacheDir string) (int, error) {
+	entries, err := os.ReadDir(cacheDir)
+	if os.IsNotExist(err) {
+		return 0, nil
+	} else if err != nil {
+		return 0, err
+	}
+	removed := 0
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		if fi, err := entry.Info(); err != nil || time.Since(fi.ModTime()) < renderCacheMaxAge {
+			continue
+		}
+		if err := os.Remove(filepath.Join(cacheDir, entry.Name())); err != nil && !os.IsNotExist(err) {
+			return removed, err
+		}
+		removed++
+	}
+	return removed, nil
+}
+
+// saveRendered saves the given image as a PNG file. A temporary file is
+// written first, so that a partially written file is never used.
+// Rendered images that have not been used for a while are then pruned from
+// the same directory.
+func saveRendered(filename string, img image.Image) error {
+	if err := os.MkdirAll(filepath.Dir(filename), 0o755); err != nil {
+		return err
+	}
+	tmpFilename := filename + ".tmp"
+	if err := imgio.Save(tmpFilename, img, imgio.PNGEncoder()); err != nil {
+		os.Remove(tmpFilename)
+		return err
+	}
+	if err := os.Rename(tmpFilename, filename); err != nil {
+		os.Remove(tmpFilename)
+		return err
+	}
+	// Pruning is not needed for the image to be used, so ignore errors
+	pruneRenderCache(filepath.Dir(filename))
+	return nil
+}
+
+// RenderFile renders the given image for the given width, height and mode,
+// and returns the filename of a PNG image in the cache directory. If the
+// image has already been rendered, and the source image has not changed
+// since then, the cached image is used.
+func RenderFile(imageFilename string, width, height uint, mode string) (string, error) {
+	renderMode, err := RenderMode(mode)
+	if err != nil {
+		return "", err
+	}
+	absFilename, err := filepath.Abs(imageFilename)
+	if err != nil {
+		return "", err
+	}
+	cachedFilename, err := renderCacheFilename(absFilename, fmt.Sprintf("%dx%d_%s", width, height, renderMode))
+	if err != nil {
+		return "", err
+	}
+	if cachedRender(cachedFilename) {
+		return cachedFilename, nil
+	}
+	img, err := OpenImage(absFilename, width, height)
+	if err != nil {
+		return "", err
+	}
+	rendered, err := Render(img, width, height, renderMode)
+	if err != nil {
+		return "", err
+	}
+	if err := saveRendered(cachedFilename, rendered); err != nil {
+		return "", err
+	}
+	return cachedFilename, nil
+}
+
+// RenderForMonitor renders the given image for the given monitor, and
+// returns the filename of the rendered PNG image in the cache directory
+func RenderForMonitor(imageFilename string, mon Monitor, mode string) (string, error) {
+	return RenderFile(imageFilename, mon.Width, mon.Height, mode)
+}
+
+// SetWallpaperRendered renders the given image for each monitor, so that
+// the given mode looks the same regardless of the backend, and then sets
+// the rendered images as the wallpaper. If the backend can not set one
+// wallpaper per monitor, the image is rendered for the primary monitor.
+func SetWallpaperRendered(imageFilename, mode string, verbose bool) error {
+	monitors, err := Monitors()
+	if err != nil {
+		return err
+	}
+	if len(monitors) > 1 {
+		outputImages := make(map[string]string, len(monitors))
+		for _, mon := range monitors {
+			if mon.Name == "" {
+				break
+			}
+			renderedFilename, err := RenderForMonitor(imageFilename, mon, mode)
+			if err != nil {
+				return err
+			}
+			outputImages[mon.Name] = renderedFilename
+		}
+		if len(outputImages) == len(monitors) {
+			if err := SetWallpaperPerMonitor(outputImages, defaultMode, verbose); err == nil {
+				saveState(&State{Image: imageFilename, Mode: mode, Render: true}, verbose)
+				return nil
+			} else if verbose {
+				fmt.Fprintf(os.Stderr, "could not set one wallpaper per monitor: %v\n", err)
+			}
+		}
+	}
+	mon := monitors[0]
+	for _, m := range monitors {
+		if m.Primary {
+			mon = m
+			break
+		}
+	}
+	renderedFilename, err := RenderForMonitor(imageFilename, mon, mode)
+	if err != nil {
+		return err
+	}
+	if err := SetWallpaperCustom(renderedFilename, defaultMode, verbose); err != nil {
+		return err
+	}
+	saveState(&State{Image: imageFilename, Mode: mode, Render: true}, verbose)
+	return nil
+}
+
+// SetWallpaperRenderedForOutput renders the given image for the monitor
+// with the given output name, like "DP-1", and sets it as the wallpaper for
+// that output only
+func SetWallpaperRenderedForOutput(output, imageFilename, mode string, verbose bool) error {
+	monitors, err := Monitors()
+	if err != nil {
+		return err
+	}
+	for _, mon := range monitors {
+		if mon.Name != output {
+			continue
+		}
+		renderedFilename, err := RenderForMonitor(imageFilename, mon, mode)
+		if err != nil {
+			return err
+		}
+		if err := SetWallpaperPerMonitor(map[string]string{output: renderedFilename}, defaultMode, verbose); err != nil {
+			return err
+		}
+		saveState(outputState(map[string]string{output: imageFilename}, mode, true), verbose)
+		return nil
+	}
+	return fmt.Errorf("could not find a monitor named %s", output)
+}
@@ -0,0 +1,159 @@
+package wallutils
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/anthonynsimon/bild/imgio"
+)
+
+var (
+	red  = color.RGBA{255, 0, 0, 255}
+	blue = color.RGBA{0, 0, 255, 255}
+)
+
+// redBlueImage returns an image where the left half is red and the right half is blue
+func redBlueImage(w, h int) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	draw.Draw(img, image.Rect(0, 0, w/2, h), image.NewUniform(red), image.Point{}, draw.Src)
+	draw.Draw(img, image.Rect(w/2, 0, w, h), image.NewUniform(blue), image.Point{}, draw.Src)
+	return img
+}
+
+func expectColor(t *testing.T, img image.Image, x, y int, expected color.RGBA) {
+	t.Helper()
+	r, g, b, _ := img.At(x, y).RGBA()
+	if uint8(r>>8) != expected.R || uint8(g>>8) != expected.G || uint8(b>>8) != expected.B {
+		t.Errorf("expected %v at (%d, %d), got %v", expected, x, y, img.At(x, y))
+	}
+}
+
+func TestRender(t *testing.T) {
+	black := color.RGBA{0, 0, 0, 255}
+	src := redBlueImage(40, 20)
+
+	// Fill: scaled to 40x20, then 10 pixels are cropped on each side
+	img, err := Render(src, 20, 20, "fill")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if img.Bounds().Dx() != 20 || img.Bounds().Dy() != 20 {
+		t.Fatalf("unexpected size: %v", img.Bounds())
+	}
+	expectColor(t, img, 2, 0, red)
+	expectColor(t, img, 17, 19, blue)
+
+	// Fit: scaled to 20x10, with black borders above and below
+	img, err = Render(src, 20, 20, "scale")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectColor(t, img, 10, 0, black)
+	expectColor(t, img, 10, 19, black)
+	expectColor(t, img, 2, 10, red)
+	expectColor(t, img, 17, 10, blue)
+
+	// Stretch: scaled to the exact size
+	img, err = Render(src, 80, 10, "stretch")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if img.Bounds().Dx() != 80 || img.Bounds().Dy() != 10 {
+		t.Fatalf("unexpected size: %v", img.Bounds())
+	}
+	expectColor(t, img, 5, 5, red)
+	expectColor(t, img, 75, 5, blue)
+
+	// Center: not scaled, cropped horizontally and with borders vertically
+	img, err = Render(src, 20, 40, "center")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectColor(t, img, 5, 0, black)
+	expectColor(t, img, 5, 20, red)
+	expectColor(t, img, 15, 20, blue)
+
+	// Tile: repeated from the upper left corner
+	img, err = Render(src, 100, 30, "tile")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectColor(t, img, 45, 25, red)
+	expectColor(t, img, 79, 29, blue)
+
+	if _, err := Render(src, 20, 20, "spanned"); err == nil {
+		t.Error("expected an error for a mode that can not be rendered")
+	}
+}
+
+func TestRenderFile(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	srcFilename := filepath.Join(t.TempDir(), "redblue.png")
+	if err := imgio.Save(srcFilename, redBlueImage(40, 20), imgio.PNGEncoder()); err != nil {
+		t.Fatal(err)
+	}
+	mon := Monitor{Width: 20, Height: 20}
+	renderedFilename, err := RenderForMonitor(srcFilename, mon, "zoom")
+	if err != nil {
+		t.Fatal(err)
+	}
+	img, err := imgio.Open(renderedFilename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if img.Bounds().Dx() != 20 || img.Bounds().Dy() != 20 {
+		t.Errorf("unexpected size: %v", img.Bounds())
+	}
+
+	// The cached image is used the second time
+	again, err := RenderForMonitor(srcFilename, mon, "fill")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again != renderedFilename {
+		t.Errorf("expected the cached image %s, got %s", renderedFilename, again)
+	}
+
+	// The image is rendered again if the source image changes
+	later := time.Now().Add(time.Minute)
+	if err := os.Chtimes(srcFilename, later, later); err != nil {
+		t.Fatal(err)
+	}
+	changed, err := RenderForMonitor(srcFilename, mon, "fill")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if changed == renderedFilename {
+		t.Error("expected a new rendered image after the source image changed")
+	}
+	if name := filepath.Base(changed); !strings.HasPrefix(name, "redblue_") || len(name) != len("redblue_0123456789abcdef.png") {
+		t.Errorf("unexpected filename: %s", name)
+	}
+
+	// Rendered images that have not been used for a while are pruned when
+	// a new image is rendered, but a cached image that is used again is kept
+	old := time.Now().Add(-renderCacheMaxAge - time.Hour)
+	for _, filename := range []string{renderedFilename, changed} {
+		if err := os.Chtimes(filename, old, old); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := RenderForMonitor(srcFilename, mon, "fill"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := RenderForMonitor(srcFilename, Monitor{Width: 10, Height: 10}, "fill"); err != nil {
+		t.Fatal(err)
+	}
+	if exists(renderedFilename) {
+		t.Errorf("expected %s to be pruned", renderedFilename)
+	}
+	if !exists(changed) {
+		t.Errorf("expected %s to be kept", changed)
+	}
+}
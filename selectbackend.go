@@ -0,0 +1,92 @@
+package wallutils
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/xyproto/env/v2"
+)
+
+// BackendEnvVar is the name of the environment variable that can be used
+// for selecting a backend by name, like "Feh" or "Gnome3", instead of
+// detecting which one is running
+const BackendEnvVar = "WALLUTILS_BACKEND"
+
+// selectedBackend is the name of the backend that was given to SetBackend
+var selectedBackend string
+
+// FindBackend returns the backend with the given name, from WMs.
+// The name is case insensitive.
+func FindBackend(name string) (WM, error) {
+	for _, wm := range WMs {
+		if strings.EqualFold(wm.Name(), name) {
+			return wm, nil
+		}
+	}
+	return nil, fmt.Errorf("unknown backend: %s (use lsbackends to list the available backends)", name)
+}
+
+// SetBackend selects the backend with the given name, like "Feh", so that it
+// is used even if another backend looks like it is running. An empty name
+// selects the backend given by the WALLUTILS_BACKEND environment variable,
+// or no backend, in which case the running backend is detected.
+func SetBackend(name string) error {
+	if name != "" {
+		if _, err := FindBackend(name); err != nil {
+			return err
+		}
+	}
+	selectedBackend = name
+	return nil
+}
+
+// SelectedBackend returns the name of the backend that was selected with
+// SetBackend or with the WALLUTILS_BACKEND environment variable, if any
+func SelectedBackend() string {
+	if selectedBackend != "" {
+		return selectedBackend
+	}
+	return env.Str(BackendEnvVar)
+}
+
+// backends returns the backends that should be tried, in order.
+// This is the selected backend only, if one has been selected, or else
+// all backends in WMs.
+func backends() ([]WM, error) {
+	name := SelectedBackend()
+	if name == "" {
+		return WMs, nil
+	}
+	wm, err := FindBackend(name)
+	if err != nil {
+		return nil, err
+	}
+	if !wm.ExecutablesExists() {
+		return nil, fmt.Errorf("the %s backend is selected, but the executables it needs could not be found: %w", wm.Name(), ErrNotAvailable)
+	}
+	return []WM{wm}, nil
+}
+
+// usable checks if the given backend can be used for setting the wallpaper.
+// A backend that has been selected does not need to look like it is running.
+func usable(wm WM) bool {
+	if !wm.ExecutablesExists() {
+		return false
+	}
+	return wm.Running() || strings.EqualFold(wm.Name(), SelectedBackend())
+}
+
+// DetectedBackend returns the backend that would be used for setting the
+// wallpaper, or nil if there are none
+func DetectedBackend() WM {
+	wms, err := backends()
+	if err != nil {
+		return nil
+	}
+	for _, wm := range wms {
+		if usable(wm) {
+			return wm
+		}
+	}
+	return nil
+}
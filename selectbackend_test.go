@@ -0,0 +1,68 @@
+package wallutils
+
+import (
+	"testing"
+
+	"github.com/xyproto/env/v2"
+)
+
+func TestFindBackend(t *testing.T) {
+	wm, err := FindBackend("feh")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if wm.Name() != "Feh" {
+		t.Errorf("expected Feh, got %s", wm.Name())
+	}
+	if _, err := FindBackend("nosuchbackend"); err == nil {
+		t.Error("expected an error for an unknown backend")
+	}
+}
+
+func TestSetBackend(t *testing.T) {
+	defer SetBackend("")
+	if err := SetBackend("nosuchbackend"); err == nil {
+		t.Error("expected an error for an unknown backend")
+	}
+	if err := SetBackend("gnome3"); err != nil {
+		t.Fatal(err)
+	}
+	if SelectedBackend() != "gnome3" {
+		t.Errorf("expected gnome3 to be selected, got %s", SelectedBackend())
+	}
+	if err := SetBackend(""); err != nil {
+		t.Fatal(err)
+	}
+
+	// The environment variable is used if no backend has been selected
+	t.Setenv(BackendEnvVar, "Plasma")
+	env.Load()
+	defer env.Load()
+	if SelectedBackend() != "Plasma" {
+		t.Errorf("expected Plasma to be selected, got %s", SelectedBackend())
+	}
+}
+
+func TestCapabilities(t *testing.T) {
+	for _, wm := range WMs {
+		capabilities, ok := BackendCapabilities(wm)
+		if !ok {
+			t.Errorf("%s does not implement CapabilitiesWM", wm.Name())
+		}
+		if len(capabilities.Modes) == 0 {
+			t.Errorf("%s does not list any modes", wm.Name())
+		}
+		if _, ok := wm.(MultiMonitorWM); ok != capabilities.PerOutput {
+			t.Errorf("%s implements MultiMonitorWM: %v, but supports one wallpaper per output: %v", wm.Name(), ok, capabilities.PerOutput)
+		}
+	}
+	if !(&Feh{}).Capabilities().SupportsMode("fill") {
+		t.Error("Feh should support the fill mode")
+	}
+	if (&Gnome3{}).Capabilities().SupportsMode("fit") {
+		t.Error("GNOME3 should not support the fit mode")
+	}
+	if !(&Weston{}).Capabilities().Restart || (&Feh{}).Capabilities().Restart {
+		t.Error("only Weston should need to be restarted")
+	}
+}
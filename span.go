@@ -0,0 +1,160 @@
+package wallutils
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/anthonynsimon/bild/transform"
+)
+
+// logicalRect returns the area that the monitor covers in the desktop
+// layout. The position is in logical pixels, so the size is divided by the
+// scale factor.
+func (m Monitor) logicalRect() image.Rectangle {
+	scale := m.Scale
+	if scale <= 0 {
+		scale = 1
+	}
+	w := int(math.Round(float64(m.Width) / scale))
+	h := int(math.Round(float64(m.Height) / scale))
+	return image.Rect(m.X, m.Y, m.X+w, m.Y+h)
+}
+
+// SpanRects returns the size of a canvas that covers all the given monitors,
+// and the area of the canvas that each monitor should show. The bezel is the
+// width of the gap between two neighbouring monitors, in pixels. It is added
+// once per column and row of monitors, so that a straight line in the image
+// will also look straight across the monitor bezels.
+func SpanRects(monitors []Monitor, bezel uint) (image.Rectangle, []image.Rectangle) {
+	if len(monitors) == 0 {
+		return image.Rectangle{}, nil
+	}
+	var (
+		bounds       = monitors[0].logicalRect()
+		lefts, tops  []int
+		rects        = make([]image.Rectangle, len(monitors))
+		canvasBounds image.Rectangle
+	)
+	for _, mon := range monitors {
+		r := mon.logicalRect()
+		bounds = bounds.Union(r)
+		lefts = append(lefts, r.Min.X)
+		tops = append(tops, r.Min.Y)
+	}
+	sort.Ints(lefts)
+	sort.Ints(tops)
+	// countBelow returns the number of distinct values in xs that are smaller than x
+	countBelow := func(xs []int, x int) int {
+		count := 0
+		for i, v := range xs {
+			if v < x && (i == 0 || v != xs[i-1]) {
+				count++
+			}
+		}
+		return count
+	}
+	for i, mon := range monitors {
+		r := mon.logicalRect().Sub(bounds.Min)
+		gap := image.Pt(countBelow(lefts, r.Min.X+bounds.Min.X)*int(bezel), countBelow(tops, r.Min.Y+bounds.Min.Y)*int(bezel))
+		rects[i] = r.Add(gap)
+		canvasBounds = canvasBounds.Union(rects[i])
+	}
+	return canvasBounds, rects
+}
+
+// RenderSpan renders one image that covers all the given monitors, and
+// returns one crop per monitor, with the same resolution as the monitor.
+// The image is first scaled to fill the whole canvas, or to fit within it,
+// depending on the mode.
+func RenderSpan(img image.Image, monitors []Monitor, bezel uint, mode string) ([]*image.RGBA, error) {
+	if len(monitors) == 0 {
+		return nil, errors.New("no monitors")
+	}
+	canvasBounds, rects := SpanRects(monitors, bezel)
+	canvas, err := Render(img, uint(canvasBounds.Dx()), uint(canvasBounds.Dy()), mode)
+	if err != nil {
+		return nil, err
+	}
+	crops := make([]*image.RGBA, len(monitors))
+	for i, mon := range monitors {
+		crop := transform.Crop(canvas, rects[i])
+		if uint(crop.Bounds().Dx()) != mon.Width || uint(crop.Bounds().Dy()) != mon.Height {
+			// The monitor is scaled, so the logical area is smaller than the resolution
+			crop = transform.Resize(crop, int(mon.Width), int(mon.Height), transform.Lanczos)
+		}
+		crops[i] = crop
+	}
+	return crops, nil
+}
+
+// SetWallpaperSpanned spans the given image across all monitors, by
+// cutting it into one image per monitor that matches the position of the
+// monitor, and then setting one wallpaper per monitor. The bezel is the
+// width of the gap between two neighbouring monitors, in pixels. If the
+// backend can not set one wallpaper per monitor, the "spanned" mode is tried.
+func SetWallpaperSpanned(imageFilename string, bezel uint, mode string, verbose bool) error {
+	monitors, err := Monitors()
+	if err != nil {
+		return err
+	}
+	absFilename, err := filepath.Abs(imageFilename)
+	if err != nil {
+		return err
+	}
+	outputImages := make(map[string]string, len(monitors))
+	var cachedFilenames []string
+	for _, mon := range monitors {
+		if mon.Name == "" {
+			return errors.New("could not find the output names of the monitors")
+		}
+		r := mon.logicalRect()
+		description := fmt.Sprintf("span_%s_%dx%d+%d+%d_%d_%s", strings.ReplaceAll(mon.Name, "/", "_"), mon.Width, mon.Height, r.Min.X, r.Min.Y, bezel, mode)
+		cachedFilename, err := renderCacheFilename(absFilename, description)
+		if err != nil {
+			return err
+		}
+		outputImages[mon.Name] = cachedFilename
+		cachedFilenames = append(cachedFilenames, cachedFilename)
+	}
+	allCached := true
+	for _, cachedFilename := range cachedFilenames {
+		if !cachedRender(cachedFilename) {
+			allCached = false
+			break
+		}
+	}
+	if !allCached {
+		canvasBounds, _ := SpanRects(monitors, bezel)
+		img, err := OpenImage(absFilename, uint(canvasBounds.Dx()), uint(canvasBounds.Dy()))
+		if err != nil {
+			return err
+		}
+		crops, err := RenderSpan(img, monitors, bezel, mode)
+		if err != nil {
+			return err
+		}
+		for i, crop := range crops {
+			if err := saveRendered(cachedFilenames[i], crop); err != nil {
+				return err
+			}
+		}
+	}
+	err = SetWallpaperPerMonitor(outputImages, defaultMode, verbose)
+	if err != nil {
+		if verbose {
+			fmt.Fprintf(os.Stderr, "could not set one wallpaper per monitor: %v\n", err)
+		}
+		// GNOME, Cinnamon, MATE and Deepin can span an image by themselves
+		if err := SetWallpaperCustom(absFilename, "spanned", verbose); err != nil {
+			return err
+		}
+	}
+	saveState(&State{Image: absFilename, Mode: mode, Span: true, Bezel: bezel}, verbose)
+	return nil
+}
@@ -0,0 +1,52 @@
+package wallutils
+
+import (
+	"image"
+	"testing"
+)
+
+func TestSpanRects(t *testing.T) {
+	monitors := []Monitor{
+		{Name: "DP-1", X: 1920, Y: 0, Width: 1920, Height: 1080, Scale: 1},
+		{Name: "eDP-1", X: 0, Y: 0, Width: 3840, Height: 2160, Scale: 2},
+		{Name: "HDMI-1", X: 0, Y: 1080, Width: 1920, Height: 1080, Scale: 1},
+	}
+	canvas, rects := SpanRects(monitors, 0)
+	if canvas != image.Rect(0, 0, 3840, 2160) {
+		t.Errorf("unexpected canvas: %v", canvas)
+	}
+	if rects[0] != image.Rect(1920, 0, 3840, 1080) || rects[1] != image.Rect(0, 0, 1920, 1080) || rects[2] != image.Rect(0, 1080, 1920, 2160) {
+		t.Errorf("unexpected areas: %v", rects)
+	}
+
+	// With bezel compensation, there is a gap between the columns and the rows
+	canvas, rects = SpanRects(monitors, 50)
+	if canvas != image.Rect(0, 0, 3890, 2210) {
+		t.Errorf("unexpected canvas: %v", canvas)
+	}
+	if rects[0] != image.Rect(1970, 0, 3890, 1080) || rects[1] != image.Rect(0, 0, 1920, 1080) || rects[2] != image.Rect(0, 1130, 1920, 2210) {
+		t.Errorf("unexpected areas: %v", rects)
+	}
+}
+
+func TestRenderSpan(t *testing.T) {
+	// Two monitors next to each other, where the right one is scaled
+	monitors := []Monitor{
+		{Name: "DP-1", X: 0, Y: 0, Width: 40, Height: 20, Scale: 1},
+		{Name: "DP-2", X: 40, Y: 0, Width: 80, Height: 40, Scale: 2},
+	}
+	crops, err := RenderSpan(redBlueImage(80, 20), monitors, 0, "fill")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(crops) != 2 {
+		t.Fatalf("expected 2 images, got %d", len(crops))
+	}
+	if crops[0].Bounds() != image.Rect(0, 0, 40, 20) || crops[1].Bounds() != image.Rect(0, 0, 80, 40) {
+		t.Errorf("unexpected sizes: %v, %v", crops[0].Bounds(), crops[1].Bounds())
+	}
+	expectColor(t, crops[0], 5, 10, red)
+	expectColor(t, crops[0], 35, 10, red)
+	expectColor(t, crops[1], 5, 20, blue)
+	expectColor(t, crops[1], 75, 20, blue)
+}
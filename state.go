@@ -0,0 +1,142 @@
+package wallutils
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// State is the wallpaper that was last set with wallutils, so that it can be
+// shown by getwallpaper and restored with setwallpaper --restore
+type State struct {
+	Image   string            `json:"image,omitempty"`   // the image filename, as given by the user
+	Dark    string            `json:"dark,omitempty"`    // the image to use with a dark color scheme, if a light/dark pair was set
+	Mode    string            `json:"mode,omitempty"`    // the wallpaper mode, like "stretch" or "fill"
+	Outputs map[string]string `json:"outputs,omitempty"` // output name -> image filename, if one wallpaper per monitor was set
+	Render  bool              `json:"render,omitempty"`  // the image was rendered for each monitor first
+	Span    bool              `json:"span,omitempty"`    // the image was spanned across all monitors
+	Bezel   uint              `json:"bezel,omitempty"`   // the gap between monitors, when spanning
+	Color   string            `json:"color,omitempty"`   // a solid color, like "#223344", instead of an image
+}
+
+// StateFilename returns the path to the file where the last wallpaper is
+// stored: $XDG_STATE_HOME/wallutils/wallpaper.json, or
+// ~/.local/state/wallutils/wallpaper.json if XDG_STATE_HOME is not set
+func StateFilename() (string, error) {
+	stateDir := os.Getenv("XDG_STATE_HOME")
+	if !filepath.IsAbs(stateDir) {
+		homeDir, err := os.UserHomeDir()
+		if err != nil {
+			return "", err
+		}
+		stateDir = filepath.Join(homeDir, ".local", "state")
+	}
+	return filepath.Join(stateDir, "wallutils", "wallpaper.json"), nil
+}
+
+// LoadState reads the last wallpaper that was set with wallutils
+func LoadState() (*State, error) {
+	filename, err := StateFilename()
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	var s State
+	if err := json.Unmarshal(data, &s); err != nil {
+		return nil, fmt.Errorf("could not read %s: %v", filename, err)
+	}
+	if s.Image == "" && len(s.Outputs) == 0 && s.Color == "" {
+		return nil, fmt.Errorf("no wallpaper in %s", filename)
+	}
+	return &s, nil
+}
+
+// Save writes the state to the state file. A temporary file is written
+// first, so that a partially written file is never read.
+func (s *State) Save() error {
+	filename, err := StateFilename()
+	if err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(filename), 0o755); err != nil {
+		return err
+	}
+	tmpFilename := filename + ".tmp"
+	if err := os.WriteFile(tmpFilename, append(data, '\n'), 0o644); err != nil {
+		os.Remove(tmpFilename)
+		return err
+	}
+	return os.Rename(tmpFilename, filename)
+}
+
+// saveState saves the given state. Setting the wallpaper has already
+// succeeded at this point, so errors are only reported when verbose.
+func saveState(s *State, verbose bool) {
+	if err := s.Save(); err != nil && verbose {
+		fmt.Fprintf(os.Stderr, "could not save the wallpaper state: %v\n", err)
+	}
+}
+
+// Restore sets the wallpaper again, the same way as it was set the last time
+func (s *State) Restore(verbose bool) error {
+	switch {
+	case s.Color != "":
+		c, err := ParseColor(s.Color)
+		if err != nil {
+			return err
+		}
+		return SetWallpaperColor(c, verbose)
+	case s.Span:
+		return SetWallpaperSpanned(s.Image, s.Bezel, s.Mode, verbose)
+	case s.Render && len(s.Outputs) > 0:
+		for output, imageFilename := range s.Outputs {
+			if err := SetWallpaperRenderedForOutput(output, imageFilename, s.Mode, verbose); err != nil {
+				return err
+			}
+		}
+		return nil
+	case s.Render:
+		return SetWallpaperRendered(s.Image, s.Mode, verbose)
+	case len(s.Outputs) > 0:
+		return SetWallpaperPerMonitor(s.Outputs, s.Mode, verbose)
+	case s.Dark != "":
+		return SetWallpaperLightDark(s.Image, s.Dark, s.Mode, verbose)
+	case s.Image != "":
+		return SetWallpaperCustom(s.Image, s.Mode, verbose)
+	}
+	return errors.New("no wallpaper to restore")
+}
+
+// RestoreWallpaper sets the wallpaper that was last set with wallutils
+func RestoreWallpaper(verbose bool) error {
+	s, err := LoadState()
+	if err != nil {
+		return err
+	}
+	return s.Restore(verbose)
+}
+
+// outputState returns a state with the given wallpapers per output. If the
+// previous wallpapers were also set per output, in the same way, the
+// wallpapers for the other outputs are kept.
+func outputState(outputImages map[string]string, mode string, render bool) *State {
+	s := &State{Mode: mode, Render: render, Outputs: make(map[string]string)}
+	if prev, err := LoadState(); err == nil && !prev.Span && prev.Render == render && prev.Mode == mode {
+		for output, imageFilename := range prev.Outputs {
+			s.Outputs[output] = imageFilename
+		}
+	}
+	for output, imageFilename := range outputImages {
+		s.Outputs[output] = imageFilename
+	}
+	return s
+}
@@ -0,0 +1,51 @@
+package wallutils
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestStateSaveLoad(t *testing.T) {
+	stateDir := t.TempDir()
+	t.Setenv("XDG_STATE_HOME", stateDir)
+
+	filename, err := StateFilename()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if filename != filepath.Join(stateDir, "wallutils", "wallpaper.json") {
+		t.Errorf("unexpected state filename: %s", filename)
+	}
+	if _, err := LoadState(); err == nil {
+		t.Error("expected an error when there is no state file")
+	}
+
+	if err := (&State{Image: "/usr/share/backgrounds/a.png", Mode: "fill", Render: true}).Save(); err != nil {
+		t.Fatal(err)
+	}
+	s, err := LoadState()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.Image != "/usr/share/backgrounds/a.png" || s.Mode != "fill" || !s.Render || s.Span {
+		t.Errorf("unexpected state: %+v", s)
+	}
+}
+
+func TestOutputState(t *testing.T) {
+	t.Setenv("XDG_STATE_HOME", t.TempDir())
+
+	if err := outputState(map[string]string{"DP-1": "/a.png"}, "fill", false).Save(); err != nil {
+		t.Fatal(err)
+	}
+	// The wallpaper for DP-1 is kept when only HDMI-A-1 is changed
+	s := outputState(map[string]string{"HDMI-A-1": "/b.png"}, "fill", false)
+	if len(s.Outputs) != 2 || s.Outputs["DP-1"] != "/a.png" || s.Outputs["HDMI-A-1"] != "/b.png" {
+		t.Errorf("unexpected outputs: %v", s.Outputs)
+	}
+	// But not if the mode has changed
+	s = outputState(map[string]string{"HDMI-A-1": "/b.png"}, "tile", false)
+	if len(s.Outputs) != 1 {
+		t.Errorf("unexpected outputs: %v", s.Outputs)
+	}
+}
@@ -5,6 +5,7 @@ package wallutils
 
 import (
 	"fmt"
+	"image/color"
 
 	"github.com/xyproto/env/v2"
 )
@@ -35,6 +36,17 @@ func (s *Sway) SetMode(mode string) {
 	s.mode = mode
 }
 
+// Capabilities returns the wallpaper modes that are supported by this backend,
+// if one wallpaper per output can be set and if the wallpaper is kept after
+// logging in again
+func (s *Sway) Capabilities() Capabilities {
+	return Capabilities{
+		Modes:      []string{"center", "tile", "fill", "stretch", "fit", "scale", "scaled", "zoom", "zoomed", "stretched"},
+		PerOutput:  true,
+		Persistent: false,
+	}
+}
+
 // SetVerbose can be used for setting the verbose field to true or false.
 // This will cause this backend to output information about what is is doing on stdout.
 func (s *Sway) SetVerbose(verbose bool) {
@@ -44,6 +56,13 @@ func (s *Sway) SetVerbose(verbose bool) {
 // SetWallpaper sets the desktop wallpaper, given an image filename.
 // The image must exist and be readable.
 func (s *Sway) SetWallpaper(imageFilename string) error {
+	return s.SetWallpaperForOutput("*", imageFilename)
+}
+
+// SetWallpaperForOutput sets the desktop wallpaper for the given output
+// (like "DP-1"), or for all outputs if the output name is "*".
+// The image must exist and be readable.
+func (s *Sway) SetWallpaperForOutput(output, imageFilename string) error {
 	if !exists(imageFilename) {
 		return fmt.Errorf("no such file: %s", imageFilename)
 	}
@@ -55,7 +74,7 @@ func (s *Sway) SetWallpaper(imageFilename string) error {
 	}
 
 	switch mode {
-	case "center", "tile", "fill", "stretch":
+	case "center", "tile", "fill", "stretch", "fit":
 		break
 	case "scale", "scaled":
 		mode = "fill"
@@ -63,8 +82,24 @@ func (s *Sway) SetWallpaper(imageFilename string) error {
 		mode = "stretch"
 	default:
 		// Invalid and unrecognized desktop wallpaper mode
-		return fmt.Errorf("invalid desktop wallpaper mode for Sway: %s", mode)
+		return fmt.Errorf("%w for Sway: %s", ErrUnsupportedMode, mode)
 	}
 
-	return run("swaymsg", []string{"output * bg \"'" + imageFilename + "'\" " + mode}, s.verbose)
+	// quote the output name, unless all outputs are selected
+	if output != "*" {
+		output = "\"" + output + "\""
+	}
+
+	return run("swaymsg", []string{"output " + output + " bg \"'" + imageFilename + "'\" " + mode}, s.verbose)
+}
+
+// CurrentWallpaper returns the current wallpaper. Sway starts swaybg for
+// drawing the wallpaper, so the image is found in the swaybg process args.
+func (s *Sway) CurrentWallpaper() (string, error) {
+	return currentSwaybgWallpaper()
+}
+
+// SetColor sets a solid color as the wallpaper for all outputs
+func (s *Sway) SetColor(c color.RGBA) error {
+	return run("swaymsg", []string{"output * bg \"" + ColorString(c) + "\" solid_color"}, s.verbose)
 }
@@ -5,6 +5,8 @@ package wallutils
 
 import (
 	"fmt"
+	"image/color"
+	"sort"
 
 	"github.com/xyproto/env/v2"
 )
@@ -13,6 +15,7 @@ import (
 type SwayBG struct {
 	mode    string
 	verbose bool
+	outputs map[string]swaybgOutput // output name (or "*") -> image filename and mode, for the running swaybg instance, or nil if not known yet
 }
 
 // Name returns the name of this window manager or desktop environment
@@ -35,6 +38,17 @@ func (sb *SwayBG) SetMode(mode string) {
 	sb.mode = mode
 }
 
+// Capabilities returns the wallpaper modes that are supported by this backend,
+// if one wallpaper per output can be set and if the wallpaper is kept after
+// logging in again
+func (sb *SwayBG) Capabilities() Capabilities {
+	return Capabilities{
+		Modes:      []string{"center", "tile", "fill", "stretch", "fit", "scale", "scaled", "zoom", "zoomed", "stretched"},
+		PerOutput:  true,
+		Persistent: false,
+	}
+}
+
 // SetVerbose can be used for setting the verbose field to true or false.
 // This will cause this backend to output information about what is is doing on stdout.
 func (sb *SwayBG) SetVerbose(verbose bool) {
@@ -44,6 +58,18 @@ func (sb *SwayBG) SetVerbose(verbose bool) {
 // SetWallpaper sets the desktop wallpaper, given an image filename.
 // The image must exist and be readable.
 func (sb *SwayBG) SetWallpaper(imageFilename string) error {
+	// forget about any per-output wallpapers
+	sb.outputs = make(map[string]swaybgOutput)
+	return sb.SetWallpaperForOutput("*", imageFilename)
+}
+
+// SetWallpaperForOutput sets the desktop wallpaper for the given output
+// (like "DP-1"), or for all outputs if the output name is "*".
+// The image must exist and be readable.
+// swaybg is restarted with all outputs that the running swaybg has images
+// for, in addition to the given output, since one swaybg instance is used
+// for all outputs. The other outputs keep their images and modes.
+func (sb *SwayBG) SetWallpaperForOutput(output, imageFilename string) error {
 	if !exists(imageFilename) {
 		return fmt.Errorf("no such file: %s", imageFilename)
 	}
@@ -64,14 +90,35 @@ func (sb *SwayBG) SetWallpaper(imageFilename string) error {
 		mode = "stretch"
 	default:
 		// Invalid and unrecognized desktop wallpaper mode
-		return fmt.Errorf("invalid desktop wallpaper mode for swaybg: %s", mode)
+		return fmt.Errorf("%w for swaybg: %s", ErrUnsupportedMode, mode)
+	}
+
+	if sb.outputs == nil {
+		// Keep the images and modes of the other outputs, from the running swaybg
+		sb.outputs = runningSwaybgOutputs()
+	}
+	sb.outputs[output] = swaybgOutput{image: imageFilename, mode: mode}
+
+	// "*" sorts before the output names, so that specific outputs are listed last
+	var outputs []string
+	for o := range sb.outputs {
+		outputs = append(outputs, o)
+	}
+	sort.Strings(outputs)
+
+	var args []string
+	for _, o := range outputs {
+		args = append(args, "-o", o, "-i", sb.outputs[o].image)
+		if sb.outputs[o].mode != "" {
+			args = append(args, "-m", sb.outputs[o].mode)
+		}
 	}
 
 	// first stop swaybg, if it`s already running (and ignore errors, if it can't be killed)
 	run("pkill", []string{"swaybg"}, sb.verbose)
 
 	// start a new instance
-	pid, err := runbg("swaybg", []string{"-i", imageFilename, "-m", mode, "-o", "*"}, sb.verbose)
+	pid, err := runbg("swaybg", args, sb.verbose)
 	if err != nil {
 		return err
 	}
@@ -82,3 +129,25 @@ func (sb *SwayBG) SetWallpaper(imageFilename string) error {
 	}
 	return nil
 }
+
+// CurrentWallpaper returns the image that the running swaybg process was
+// started with
+func (sb *SwayBG) CurrentWallpaper() (string, error) {
+	return currentSwaybgWallpaper()
+}
+
+// SetColor sets a solid color as the wallpaper for all outputs, by using
+// the solid_color mode of swaybg
+func (sb *SwayBG) SetColor(c color.RGBA) error {
+	// forget about any per-output wallpapers
+	sb.outputs = nil
+	run("pkill", []string{"swaybg"}, sb.verbose)
+	pid, err := runbg("swaybg", []string{"-o", "*", "-c", ColorString(c), "-m", "solid_color"}, sb.verbose)
+	if err != nil {
+		return err
+	}
+	if sb.verbose {
+		fmt.Println("started PID", pid)
+	}
+	return nil
+}
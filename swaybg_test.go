@@ -0,0 +1,24 @@
+//go:build cgo
+// +build cgo
+
+package wallutils
+
+import "testing"
+
+func init() {
+	multiMonitorWMs = append(multiMonitorWMs, &Sway{}, &SwayBG{})
+}
+
+func TestSwaybgOutputs(t *testing.T) {
+	outputs := swaybgOutputs([]string{"-o", "*", "-i", "/a.png", "-m", "fill", "-o", "DP-1", "-i", "/b.png", "-m", "tile", "--output=HDMI-A-1", "--image=/c.png", "--mode=center"})
+	if len(outputs) != 3 || outputs["*"] != (swaybgOutput{"/a.png", "fill"}) || outputs["DP-1"] != (swaybgOutput{"/b.png", "tile"}) || outputs["HDMI-A-1"] != (swaybgOutput{"/c.png", "center"}) {
+		t.Errorf("unexpected outputs: %v", outputs)
+	}
+	// Without an output, the image is for all outputs
+	if outputs := swaybgOutputs([]string{"-i", "/a.png"}); len(outputs) != 1 || outputs["*"] != (swaybgOutput{image: "/a.png"}) {
+		t.Errorf("unexpected outputs: %v", outputs)
+	}
+	if outputs := swaybgOutputs([]string{"-c", "#000000", "-m", "solid_color"}); len(outputs) != 0 {
+		t.Errorf("expected no outputs, got %v", outputs)
+	}
+}
@@ -170,6 +170,9 @@ func Quit(err error) {
 		msg += "."
 	}
 	fmt.Fprintf(os.Stderr, "%s%s\n", strings.ToUpper(string(msg[0])), msg[1:])
+	if hint := Hint(err); hint != "" {
+		fmt.Fprintln(os.Stderr, hint)
+	}
 	os.Stdout.Sync()
 	os.Stderr.Sync()
 	os.Exit(1)
@@ -3,7 +3,10 @@ package wallutils
 import (
 	"errors"
 	"fmt"
+	"net/url"
 	"os"
+	"path/filepath"
+	"sort"
 	"strings"
 )
 
@@ -17,6 +20,27 @@ type WM interface {
 	SetMode(string)
 }
 
+// CapabilitiesWM is an interface for backends that can tell what they can
+// do, like which wallpaper modes they support
+type CapabilitiesWM interface {
+	WM
+	Capabilities() Capabilities
+}
+
+// MultiMonitorWM is an interface for backends that can also set a different
+// wallpaper per output (monitor), given the name of the output
+type MultiMonitorWM interface {
+	WM
+	SetWallpaperForOutput(output, imageFilename string) error
+}
+
+// CurrentWallpaperWM is an interface for backends that can also tell which
+// image is currently used as the wallpaper
+type CurrentWallpaperWM interface {
+	WM
+	CurrentWallpaper() (string, error)
+}
+
 // Wallpaper represents an image file that is part of a wallpaper collection (in a directory with several resolutions of the same image, for example)
 type Wallpaper struct {
 	CollectionName   string // the name of the directory containing this wallpaper, if it's not "pixmaps", "images" or "contents". May use the parent of the parent.
@@ -24,6 +48,10 @@ type Wallpaper struct {
 	Width            uint   // width of the image
 	Height           uint   // height of the image
 	PartOfCollection bool   // likely to be part of a wallpaper collection
+	Name             string // the name of the KDE Plasma wallpaper package this wallpaper is part of, if any
+	Author           string // the author, from the wallpaper package, if any
+	License          string // the license, from the wallpaper package, if any
+	Dark             bool   // this is the dark variant of the wallpaper, from an images_dark directory
 }
 
 // All backends should support these modes, if possible: stretch, fill, scale, tile, center
@@ -31,47 +59,225 @@ const defaultMode = "stretch"
 
 // SetWallpaperCustom will set the given image filename as the wallpaper,
 // regardless of which display server, window manager or desktop environment is in use.
+// See SetWallpaperCustomAndSave for also saving the wallpaper, so that it can be restored.
 func SetWallpaperCustom(imageFilename, mode string, verbose bool) error {
 	if !exists(imageFilename) {
 		return fmt.Errorf("no such file: %s", imageFilename)
 	}
-	var lastErr error
+	wms, err := backends()
+	if err != nil {
+		return err
+	}
+	noBackend := &NoBackendError{What: "setting the desktop wallpaper"}
 	// Loop through all available WM structs
-	for _, wm := range WMs {
-		if wm.Running() && wm.ExecutablesExists() {
-			if verbose {
-				fmt.Printf("Using the %s backend.\n", wm.Name())
-			}
-			wm.SetVerbose(verbose)
-			if mode != "" && mode != defaultMode {
-				wm.SetMode(mode)
+	for _, wm := range wms {
+		if !usable(wm) {
+			continue
+		}
+		// The running desktop environment or window manager was found, so
+		// don't try the next backend if it does not support the mode
+		if unsupportedMode(wm, mode) {
+			return fmt.Errorf("%w for %s: %s", ErrUnsupportedMode, wm.Name(), mode)
+		}
+		if verbose {
+			fmt.Printf("Using the %s backend.\n", wm.Name())
+		}
+		wm.SetVerbose(verbose)
+		if mode != "" && mode != defaultMode {
+			wm.SetMode(mode)
+		}
+		filename, err := convertFor(wm, imageFilename, verbose)
+		if err != nil {
+			return err
+		}
+		err = wm.SetWallpaper(filename)
+		if err == nil {
+			return nil
+		}
+		if verbose {
+			fmt.Fprintf(os.Stderr, "failed: %v\n", err)
+		}
+		// If the wallpaper mode is wrong, or if the backend can never set the
+		// wallpaper, don't try the next backend, but return the error
+		if errors.Is(err, ErrUnsupportedMode) || errors.Is(err, ErrPermanent) {
+			return err
+		}
+		// Try the next one
+		noBackend.Errors = append(noBackend.Errors, &BackendError{Backend: wm.Name(), Err: err})
+	}
+	return noBackend
+}
+
+// SetWallpaperCustomAndSave sets the wallpaper, like SetWallpaperCustom, and
+// saves the image filename and mode, so that the wallpaper can be restored.
+// This is for wallpapers that are chosen by the user, not for wallpapers that
+// are changed by a program, like timed wallpapers.
+func SetWallpaperCustomAndSave(imageFilename, mode string, verbose bool) error {
+	if err := SetWallpaperCustom(imageFilename, mode, verbose); err != nil {
+		return err
+	}
+	saveState(&State{Image: imageFilename, Mode: mode}, verbose)
+	return nil
+}
+
+// convertFor returns the given image filename if the backend can read the
+// image format, or else the filename of a converted PNG image
+func convertFor(wm WM, imageFilename string, verbose bool) (string, error) {
+	if capabilities, _ := BackendCapabilities(wm); capabilities.SupportsFormat(imageFilename) {
+		return imageFilename, nil
+	}
+	convertedFilename, err := ConvertToPNG(imageFilename)
+	if err != nil {
+		return "", err
+	}
+	if verbose {
+		fmt.Printf("Using %s, since %s can not read %s.\n", convertedFilename, wm.Name(), filepath.Base(imageFilename))
+	}
+	return convertedFilename, nil
+}
+
+// SetWallpaperPerMonitor will set one wallpaper per output, given a map from
+// output name (like "DP-1") to image filename. Only backends that implements
+// the MultiMonitorWM interface are considered.
+// See SetWallpaperPerMonitorAndSave for also saving the wallpapers.
+func SetWallpaperPerMonitor(outputImages map[string]string, mode string, verbose bool) error {
+	if len(outputImages) == 0 {
+		return errors.New("no outputs given")
+	}
+	// Sort the output names, so that the wallpapers are set in a predictable order
+	var outputs []string
+	for output, imageFilename := range outputImages {
+		if !exists(imageFilename) {
+			return fmt.Errorf("no such file: %s", imageFilename)
+		}
+		outputs = append(outputs, output)
+	}
+	sort.Strings(outputs)
+	wms, err := backends()
+	if err != nil {
+		return err
+	}
+	noBackend := &NoBackendError{What: "setting the desktop wallpaper per monitor"}
+	// Loop through all available WM structs
+NEXT_WM:
+	for _, wm := range wms {
+		mwm, ok := wm.(MultiMonitorWM)
+		if !ok || !usable(wm) {
+			continue
+		}
+		if capabilities, ok := BackendCapabilities(wm); ok && !capabilities.PerOutput {
+			continue
+		}
+		// The running desktop environment or window manager was found, so
+		// don't try the next backend if it does not support the mode
+		if unsupportedMode(wm, mode) {
+			return fmt.Errorf("%w for %s: %s", ErrUnsupportedMode, wm.Name(), mode)
+		}
+		if verbose {
+			fmt.Printf("Using the %s backend.\n", wm.Name())
+		}
+		wm.SetVerbose(verbose)
+		if mode != "" && mode != defaultMode {
+			wm.SetMode(mode)
+		}
+		for _, output := range outputs {
+			filename, err := convertFor(wm, outputImages[output], verbose)
+			if err != nil {
+				return err
 			}
-			if err := wm.SetWallpaper(imageFilename); err != nil {
-				lastErr = err
-				switch wm.Name() {
-				case "Weston":
-					// If the current windowmanager is Weston, no method is currently available
+			if err := mwm.SetWallpaperForOutput(output, filename); err != nil {
+				if verbose {
+					fmt.Fprintf(os.Stderr, "failed: %v\n", err)
+				}
+				// If the wallpaper mode is wrong, or if the backend can never set the
+				// wallpaper, don't try the next backend, but return the error
+				if errors.Is(err, ErrUnsupportedMode) || errors.Is(err, ErrPermanent) {
 					return err
-				default:
-					if verbose {
-						fmt.Fprintf(os.Stderr, "failed: %v\n", err)
-					}
-					// If the wallpaper mode is wrong, don't try the next backend, but return the error
-					if strings.Contains(err.Error(), "invalid desktop wallpaper mode") {
-						return err
-					}
-					// Try the next one
-					continue
 				}
-			} else {
-				return nil
+				// Try the next one
+				noBackend.Errors = append(noBackend.Errors, &BackendError{Backend: wm.Name(), Err: err})
+				continue NEXT_WM
 			}
 		}
+		return nil
+	}
+	return noBackend
+}
+
+// SetWallpaperPerMonitorAndSave sets one wallpaper per output, like
+// SetWallpaperPerMonitor, and saves the wallpapers, so that they can be restored
+func SetWallpaperPerMonitorAndSave(outputImages map[string]string, mode string, verbose bool) error {
+	if err := SetWallpaperPerMonitor(outputImages, mode, verbose); err != nil {
+		return err
+	}
+	saveState(outputState(outputImages, mode, false), verbose)
+	return nil
+}
+
+// CurrentWallpaperVerbose returns the filename of the current wallpaper.
+// The running backends are asked first. If none of them can tell, the
+// wallpaper that was last set with wallutils is returned.
+func CurrentWallpaperVerbose(verbose bool) (string, error) {
+	wms, err := backends()
+	if err != nil {
+		return "", err
+	}
+	for _, wm := range wms {
+		cwm, ok := wm.(CurrentWallpaperWM)
+		if !ok || !usable(wm) {
+			continue
+		}
+		if verbose {
+			fmt.Printf("Using the %s backend.\n", wm.Name())
+		}
+		wm.SetVerbose(verbose)
+		imageFilename, err := cwm.CurrentWallpaper()
+		if err == nil && imageFilename != "" {
+			return imageFilename, nil
+		}
+		if verbose && err != nil {
+			fmt.Fprintf(os.Stderr, "failed: %v\n", err)
+		}
+	}
+	s, err := LoadState()
+	if err != nil {
+		return "", fmt.Errorf("could not find the current wallpaper: %v", err)
+	}
+	if verbose {
+		fmt.Println("Using the last wallpaper that was set with wallutils.")
+	}
+	if s.Color != "" {
+		// A solid color, like "#223344"
+		return s.Color, nil
+	}
+	if s.Image == "" {
+		// Return the wallpaper of the first output
+		var outputs []string
+		for output := range s.Outputs {
+			outputs = append(outputs, output)
+		}
+		sort.Strings(outputs)
+		return s.Outputs[outputs[0]], nil
+	}
+	return s.Image, nil
+}
+
+// CurrentWallpaper returns the filename of the current wallpaper
+func CurrentWallpaper() (string, error) {
+	return CurrentWallpaperVerbose(false)
+}
+
+// uriToFilename converts a file:// URI to a filename. The filename is
+// unescaped if needed, since some tools escape spaces as %20 and some do not.
+func uriToFilename(uri string) string {
+	filename := strings.TrimPrefix(uri, "file://")
+	if exists(filename) {
+		return filename
 	}
-	if lastErr != nil {
-		return fmt.Errorf("found no working method for setting the desktop wallpaper:\n%v", lastErr)
+	if unescaped, err := url.PathUnescape(filename); err == nil {
+		return unescaped
 	}
-	return errors.New("found no working method for setting the desktop wallpaper")
+	return filename
 }
 
 // SetWallpaperVerbose will set the desktop wallpaper, for any supported
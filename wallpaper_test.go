@@ -0,0 +1,143 @@
+package wallutils
+
+import (
+	"errors"
+	"os"
+	"testing"
+
+	"github.com/xyproto/env/v2"
+)
+
+// multiMonitorWMs are the backends that should implement MultiMonitorWM.
+// Backends that need cgo are added in swaybg_test.go.
+var multiMonitorWMs = []WM{&Hyprpaper{}, &Xfce4{}, &Plasma{}}
+
+func TestMultiMonitorWM(t *testing.T) {
+	for _, wm := range multiMonitorWMs {
+		if _, ok := wm.(MultiMonitorWM); !ok {
+			t.Errorf("%s does not implement MultiMonitorWM", wm.Name())
+		}
+	}
+	if _, ok := WM(&Feh{}).(MultiMonitorWM); ok {
+		t.Error("Feh should not implement MultiMonitorWM")
+	}
+}
+
+func TestPlasmaOutputByName(t *testing.T) {
+	f, err := os.CreateTemp("", "wallutils*.png")
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+	defer os.Remove(f.Name())
+
+	// Plasma can only select outputs by screen number
+	if err := (&Plasma{}).SetWallpaperForOutput("DP-1", f.Name()); err == nil {
+		t.Error("expected an error when selecting a Plasma output by name")
+	}
+}
+
+func TestCurrentWallpaperWM(t *testing.T) {
+	for _, wm := range []WM{&Gnome3{}, &Xfce4{}, &Plasma{}, &Feh{}} {
+		if _, ok := wm.(CurrentWallpaperWM); !ok {
+			t.Errorf("%s does not implement CurrentWallpaperWM", wm.Name())
+		}
+	}
+}
+
+func TestParseFehbg(t *testing.T) {
+	script := "#!/bin/sh\nfeh --no-fehbg --bg-fill '/home/user/My Pictures/a.jpg' '/home/user/it'\\''s.png' \n"
+	imageFilenames := parseFehbg(script)
+	if len(imageFilenames) != 2 || imageFilenames[0] != "/home/user/My Pictures/a.jpg" || imageFilenames[1] != "/home/user/it's.png" {
+		t.Errorf("unexpected image filenames: %q", imageFilenames)
+	}
+}
+
+func TestSwaybgImage(t *testing.T) {
+	if imageFilename := swaybgImage([]string{"-o", "*", "-i", "/a.png", "-m", "fill", "-o", "DP-1", "-i", "/b.png", "-m", "fill"}); imageFilename != "/a.png" {
+		t.Errorf("expected /a.png, got %s", imageFilename)
+	}
+	if imageFilename := swaybgImage([]string{"-o", "DP-1", "-i", "/b.png", "-m", "fill"}); imageFilename != "/b.png" {
+		t.Errorf("expected /b.png, got %s", imageFilename)
+	}
+	if imageFilename := swaybgImage([]string{"--output=DP-1", "-i", "/b.png", "-o", "*", "--image=/a.png"}); imageFilename != "/a.png" {
+		t.Errorf("expected /a.png, got %s", imageFilename)
+	}
+	if imageFilename := swaybgImage([]string{"-c", "#000000"}); imageFilename != "" {
+		t.Errorf("expected no image, got %s", imageFilename)
+	}
+}
+
+func TestDBusReplyString(t *testing.T) {
+	reply := "method return time=1700000000.1 sender=:1.20 -> destination=:1.99 serial=42 reply_serial=2\n   string \"file:///home/user/a.jpg\n\"\n"
+	if uri := dbusReplyString(reply); uri != "file:///home/user/a.jpg" {
+		t.Errorf("unexpected string: %q", uri)
+	}
+	if filename := uriToFilename("file:///no/such/dir/My%20Picture.jpg"); filename != "/no/such/dir/My Picture.jpg" {
+		t.Errorf("unexpected filename: %s", filename)
+	}
+}
+
+// testWM is a backend that is always running, and that records the wallpaper
+type testWM struct {
+	name      string
+	modes     []string
+	mode      string
+	wallpaper string
+	err       error // returned by SetWallpaper, if not nil
+}
+
+func (t *testWM) Name() string            { return t.name }
+func (t *testWM) ExecutablesExists() bool { return true }
+func (t *testWM) Running() bool           { return true }
+func (t *testWM) SetVerbose(bool)         {}
+func (t *testWM) SetMode(mode string)     { t.mode = mode }
+
+func (t *testWM) SetWallpaper(imageFilename string) error {
+	if t.err != nil {
+		return t.err
+	}
+	t.wallpaper = imageFilename
+	return nil
+}
+
+func (t *testWM) Capabilities() Capabilities {
+	return Capabilities{Modes: t.modes}
+}
+
+func TestSetWallpaperCustomMode(t *testing.T) {
+	f, err := os.CreateTemp("", "wallutils*.png")
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+	defer os.Remove(f.Name())
+
+	fill := &testWM{name: "Fill", modes: []string{"fill"}}
+	tile := &testWM{name: "Tile", modes: []string{"fill", "tile"}}
+	defer func(wms []WM) { WMs = wms }(WMs)
+	WMs = []WM{fill, tile}
+	defer func(name string) { selectedBackend = name }(selectedBackend)
+	selectedBackend = ""
+	t.Setenv(BackendEnvVar, "")
+	env.Load()
+	defer env.Load()
+
+	// The first backend that is running does not support the mode, so the
+	// other backends are not tried
+	if err := SetWallpaperCustom(f.Name(), "tile", false); !errors.Is(err, ErrUnsupportedMode) {
+		t.Errorf("expected ErrUnsupportedMode, got %v", err)
+	}
+	if fill.wallpaper != "" || tile.wallpaper != "" {
+		t.Errorf("expected no backend to be used, got %+v and %+v", fill, tile)
+	}
+
+	// The next backend is only tried if the first one fails
+	fill.err = errors.New("could not set the wallpaper")
+	if err := SetWallpaperCustom(f.Name(), "fill", false); err != nil {
+		t.Fatal(err)
+	}
+	if tile.wallpaper != f.Name() || tile.mode != "fill" {
+		t.Errorf("expected the Tile backend to be used, got %+v", tile)
+	}
+}
@@ -7,8 +7,7 @@ import "C"
 import (
 	"errors"
 	"log"
-	"strconv"
-	"strings"
+	"unsafe"
 )
 
 // WaylandCanConnect checks if a Wayland server is up and running
@@ -21,90 +20,73 @@ func WaylandInfo() (string, error) {
 	if !WaylandCanConnect() {
 		return "", errors.New("WaylandInfo(): not connected over Wayland")
 	}
-	infoString := C.GoString(C.WaylandInfoString())
-	return infoString, nil
+	cs := C.WaylandInfoString()
+	defer C.free(unsafe.Pointer(cs))
+	return C.GoString(cs), nil
 }
 
 // WaylandMonitors returns information about the available monitors.
 // The given slices are filled with data about resolution and DPI.
 func WaylandMonitors(IDs, widths, heights, wDPIs, hDPIs *[]uint) error {
-	if !WaylandCanConnect() {
-		return errors.New("WaylandMonitors(): not connected over Wayland")
-	}
-	info, err := WaylandInfo()
+	monitors, err := waylandMonitors()
 	if err != nil {
 		return err
 	}
+	for _, mon := range monitors {
+		*IDs = append(*IDs, mon.ID)
+		*widths = append(*widths, mon.Width)
+		*heights = append(*heights, mon.Height)
+		*wDPIs = append(*wDPIs, mon.DPIw)
+		*hDPIs = append(*hDPIs, mon.DPIh)
+	}
+	return nil
+}
 
+// waylandMonitors returns a slice of Monitor structs, one per wl_output
+func waylandMonitors() ([]Monitor, error) {
+	var outputs *C.struct_wayland_output
+	count := int(C.WaylandOutputs(&outputs))
+	if count < 0 {
+		return nil, errors.New("WaylandMonitors(): not connected over Wayland")
+	}
+	defer C.WaylandFreeOutputs(outputs, C.int(count))
+	if count == 0 {
+		return []Monitor{}, nil
+	}
 	var (
-		counter     uint
+		monitors    []Monitor
 		physCounter uint
-		physW       uint
-		physH       uint
-		wDPI        uint
-		hDPI        uint
 	)
-
-	// TODO: Write a C implementation instead of parsing the string output
-	lines := strings.Split(info, "\n")
-
-	// The physical width and height of the last encountered monitor, in millimeters
-	for i, line := range lines {
-		// Example output from Wayland Info:
-		//   width: 1920 px, height: 1200 px, refresh: 60 Hz,
-		//   flags: current
-		if strings.Contains(line, "flags: current") {
-			prevline := lines[i-1]
-			fields := strings.Fields(prevline)
-			if len(fields) > 4 {
-				w, err := strconv.Atoi(fields[1])
-				if err != nil {
-					return err
-				}
-				h, err := strconv.Atoi(fields[4])
-				if err != nil {
-					return err
-				}
-				*IDs = append(*IDs, counter)
-				*widths = append(*widths, uint(w))
-				*heights = append(*heights, uint(h))
-				if physW == 0 || physH == 0 {
-					wDPI = 96 // default DPI value, if no physical size is given
-					hDPI = 96 // default DPI value, if no physical size is given
-					log.Println("WARN: No physical monitor size detected!")
-					// return errors.New("no physical monitor size detected")
-				}
-				if physW > 0 && physH > 0 {
-					// Calculate DPI, from the monitor size (in mm) and the pixel size
-					wDPI = uint(float64(w) / (float64(physW) / 25.4))
-					hDPI = uint(float64(h) / (float64(physH) / 25.4))
-				}
-				*wDPIs = append(*wDPIs, wDPI)
-				*hDPIs = append(*hDPIs, hDPI)
-				counter++
-			}
-		} else if strings.Contains(line, "physical_width:") {
-			// Example output from Wayland Info:
-			//   physical_width: 518 mm, physical_height: 324 mm,
-			fields := strings.Fields(line)
-			if len(fields) > 5 {
-				w, err := strconv.Atoi(fields[1])
-				if err != nil {
-					return err
-				}
-				h, err := strconv.Atoi(fields[4])
-				if err != nil {
-					return err
-				}
-				physW = uint(w)
-				physH = uint(h)
-				physCounter++
-			}
+	for i, o := range unsafe.Slice(outputs, count) {
+		var wo waylandOutput
+		wo.x, wo.y = int(o.x), int(o.y)
+		wo.physW, wo.physH = int(o.physical_width), int(o.physical_height)
+		wo.transform = int(o.transform)
+		wo.scale = int(o.scale)
+		wo.width, wo.height = int(o.width), int(o.height)
+		wo.refresh = int(o.refresh)
+		wo.hasLogical = bool(o.has_logical)
+		wo.logicalX, wo.logicalY = int(o.logical_x), int(o.logical_y)
+		wo.logicalW, wo.logicalH = int(o.logical_width), int(o.logical_height)
+		wo.name = cString(o.name)
+		wo.make = cString(o.make)
+		wo.model = cString(o.model)
+		if wo.physW > 0 && wo.physH > 0 {
+			physCounter++
 		}
+		monitors = append(monitors, wo.monitor(uint(i)))
 	}
-	if 0 < physCounter && physCounter < counter {
+	if 0 < physCounter && physCounter < uint(len(monitors)) {
 		log.Println("WARN: Some monitors contains a physical size, but not all of them")
 		// return errors.New("some monitors contains a physical size, but not all of them")
 	}
-	return nil
+	return monitors, nil
+}
+
+// cString converts a C string that may be NULL to a Go string
+func cString(s *C.char) string {
+	if s == nil {
+		return ""
+	}
+	return C.GoString(s)
 }
@@ -1,8 +1,12 @@
+//go:build cgo
+// +build cgo
+
 package wallutils
 
 import (
 	"fmt"
 	"log"
+	"strings"
 	"testing"
 )
 
@@ -29,3 +33,48 @@ func TestWaylandMonitors(t *testing.T) {
 		fmt.Printf("monitor %d: %dx%d (DPI: %dx%d)\n", ID, widths[i], heights[i], wDPIs[i], hDPIs[i])
 	}
 }
+
+func TestWaylandMonitorsFakeCompositor(t *testing.T) {
+	startFakeCompositor(t, true, twoFakeOutputs...)
+	if !WaylandCanConnect() {
+		t.Skip("could not connect to the fake compositor")
+	}
+	monitors, err := waylandMonitors()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(monitors) != 2 {
+		t.Fatalf("expected 2 monitors, got %d", len(monitors))
+	}
+	laptop, external := monitors[0], monitors[1]
+	if laptop.Name != "eDP-1" || laptop.Width != 2880 || laptop.Height != 1620 || laptop.Scale != 1.5 || laptop.Refresh != 60 {
+		t.Errorf("unexpected monitor: %+v", laptop)
+	}
+	if external.Name != "DP-1" || external.X != 1920 || external.Rotation != 90 || external.Scale != 1 || external.Make != "Dell Inc." {
+		t.Errorf("unexpected monitor: %+v", external)
+	}
+	if external.Width != 1440 || external.Height != 2560 || external.DPIw != 107 || external.DPIh != 108 {
+		t.Errorf("unexpected DPI: %dx%d", external.DPIw, external.DPIh)
+	}
+	info, err := WaylandInfo()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(info, "logical_width: 1440, logical_height: 2560") {
+		t.Errorf("missing logical size in the info string:\n%s", info)
+	}
+}
+
+func TestWaylandMonitorsFakeCompositorNoXDG(t *testing.T) {
+	startFakeCompositor(t, false, twoFakeOutputs[0])
+	if !WaylandCanConnect() {
+		t.Skip("could not connect to the fake compositor")
+	}
+	monitors, err := waylandMonitors()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(monitors) != 1 || monitors[0].Scale != 2 || monitors[0].Name != "eDP-1" {
+		t.Errorf("unexpected monitors: %+v", monitors)
+	}
+}
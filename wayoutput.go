@@ -0,0 +1,68 @@
+package wallutils
+
+import "log"
+
+// waylandOutput contains the information that is sent by a Wayland server
+// for one wl_output, together with the logical position and size from
+// xdg_output, if available
+type waylandOutput struct {
+	x, y               int // position, in the compositor space
+	physW, physH       int // physical size, in mm
+	transform          int // wl_output transform, from 0 to 7
+	scale              int // integer scale factor
+	width, height      int // current mode, in pixels
+	refresh            int // current mode, in mHz
+	hasLogical         bool
+	logicalX, logicalY int // logical position, from xdg_output
+	logicalW, logicalH int // logical size, from xdg_output
+	name, make, model  string
+}
+
+// monitor converts the output information to a Monitor struct with the given ID
+func (o waylandOutput) monitor(ID uint) Monitor {
+	mon := Monitor{
+		ID:       ID,
+		Name:     o.name,
+		X:        o.x,
+		Y:        o.y,
+		Width:    uint(o.width),
+		Height:   uint(o.height),
+		Scale:    1,
+		Rotation: uint(o.transform%4) * 90,
+		Refresh:  float64(o.refresh) / 1000,
+		Make:     o.make,
+		Model:    o.model,
+	}
+	if o.scale > 0 {
+		mon.Scale = float64(o.scale)
+	}
+	if o.hasLogical {
+		mon.X, mon.Y = o.logicalX, o.logicalY
+		// The logical size is after the transform, while the mode is before,
+		// so this also handles fractional scaling
+		w := o.width
+		if mon.Rotation == 90 || mon.Rotation == 270 {
+			w = o.height
+		}
+		if o.logicalW > 0 && w > 0 {
+			mon.Scale = float64(w) / float64(o.logicalW)
+		}
+	}
+	if o.physW > 0 && o.physH > 0 {
+		// Calculate DPI, from the monitor size (in mm) and the pixel size
+		mon.DPIw = uint(float64(o.width) / (float64(o.physW) / 25.4))
+		mon.DPIh = uint(float64(o.height) / (float64(o.physH) / 25.4))
+		mon.DPISource = DPIFromServer
+	} else {
+		mon.DPIw = 96 // default DPI value, if no physical size is given
+		mon.DPIh = 96 // default DPI value, if no physical size is given
+		mon.DPISource = DPIDefault
+		log.Println("WARN: No physical monitor size detected!")
+	}
+	if mon.Rotation == 90 || mon.Rotation == 270 {
+		// The mode is not rotated, but use the size as it is shown, like xrandr does
+		mon.Width, mon.Height = mon.Height, mon.Width
+		mon.DPIw, mon.DPIh = mon.DPIh, mon.DPIw
+	}
+	return mon
+}
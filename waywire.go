@@ -0,0 +1,290 @@
+package wallutils
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// wlConn is a minimal client for the Wayland wire protocol, that only knows
+// enough to enumerate the outputs. No C libraries are needed.
+type wlConn struct {
+	conn   net.Conn
+	nextID uint32
+}
+
+// wlEvent is an event that has been received from the Wayland server
+type wlEvent struct {
+	object uint32
+	opcode uint16
+	body   []byte
+	pos    int
+}
+
+// waylandSocketPath returns the path to the Wayland socket, based on
+// WAYLAND_DISPLAY and XDG_RUNTIME_DIR, or an empty string
+func waylandSocketPath() string {
+	display := os.Getenv("WAYLAND_DISPLAY")
+	if display == "" {
+		display = "wayland-0"
+	}
+	if filepath.IsAbs(display) {
+		return display
+	}
+	runtimeDir := os.Getenv("XDG_RUNTIME_DIR")
+	if runtimeDir == "" {
+		return ""
+	}
+	return filepath.Join(runtimeDir, display)
+}
+
+// dialWayland connects to the Wayland server
+func dialWayland() (*wlConn, error) {
+	socketPath := waylandSocketPath()
+	if socketPath == "" {
+		return nil, errors.New("XDG_RUNTIME_DIR is not set")
+	}
+	conn, err := net.DialTimeout("unix", socketPath, time.Second)
+	if err != nil {
+		return nil, err
+	}
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+	// object ID 1 is always the wl_display
+	return &wlConn{conn: conn, nextID: 2}, nil
+}
+
+// newID returns a new object ID
+func (c *wlConn) newID() uint32 {
+	id := c.nextID
+	c.nextID++
+	return id
+}
+
+// request sends a request. The arguments can be uint32, int32 or string.
+func (c *wlConn) request(object uint32, opcode uint16, args ...interface{}) error {
+	var body []byte
+	for _, arg := range args {
+		switch v := arg.(type) {
+		case uint32:
+			body = binary.LittleEndian.AppendUint32(body, v)
+		case int32:
+			body = binary.LittleEndian.AppendUint32(body, uint32(v))
+		case string:
+			body = binary.LittleEndian.AppendUint32(body, uint32(len(v)+1))
+			body = append(body, v...)
+			body = append(body, 0)
+			for len(body)%4 != 0 {
+				body = append(body, 0)
+			}
+		default:
+			return fmt.Errorf("unsupported Wayland argument type: %T", arg)
+		}
+	}
+	msg := make([]byte, 8, 8+len(body))
+	binary.LittleEndian.PutUint32(msg, object)
+	binary.LittleEndian.PutUint32(msg[4:], uint32(8+len(body))<<16|uint32(opcode))
+	_, err := c.conn.Write(append(msg, body...))
+	return err
+}
+
+// readEvent reads the next event from the Wayland server
+func (c *wlConn) readEvent() (*wlEvent, error) {
+	header := make([]byte, 8)
+	if _, err := io.ReadFull(c.conn, header); err != nil {
+		return nil, err
+	}
+	sizeOpcode := binary.LittleEndian.Uint32(header[4:])
+	size := sizeOpcode >> 16
+	if size < 8 {
+		return nil, errors.New("invalid Wayland message size")
+	}
+	body := make([]byte, size-8)
+	if _, err := io.ReadFull(c.conn, body); err != nil {
+		return nil, err
+	}
+	return &wlEvent{object: binary.LittleEndian.Uint32(header), opcode: uint16(sizeOpcode), body: body}, nil
+}
+
+// roundtrip sends a wl_display.sync request and passes all events to the
+// given function, until the server is done with all previous requests
+func (c *wlConn) roundtrip(handle func(*wlEvent)) error {
+	callback := c.newID()
+	if err := c.request(1, 0, callback); err != nil { // wl_display.sync
+		return err
+	}
+	for {
+		e, err := c.readEvent()
+		if err != nil {
+			return err
+		}
+		switch {
+		case e.object == callback && e.opcode == 0: // wl_callback.done
+			return nil
+		case e.object == 1 && e.opcode == 0: // wl_display.error
+			object, code, message := e.uint(), e.uint(), e.string()
+			return fmt.Errorf("Wayland error %d for object %d: %s", code, object, message)
+		case e.object == 1: // wl_display.delete_id
+			continue
+		}
+		handle(e)
+	}
+}
+
+func (c *wlConn) Close() error {
+	return c.conn.Close()
+}
+
+func (e *wlEvent) uint() uint32 {
+	if e.pos+4 > len(e.body) {
+		return 0
+	}
+	v := binary.LittleEndian.Uint32(e.body[e.pos:])
+	e.pos += 4
+	return v
+}
+
+func (e *wlEvent) int() int32 {
+	return int32(e.uint())
+}
+
+func (e *wlEvent) string() string {
+	n := int(e.uint())
+	if n == 0 || e.pos+n > len(e.body) {
+		return ""
+	}
+	s := string(e.body[e.pos : e.pos+n-1]) // skip the trailing NUL
+	e.pos += (n + 3) / 4 * 4
+	return s
+}
+
+// wireWaylandMonitors connects to the Wayland server without using any C
+// libraries, and returns one Monitor struct per wl_output
+func wireWaylandMonitors() ([]Monitor, error) {
+	c, err := dialWayland()
+	if err != nil {
+		return nil, err
+	}
+	defer c.Close()
+
+	type global struct {
+		name, version uint32
+		iface         string
+	}
+
+	var (
+		globals  []global
+		registry = c.newID()
+	)
+	if err := c.request(1, 1, registry); err != nil { // wl_display.get_registry
+		return nil, err
+	}
+	if err := c.roundtrip(func(e *wlEvent) {
+		if e.object == registry && e.opcode == 0 { // wl_registry.global
+			name, iface, version := e.uint(), e.string(), e.uint()
+			globals = append(globals, global{name, version, iface})
+		}
+	}); err != nil {
+		return nil, err
+	}
+
+	var (
+		outputIDs     []uint32
+		outputs       = make(map[uint32]*waylandOutput)
+		xdgNames      = make(map[uint32]string)
+		xdgOutputs    = make(map[uint32]uint32) // xdg_output ID -> wl_output ID
+		outputManager uint32
+	)
+	for _, g := range globals {
+		switch g.iface {
+		case "wl_output":
+			// version 2 adds the scale event, version 4 adds the name and description events
+			id := c.newID()
+			if err := c.request(registry, 0, g.name, g.iface, min(g.version, 4), id); err != nil {
+				return nil, err
+			}
+			outputIDs = append(outputIDs, id)
+			outputs[id] = &waylandOutput{scale: 1}
+		case "zxdg_output_manager_v1":
+			if outputManager != 0 {
+				continue
+			}
+			outputManager = c.newID()
+			if err := c.request(registry, 0, g.name, g.iface, min(g.version, 3), outputManager); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if len(outputIDs) == 0 {
+		return []Monitor{}, nil
+	}
+
+	handle := func(e *wlEvent) {
+		if o, ok := outputs[e.object]; ok {
+			switch e.opcode {
+			case 0: // geometry
+				o.x, o.y = int(e.int()), int(e.int())
+				o.physW, o.physH = int(e.int()), int(e.int())
+				e.int() // subpixel
+				o.make, o.model = e.string(), e.string()
+				o.transform = int(e.int())
+			case 1: // mode
+				flags := e.uint()
+				width, height, refresh := e.int(), e.int(), e.int()
+				if flags&0x1 != 0 { // current mode
+					o.width, o.height, o.refresh = int(width), int(height), int(refresh)
+				}
+			case 3: // scale
+				o.scale = int(e.int())
+			case 4: // name
+				o.name = e.string()
+			}
+			return
+		}
+		if outputID, ok := xdgOutputs[e.object]; ok {
+			o := outputs[outputID]
+			switch e.opcode {
+			case 0: // logical_position
+				o.hasLogical = true
+				o.logicalX, o.logicalY = int(e.int()), int(e.int())
+			case 1: // logical_size
+				o.hasLogical = true
+				o.logicalW, o.logicalH = int(e.int()), int(e.int())
+			case 3: // name
+				xdgNames[outputID] = e.string()
+			}
+		}
+	}
+	if err := c.roundtrip(handle); err != nil {
+		return nil, err
+	}
+
+	// Ask for the logical position and size of all outputs, if possible
+	if outputManager != 0 {
+		for _, outputID := range outputIDs {
+			id := c.newID()
+			if err := c.request(outputManager, 1, id, outputID); err != nil { // get_xdg_output
+				return nil, err
+			}
+			xdgOutputs[id] = outputID
+		}
+		if err := c.roundtrip(handle); err != nil {
+			return nil, err
+		}
+	}
+
+	monitors := make([]Monitor, 0, len(outputIDs))
+	for i, outputID := range outputIDs {
+		o := outputs[outputID]
+		// wl_output.name is preferred, but is only available from wl_output version 4
+		if o.name == "" {
+			o.name = xdgNames[outputID]
+		}
+		monitors = append(monitors, o.monitor(uint(i)))
+	}
+	return monitors, nil
+}
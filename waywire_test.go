@@ -0,0 +1,47 @@
+package wallutils
+
+import "testing"
+
+func TestWireWaylandMonitors(t *testing.T) {
+	startFakeCompositor(t, true, twoFakeOutputs...)
+	monitors, err := wireWaylandMonitors()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(monitors) != 2 {
+		t.Fatalf("expected 2 monitors, got %d", len(monitors))
+	}
+	laptop, external := monitors[0], monitors[1]
+	if laptop.Name != "eDP-1" || laptop.Width != 2880 || laptop.Height != 1620 || laptop.Scale != 1.5 || laptop.Refresh != 60 {
+		t.Errorf("unexpected monitor: %+v", laptop)
+	}
+	if laptop.Make != "BOE" || laptop.Model != "0x0BCA" || laptop.DPIw != 235 {
+		t.Errorf("unexpected monitor: %+v", laptop)
+	}
+	if external.Name != "DP-1" || external.X != 1920 || external.Rotation != 90 || external.Scale != 1 || external.Refresh != 59.951 {
+		t.Errorf("unexpected monitor: %+v", external)
+	}
+}
+
+func TestWireWaylandMonitorsNoXDG(t *testing.T) {
+	startFakeCompositor(t, false, twoFakeOutputs...)
+	monitors, err := wireWaylandMonitors()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(monitors) != 2 {
+		t.Fatalf("expected 2 monitors, got %d", len(monitors))
+	}
+	// Without xdg_output, the integer scale and the wl_output position is used
+	if monitors[0].Scale != 2 || monitors[1].X != 1920 || monitors[1].Name != "DP-1" {
+		t.Errorf("unexpected monitors: %+v", monitors)
+	}
+}
+
+func TestWireWaylandNoServer(t *testing.T) {
+	t.Setenv("XDG_RUNTIME_DIR", t.TempDir())
+	t.Setenv("WAYLAND_DISPLAY", "wayland-wallutils")
+	if _, err := wireWaylandMonitors(); err == nil {
+		t.Error("expected an error when there is no Wayland server")
+	}
+}
@@ -1,8 +1,8 @@
 package wallutils
 
 import (
-	"errors"
 	"fmt"
+	"os"
 
 	"github.com/xyproto/env/v2"
 )
@@ -33,6 +33,18 @@ func (w *Weston) SetMode(mode string) {
 	w.mode = mode
 }
 
+// Capabilities returns the wallpaper modes that are supported by this backend,
+// if one wallpaper per output can be set and if the wallpaper is kept after
+// logging in again. Weston must be restarted before a new wallpaper is shown.
+func (w *Weston) Capabilities() Capabilities {
+	return Capabilities{
+		Modes:      []string{"scale", "scale-crop", "tile", "centered", "stretch", "stretched", "scaled", "fill", "zoom", "zoomed", "crop", "cropped", "tiled", "wallpaper", "center"},
+		PerOutput:  false,
+		Persistent: true,
+		Restart:    true,
+	}
+}
+
 // SetVerbose can be used for setting the verbose field to true or false.
 // This will cause this backend to output information about what is is doing on stdout.
 func (w *Weston) SetVerbose(verbose bool) {
@@ -41,20 +53,68 @@ func (w *Weston) SetVerbose(verbose bool) {
 
 // SetWallpaper sets the desktop wallpaper, given an image filename.
 // The image must exist and be readable.
-func (*Weston) SetWallpaper(imageFilename string) error {
+// The background-image and background-type settings in the [shell] section
+// of weston.ini are changed, and Weston must be restarted to show the new
+// wallpaper, since it only reads weston.ini when starting.
+func (w *Weston) SetWallpaper(imageFilename string) error {
 	if !exists(imageFilename) {
 		return fmt.Errorf("no such file: %s", imageFilename)
 	}
 
-	fmt.Println("WESTON CONFIG FILE: ", env.Str("WESTON_CONFIG_FILE"))
+	// initialize the mode setting (stretched/tiled etc)
+	mode := defaultMode
+	if w.mode != "" {
+		mode = w.mode
+	}
+
+	// possible values for background-type: "scale", "scale-crop", "tile", "centered"
+	switch mode {
+	case "scale", "scale-crop", "tile", "centered":
+		break
+	case "stretch", "stretched", "scaled":
+		mode = "scale"
+	case "fill", "zoom", "zoomed", "crop", "cropped":
+		mode = "scale-crop"
+	case "tiled", "wallpaper":
+		mode = "tile"
+	case "center":
+		mode = "centered"
+	default:
+		// Invalid and unrecognized desktop wallpaper mode
+		return fmt.Errorf("%w for Weston: %s", ErrUnsupportedMode, mode)
+	}
+
+	activeFilename := WestonConfigFilename()
+	ini, err := ReadWestonINI(activeFilename)
+	if os.IsNotExist(err) {
+		ini = ParseWestonINI(nil)
+	} else if err != nil {
+		return err
+	}
+
+	// If the active weston.ini file is system-wide, a copy is written to ~/.config
+	filename := userWestonConfigFilename(activeFilename)
 
-	// TODO: Add the following to ~/.config/weston.ini
-	//       (or whichever configuration file Weston uses)
-	// [shell]
-	// background-image=/home/user/somewhere/image.jpg
-	// background-type=scale
-	//
-	// Also use w.mode for setting the background type
+	ini.Set("shell", "background-image", imageFilename)
+	ini.Set("shell", "background-type", mode)
 
-	return errors.New("Weston currently does not support changing the desktop wallpaper at runtime")
+	if w.verbose {
+		fmt.Printf("Writing %s\n", filename)
+	}
+	// Weston only reads weston.ini when starting, see RestartHint
+	return ini.Write(filename)
+}
+
+// CurrentWallpaper returns the background-image setting from weston.ini
+func (w *Weston) CurrentWallpaper() (string, error) {
+	filename := WestonConfigFilename()
+	ini, err := ReadWestonINI(filename)
+	if err != nil {
+		return "", err
+	}
+	imageFilename, ok := ini.Get("shell", "background-image")
+	if !ok || imageFilename == "" {
+		return "", fmt.Errorf("no background-image in %s", filename)
+	}
+	return imageFilename, nil
 }
@@ -0,0 +1,205 @@
+package wallutils
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// WestonINI is a weston.ini configuration file. Only the lines that are
+// changed are touched, so that other sections, comments and blank lines
+// are kept as they are.
+type WestonINI struct {
+	lines []string
+}
+
+// ParseWestonINI parses the contents of a weston.ini file
+func ParseWestonINI(data []byte) *WestonINI {
+	text := strings.ReplaceAll(string(data), "\r\n", "\n")
+	text = strings.TrimSuffix(text, "\n")
+	if text == "" {
+		return &WestonINI{}
+	}
+	return &WestonINI{lines: strings.Split(text, "\n")}
+}
+
+// ReadWestonINI reads and parses the given weston.ini file
+func ReadWestonINI(filename string) (*WestonINI, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	return ParseWestonINI(data), nil
+}
+
+// Bytes returns the contents of the weston.ini file
+func (ini *WestonINI) Bytes() []byte {
+	if len(ini.lines) == 0 {
+		return []byte{}
+	}
+	return []byte(strings.Join(ini.lines, "\n") + "\n")
+}
+
+// Write writes the weston.ini file to the given filename. A temporary file
+// is written first, so that Weston never reads a partially written file.
+func (ini *WestonINI) Write(filename string) error {
+	perm := os.FileMode(0o644)
+	if fi, err := os.Stat(filename); err == nil {
+		perm = fi.Mode().Perm()
+	}
+	if err := os.MkdirAll(filepath.Dir(filename), 0o755); err != nil {
+		return err
+	}
+	tmpFilename := filename + ".tmp"
+	if err := os.WriteFile(tmpFilename, ini.Bytes(), perm); err != nil {
+		os.Remove(tmpFilename)
+		return err
+	}
+	return os.Rename(tmpFilename, filename)
+}
+
+// sectionName returns the section name if the given line is a section
+// header, like "[shell]"
+func sectionName(line string) (string, bool) {
+	line = strings.TrimSpace(line)
+	if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+		return strings.TrimSpace(line[1 : len(line)-1]), true
+	}
+	return "", false
+}
+
+// keyValue returns the key and value if the given line is a key=value line
+func keyValue(line string) (string, string, bool) {
+	trimmed := strings.TrimSpace(line)
+	if trimmed == "" || strings.HasPrefix(trimmed, "#") || strings.HasPrefix(trimmed, ";") {
+		return "", "", false
+	}
+	key, value, found := strings.Cut(trimmed, "=")
+	if !found {
+		return "", "", false
+	}
+	return strings.TrimSpace(key), strings.TrimSpace(value), true
+}
+
+// sectionRange returns the index of the header of the first section with
+// the given name, and the index after the last key=value line in that section.
+// -1 is returned if the section does not exist.
+func (ini *WestonINI) sectionRange(section string) (int, int) {
+	start := -1
+	end := -1
+	for i, line := range ini.lines {
+		if name, ok := sectionName(line); ok {
+			if start >= 0 {
+				break
+			}
+			if name == section {
+				start = i
+				end = i + 1
+			}
+			continue
+		}
+		if start >= 0 {
+			if _, _, ok := keyValue(line); ok {
+				end = i + 1
+			}
+		}
+	}
+	return start, end
+}
+
+// Get returns the value for the given key in the first section with the
+// given name, like "shell"
+func (ini *WestonINI) Get(section, key string) (string, bool) {
+	start, end := ini.sectionRange(section)
+	if start < 0 {
+		return "", false
+	}
+	for _, line := range ini.lines[start+1 : end] {
+		if k, v, ok := keyValue(line); ok && k == key {
+			return v, true
+		}
+	}
+	return "", false
+}
+
+// Set sets the value for the given key in the first section with the given
+// name. The line is changed in place if the key is already there, or added
+// after the last key in the section. If the section does not exist, it is
+// added at the end.
+func (ini *WestonINI) Set(section, key, value string) {
+	newLine := key + "=" + value
+	start, end := ini.sectionRange(section)
+	if start < 0 {
+		if len(ini.lines) > 0 && strings.TrimSpace(ini.lines[len(ini.lines)-1]) != "" {
+			ini.lines = append(ini.lines, "")
+		}
+		ini.lines = append(ini.lines, "["+section+"]", newLine)
+		return
+	}
+	for i := start + 1; i < end; i++ {
+		if k, _, ok := keyValue(ini.lines[i]); ok && k == key {
+			ini.lines[i] = newLine
+			return
+		}
+	}
+	ini.lines = append(ini.lines[:end], append([]string{newLine}, ini.lines[end:]...)...)
+}
+
+// westonConfigDirs returns the directories where Weston looks for
+// weston.ini, in order: $XDG_CONFIG_HOME (or ~/.config), then
+// the weston directory in each of $XDG_CONFIG_DIRS (or /etc/xdg)
+func westonConfigDirs() []string {
+	var dirs []string
+	if configHome := os.Getenv("XDG_CONFIG_HOME"); filepath.IsAbs(configHome) {
+		dirs = append(dirs, configHome)
+	} else if homeDir, err := os.UserHomeDir(); err == nil {
+		dirs = append(dirs, filepath.Join(homeDir, ".config"))
+	}
+	configDirs := os.Getenv("XDG_CONFIG_DIRS")
+	if configDirs == "" {
+		configDirs = "/etc/xdg"
+	}
+	for _, dir := range filepath.SplitList(configDirs) {
+		if filepath.IsAbs(dir) {
+			dirs = append(dirs, filepath.Join(dir, "weston"))
+		}
+	}
+	return dirs
+}
+
+// WestonConfigFilename returns the weston.ini file that is used by Weston.
+// WESTON_CONFIG_FILE is used if it is set, since Weston sets it for the
+// clients it starts. If no weston.ini file exists, the filename in
+// $XDG_CONFIG_HOME (or ~/.config) is returned.
+func WestonConfigFilename() string {
+	if filename := os.Getenv("WESTON_CONFIG_FILE"); filename != "" {
+		return filename
+	}
+	dirs := westonConfigDirs()
+	for _, dir := range dirs {
+		if filename := filepath.Join(dir, "weston.ini"); exists(filename) {
+			return filename
+		}
+	}
+	if len(dirs) == 0 {
+		return "weston.ini"
+	}
+	return filepath.Join(dirs[0], "weston.ini")
+}
+
+// userWestonConfigFilename returns the weston.ini file that should be
+// written to. If the active weston.ini file is a system-wide file that can
+// not be written to, the user weston.ini is returned instead, so that the
+// system-wide file can be copied there first.
+func userWestonConfigFilename(activeFilename string) string {
+	if f, err := os.OpenFile(activeFilename, os.O_WRONLY, 0); err == nil {
+		f.Close()
+		return activeFilename
+	}
+	if exists(activeFilename) {
+		if dirs := westonConfigDirs(); len(dirs) > 0 {
+			return filepath.Join(dirs[0], "weston.ini")
+		}
+	}
+	return activeFilename
+}
@@ -0,0 +1,161 @@
+package wallutils
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// westonINIExample is based on the weston.ini that comes with Weston
+const westonINIExample = `[core]
+#modules=cms-colord.so
+#xwayland=true
+#shell=desktop-shell.so
+#gbm-format=xrgb2101010
+#require-input=true
+
+[shell]
+background-image=/usr/share/backgrounds/gnome/Aqua.jpg
+background-color=0xff002244
+background-type=tile
+clock-format=minutes
+panel-color=0x90ff0000
+locking=true
+animation=zoom
+startup-animation=fade
+#binding-modifier=ctrl
+#num-workspaces=6
+#cursor-theme=whiteglass
+#cursor-size=24
+
+#lockscreen-icon=/usr/share/icons/gnome/256x256/actions/lock.png
+#lockscreen=/usr/share/backgrounds/gnome/Garden.jpg
+#homescreen=/usr/share/backgrounds/gnome/Blinds.jpg
+#animation=fade
+
+[launcher]
+icon=/usr/share/icons/gnome/24x24/apps/utilities-terminal.png
+path=/usr/bin/gnome-terminal
+
+[launcher]
+icon=/usr/share/icons/gnome/24x24/apps/utilities-terminal.png
+path=/usr/bin/weston-terminal
+
+[input-method]
+path=/usr/libexec/weston-keyboard
+
+[output]
+name=LVDS1
+mode=1680x1050
+transform=rotate-90
+
+[output]
+name=VGA1
+mode=173.00  1920 2048 2248 2576  1080 1083 1088 1120 -hsync +vsync
+transform=flipped
+
+; Only used if there is no [keyboard] section elsewhere
+[keyboard]
+keymap_rules=evdev
+#keymap_layout=gb,de
+#keymap_options=caps:ctrl_modifier,shift:both_capslock_cancel
+repeat-rate=30
+repeat-delay=300
+`
+
+// westonINIMinimal has no [shell] section and Windows line endings
+const westonINIMinimal = "# Generated by a distribution installer\r\n[core]\r\nidle-time=0\r\n"
+
+func TestWestonINIRoundTrip(t *testing.T) {
+	for _, example := range []string{westonINIExample, strings.ReplaceAll(westonINIMinimal, "\r\n", "\n"), ""} {
+		if got := string(ParseWestonINI([]byte(example)).Bytes()); got != example {
+			t.Errorf("the file changed when reading and writing it:\n%s", got)
+		}
+	}
+}
+
+func TestWestonINISet(t *testing.T) {
+	ini := ParseWestonINI([]byte(westonINIExample))
+	if imageFilename, ok := ini.Get("shell", "background-image"); !ok || imageFilename != "/usr/share/backgrounds/gnome/Aqua.jpg" {
+		t.Errorf("unexpected background-image: %s", imageFilename)
+	}
+	// A commented out setting is not used
+	if _, ok := ini.Get("shell", "lockscreen"); ok {
+		t.Error("did not expect to find a commented out setting")
+	}
+	ini.Set("shell", "background-image", "/home/user/a.png")
+	ini.Set("shell", "background-type", "scale-crop")
+	ini.Set("shell", "panel-position", "bottom")
+
+	// Only the changed lines differ, and the new key is added after the last key in [shell]
+	expected := strings.Replace(westonINIExample, "background-image=/usr/share/backgrounds/gnome/Aqua.jpg", "background-image=/home/user/a.png", 1)
+	expected = strings.Replace(expected, "background-type=tile", "background-type=scale-crop", 1)
+	expected = strings.Replace(expected, "startup-animation=fade\n", "startup-animation=fade\npanel-position=bottom\n", 1)
+	if got := string(ini.Bytes()); got != expected {
+		t.Errorf("unexpected weston.ini:\n%s", got)
+	}
+
+	// A [shell] section is added if there is none
+	ini = ParseWestonINI([]byte(westonINIMinimal))
+	ini.Set("shell", "background-image", "/home/user/a.png")
+	expected = "# Generated by a distribution installer\n[core]\nidle-time=0\n\n[shell]\nbackground-image=/home/user/a.png\n"
+	if got := string(ini.Bytes()); got != expected {
+		t.Errorf("unexpected weston.ini:\n%s", got)
+	}
+}
+
+func TestWestonSetWallpaper(t *testing.T) {
+	configHome := t.TempDir()
+	systemDir := t.TempDir()
+	t.Setenv("WESTON_CONFIG_FILE", "")
+	t.Setenv("XDG_CONFIG_HOME", configHome)
+	t.Setenv("XDG_CONFIG_DIRS", systemDir)
+
+	// The system-wide weston.ini is used if there is no user weston.ini
+	systemFilename := filepath.Join(systemDir, "weston", "weston.ini")
+	if err := os.MkdirAll(filepath.Dir(systemFilename), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(systemFilename, []byte(westonINIExample), 0o444); err != nil {
+		t.Fatal(err)
+	}
+	if filename := WestonConfigFilename(); filename != systemFilename {
+		t.Errorf("expected %s, got %s", systemFilename, filename)
+	}
+
+	imageFilename := filepath.Join(t.TempDir(), "a.png")
+	if err := os.WriteFile(imageFilename, []byte{}, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	w := &Weston{}
+	w.SetMode("fill")
+	if err := w.SetWallpaper(imageFilename); err != nil {
+		t.Fatal(err)
+	}
+
+	// Unless running as root, the system-wide file can not be written to,
+	// so it is copied to the user configuration directory first
+	filename := WestonConfigFilename()
+	ini, err := ReadWestonINI(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if value, _ := ini.Get("shell", "background-image"); value != imageFilename {
+		t.Errorf("unexpected background-image: %s", value)
+	}
+	if value, _ := ini.Get("shell", "background-type"); value != "scale-crop" {
+		t.Errorf("unexpected background-type: %s", value)
+	}
+	if value, _ := ini.Get("keyboard", "repeat-rate"); value != "30" {
+		t.Errorf("the other sections should be kept, got repeat-rate=%s", value)
+	}
+	if current, err := w.CurrentWallpaper(); err != nil || current != imageFilename {
+		t.Errorf("unexpected current wallpaper: %s, %v", current, err)
+	}
+
+	w.SetMode("spanned")
+	if err := w.SetWallpaper(imageFilename); err == nil {
+		t.Error("expected an error for a mode that Weston does not support")
+	}
+}
@@ -0,0 +1,294 @@
+package wallutils
+
+// This backend draws the wallpaper on the X11 root window by talking the
+// X11 protocol directly, so that neither feh nor libX11 is needed
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"image/draw"
+	"math/bits"
+	"sort"
+
+	"github.com/xyproto/env/v2"
+)
+
+// X11 is a structure containing settings for setting the wallpaper of the
+// X11 root window, for window managers that do not draw a wallpaper by themselves
+type X11 struct {
+	mode    string
+	verbose bool
+}
+
+// Name returns the name of this method of setting a wallpaper
+func (x *X11) Name() string {
+	return "X11"
+}
+
+// ExecutablesExists returns true, since no executables are needed
+func (x *X11) ExecutablesExists() bool {
+	return true
+}
+
+// Running checks if an X server is available, and that this is not a Wayland session
+func (x *X11) Running() bool {
+	return env.Has("DISPLAY") && !env.Has("WAYLAND_DISPLAY")
+}
+
+// SetMode will set the current way to display the wallpaper (stretched, tiled etc)
+func (x *X11) SetMode(mode string) {
+	x.mode = mode
+}
+
+// Capabilities returns the wallpaper modes that are supported by this backend,
+// if one wallpaper per output can be set and if the wallpaper is kept after
+// logging in again
+func (x *X11) Capabilities() Capabilities {
+	modes := make([]string, 0, len(renderModeAliases))
+	for mode := range renderModeAliases {
+		modes = append(modes, mode)
+	}
+	sort.Strings(modes)
+	return Capabilities{
+		Modes:      modes,
+		PerOutput:  false,
+		Persistent: false,
+		Formats:    ImageExtensions,
+	}
+}
+
+// SetVerbose can be used for setting the verbose field to true or false.
+// This will cause this backend to output information about what is is doing on stdout.
+func (x *X11) SetVerbose(verbose bool) {
+	x.verbose = verbose
+}
+
+// SetWallpaper sets the desktop wallpaper, given an image filename.
+// The image must exist and be readable. The image is rendered for each
+// monitor and drawn into a pixmap that is used as the background of the
+// root window. The pixmap is kept after this program exits.
+func (x *X11) SetWallpaper(imageFilename string) error {
+	if !exists(imageFilename) {
+		return fmt.Errorf("no such file: %s", imageFilename)
+	}
+	mode := defaultMode
+	if x.mode != "" {
+		mode = x.mode
+	}
+	if _, ok := renderModeAliases[mode]; !ok {
+		return fmt.Errorf("%w for X11: %s", ErrUnsupportedMode, mode)
+	}
+	c, err := dialX11()
+	if err != nil {
+		return fmt.Errorf("could not connect to the X server: %v: %w", err, ErrNotAvailable)
+	}
+	defer c.Close()
+	s := c.screens[c.screen]
+	img, err := OpenImage(imageFilename, uint(s.widthPx), uint(s.heightPx))
+	if err != nil {
+		return err
+	}
+	canvas, err := c.renderRoot(img, mode)
+	if err != nil {
+		return err
+	}
+	if x.verbose {
+		fmt.Printf("Drawing %s on the X11 root window (%dx%d)\n", imageFilename, canvas.Bounds().Dx(), canvas.Bounds().Dy())
+	}
+	return c.setRootPixmap(canvas)
+}
+
+// renderRoot renders the image once per monitor, on a canvas that has
+// the size of the root window
+func (c *x11Conn) renderRoot(img image.Image, mode string) (*image.RGBA, error) {
+	s := c.screens[c.screen]
+	canvas := image.NewRGBA(image.Rect(0, 0, int(s.widthPx), int(s.heightPx)))
+	monitors, err := c.randrMonitors()
+	if err != nil || len(monitors) == 0 {
+		monitors = []Monitor{{Width: uint(s.widthPx), Height: uint(s.heightPx)}}
+	}
+	for _, mon := range monitors {
+		rendered, err := Render(img, mon.Width, mon.Height, mode)
+		if err != nil {
+			return nil, err
+		}
+		r := image.Rect(mon.X, mon.Y, mon.X+int(mon.Width), mon.Y+int(mon.Height))
+		draw.Draw(canvas, r, rendered, image.Point{}, draw.Src)
+	}
+	return canvas, nil
+}
+
+// encodePixels returns the pixels of the given image as ZPixmap image data
+// for the given pixmap format and TrueColor visual
+func (c *x11Conn) encodePixels(img *image.RGBA, format x11Format, s x11Screen) []byte {
+	// colorValue scales an 8 bit color value to the given mask
+	colorValue := func(v uint8, mask uint32) uint32 {
+		shift := bits.TrailingZeros32(mask)
+		width := bits.OnesCount32(mask)
+		if width >= 8 {
+			return uint32(v) << (shift + width - 8) & mask
+		}
+		return uint32(v) >> (8 - width) << shift
+	}
+	bytesPerPixel := int(format.bitsPerPixel) / 8
+	w, h := img.Bounds().Dx(), img.Bounds().Dy()
+	pad := int(format.scanlinePad) / 8
+	stride := w * bytesPerPixel
+	if pad > 0 {
+		stride = (stride + pad - 1) / pad * pad
+	}
+	data := make([]byte, stride*h)
+	for y := 0; y < h; y++ {
+		row := data[y*stride:]
+		for x := 0; x < w; x++ {
+			i := img.PixOffset(img.Bounds().Min.X+x, img.Bounds().Min.Y+y)
+			r, g, b := img.Pix[i], img.Pix[i+1], img.Pix[i+2]
+			pixel := colorValue(r, s.redMask) | colorValue(g, s.greenMask) | colorValue(b, s.blueMask)
+			if bytesPerPixel == 4 {
+				c.imageOrder.PutUint32(row[x*4:], pixel)
+			} else {
+				c.imageOrder.PutUint16(row[x*2:], uint16(pixel))
+			}
+		}
+	}
+	return data
+}
+
+// pixmapProperty returns the pixmap ID in the given property of the window,
+// or 0 if the property is not set
+func (c *x11Conn) pixmapProperty(window, property uint32) (uint32, error) {
+	const pixmapType = 20
+	body := x11Order.AppendUint32(nil, window)
+	body = x11Order.AppendUint32(body, property)
+	body = x11Order.AppendUint32(body, pixmapType)
+	body = x11Order.AppendUint32(body, 0) // offset
+	body = x11Order.AppendUint32(body, 1) // length
+	reply, err := c.request(20, 0, body)  // GetProperty
+	if err != nil {
+		return 0, err
+	}
+	if len(reply) < 36 || reply[1] != 32 || x11Order.Uint32(reply[8:]) != pixmapType || x11Order.Uint32(reply[16:]) != 1 {
+		return 0, nil
+	}
+	return x11Order.Uint32(reply[32:]), nil
+}
+
+// setRootPixmap draws the image into a new pixmap, and uses it as the
+// background of the root window. The pixmap is kept when the connection is
+// closed, and is set as _XROOTPMAP_ID and ESETROOT_PMAP_ID, so that
+// compositors and terminals with pseudo-transparency can find it. The
+// previous pixmap is freed, if it was set the same way.
+func (c *x11Conn) setRootPixmap(img *image.RGBA) error {
+	if c.screen >= len(c.screens) {
+		return errors.New("no X11 screen")
+	}
+	s := c.screens[c.screen]
+	format, ok := c.formats[s.rootDepth]
+	if !ok || (format.bitsPerPixel != 16 && format.bitsPerPixel != 32) {
+		return fmt.Errorf("unsupported X11 root window depth: %d", s.rootDepth)
+	}
+	if s.redMask == 0 || s.greenMask == 0 || s.blueMask == 0 {
+		return errors.New("the X11 root window does not use a TrueColor visual")
+	}
+	w, h := img.Bounds().Dx(), img.Bounds().Dy()
+	data := c.encodePixels(img, format, s)
+	stride := len(data) / h
+
+	// Keep the pixmap after the connection is closed
+	const retainPermanent = 1
+	if err := c.send(112, retainPermanent, nil); err != nil { // SetCloseDownMode
+		return err
+	}
+	pixmap := c.newID()
+	body := x11Order.AppendUint32(nil, pixmap)
+	body = x11Order.AppendUint32(body, s.root)
+	body = x11Order.AppendUint16(body, uint16(w))
+	body = x11Order.AppendUint16(body, uint16(h))
+	if err := c.send(53, s.rootDepth, body); err != nil { // CreatePixmap
+		return err
+	}
+	gc := c.newID()
+	body = x11Order.AppendUint32(nil, gc)
+	body = x11Order.AppendUint32(body, pixmap)
+	body = x11Order.AppendUint32(body, 0)       // no values
+	if err := c.send(55, 0, body); err != nil { // CreateGC
+		return err
+	}
+
+	// Send the image data in strips of rows that fit within the maximum request length
+	const putImageHeader = 24
+	rowsPerRequest := (c.maxRequest - putImageHeader) / stride
+	if rowsPerRequest < 1 {
+		return fmt.Errorf("the root window is too wide for the X server: %d pixels", w)
+	}
+	const zPixmap = 2
+	for y := 0; y < h; y += rowsPerRequest {
+		rows := min(rowsPerRequest, h-y)
+		body = x11Order.AppendUint32(nil, pixmap)
+		body = x11Order.AppendUint32(body, gc)
+		body = x11Order.AppendUint16(body, uint16(w))
+		body = x11Order.AppendUint16(body, uint16(rows))
+		body = x11Order.AppendUint16(body, 0)         // x
+		body = x11Order.AppendUint16(body, uint16(y)) // y
+		body = append(body, 0, s.rootDepth, 0, 0)     // left pad, depth
+		body = append(body, data[y*stride:(y+rows)*stride]...)
+		if err := c.send(72, zPixmap, body); err != nil { // PutImage
+			return err
+		}
+	}
+	if err := c.send(60, 0, x11Order.AppendUint32(nil, gc)); err != nil { // FreeGC
+		return err
+	}
+	if err := c.sync(); err != nil {
+		return fmt.Errorf("could not draw the wallpaper: %v", err)
+	}
+
+	// Free the previous wallpaper pixmap, if it was set by feh, xsetroot, hsetroot or wallutils
+	rootAtom, err := c.internAtom("_XROOTPMAP_ID", true)
+	if err != nil {
+		return err
+	}
+	esetrootAtom, err := c.internAtom("ESETROOT_PMAP_ID", true)
+	if err != nil {
+		return err
+	}
+	if oldPixmap, err := c.pixmapProperty(s.root, rootAtom); err == nil && oldPixmap != 0 {
+		if oldEsetroot, err := c.pixmapProperty(s.root, esetrootAtom); err == nil && oldEsetroot == oldPixmap {
+			if err := c.send(113, 0, x11Order.AppendUint32(nil, oldPixmap)); err != nil { // KillClient
+				return err
+			}
+			// The pixmap may already be gone, which is fine
+			_ = c.sync()
+		}
+	}
+
+	const pixmapType = 20
+	for _, atom := range []uint32{rootAtom, esetrootAtom} {
+		body = x11Order.AppendUint32(nil, s.root)
+		body = x11Order.AppendUint32(body, atom)
+		body = x11Order.AppendUint32(body, pixmapType)
+		body = append(body, 32, 0, 0, 0)            // format
+		body = x11Order.AppendUint32(body, 1)       // length
+		body = x11Order.AppendUint32(body, pixmap)  // data
+		if err := c.send(18, 0, body); err != nil { // ChangeProperty, replace
+			return err
+		}
+	}
+	const cwBackPixmap = 1
+	body = x11Order.AppendUint32(nil, s.root)
+	body = x11Order.AppendUint32(body, cwBackPixmap)
+	body = x11Order.AppendUint32(body, pixmap)
+	if err := c.send(2, 0, body); err != nil { // ChangeWindowAttributes
+		return err
+	}
+	body = x11Order.AppendUint32(nil, s.root)
+	body = append(body, make([]byte, 8)...)     // the whole window
+	if err := c.send(61, 0, body); err != nil { // ClearArea
+		return err
+	}
+	if err := c.sync(); err != nil {
+		return fmt.Errorf("could not set the root window background: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,154 @@
+package wallutils
+
+import (
+	"bufio"
+	"errors"
+	"image/color"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/anthonynsimon/bild/imgio"
+)
+
+// writeRedBlueImage writes a PNG image where the left half is red and the
+// right half is blue, and returns the filename
+func writeRedBlueImage(t *testing.T, w, h int) string {
+	t.Helper()
+	filename := filepath.Join(t.TempDir(), "redblue.png")
+	if err := imgio.Save(filename, redBlueImage(w, h), imgio.PNGEncoder()); err != nil {
+		t.Fatal(err)
+	}
+	return filename
+}
+
+func TestX11SetWallpaper(t *testing.T) {
+	fx := startFakeXServer(t, false)
+	fx.mu.Lock()
+	fx.width, fx.height = 64, 40
+	// A wallpaper that was set by feh before
+	fx.atoms["_XROOTPMAP_ID"], fx.atoms["ESETROOT_PMAP_ID"] = 0x70, 0x71
+	fx.properties[0x70], fx.properties[0x71] = 0x1234, 0x1234
+	fx.mu.Unlock()
+
+	x := &X11{}
+	x.SetMode("stretch")
+	if err := x.SetWallpaper(writeRedBlueImage(t, 32, 20)); err != nil {
+		t.Fatal(err)
+	}
+	fx.mu.Lock()
+	defer fx.mu.Unlock()
+	if fx.closeDownMode != 1 {
+		t.Error("expected the close down mode to be RetainPermanent")
+	}
+	pixmap := fx.background
+	img, ok := fx.pixmaps[pixmap]
+	if !ok {
+		t.Fatalf("the root window background is not a pixmap that was created: %x", pixmap)
+	}
+	if fx.properties[0x70] != pixmap || fx.properties[0x71] != pixmap {
+		t.Errorf("expected _XROOTPMAP_ID and ESETROOT_PMAP_ID to be %x, got %x and %x", pixmap, fx.properties[0x70], fx.properties[0x71])
+	}
+	if len(fx.killed) != 1 || fx.killed[0] != 0x1234 {
+		t.Errorf("expected the previous pixmap to be freed, got %x", fx.killed)
+	}
+	if fx.putImages < 2 {
+		t.Errorf("expected the image to be sent in several requests, got %d", fx.putImages)
+	}
+	expectColor(t, img, 5, 20, red)
+	expectColor(t, img, 60, 20, blue)
+}
+
+func TestX11SetWallpaperMode(t *testing.T) {
+	x := &X11{}
+	x.SetMode("spanned")
+	if err := x.SetWallpaper(writeRedBlueImage(t, 4, 2)); !errors.Is(err, ErrUnsupportedMode) {
+		t.Errorf("expected ErrUnsupportedMode, got %v", err)
+	}
+}
+
+func TestX11SetWallpaperXvfb(t *testing.T) {
+	xvfb, err := exec.LookPath("Xvfb")
+	if err != nil {
+		t.Skip("Xvfb is not installed")
+	}
+	// Let Xvfb pick a free display number, and write it to a pipe
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	cmd := exec.Command(xvfb, "-displayfd", "3", "-screen", "0", "320x200x24", "-nolisten", "tcp")
+	cmd.ExtraFiles = []*os.File{w}
+	if err := cmd.Start(); err != nil {
+		t.Fatal(err)
+	}
+	w.Close()
+	t.Cleanup(func() {
+		cmd.Process.Kill()
+		cmd.Wait()
+	})
+	number, err := bufio.NewReader(r).ReadString('\n')
+	if err != nil {
+		t.Fatalf("could not start Xvfb: %v", err)
+	}
+	t.Setenv("DISPLAY", ":"+strings.TrimSpace(number))
+	t.Setenv("WAYLAND_DISPLAY", "")
+
+	x := &X11{}
+	x.SetMode("stretch")
+	for i := 0; i < 2; i++ { // the second time, the first pixmap is freed
+		if err := x.SetWallpaper(writeRedBlueImage(t, 32, 20)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	c, err := dialX11()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	root := c.screens[c.screen].root
+	rootAtom, err := c.internAtom("_XROOTPMAP_ID", false)
+	if err != nil || rootAtom == 0 {
+		t.Fatalf("_XROOTPMAP_ID does not exist: %v", err)
+	}
+	esetrootAtom, err := c.internAtom("ESETROOT_PMAP_ID", false)
+	if err != nil || esetrootAtom == 0 {
+		t.Fatalf("ESETROOT_PMAP_ID does not exist: %v", err)
+	}
+	pixmap, err := c.pixmapProperty(root, rootAtom)
+	if err != nil || pixmap == 0 {
+		t.Fatalf("_XROOTPMAP_ID is not set: %v", err)
+	}
+	if esetroot, err := c.pixmapProperty(root, esetrootAtom); err != nil || esetroot != pixmap {
+		t.Errorf("expected ESETROOT_PMAP_ID to be %x, got %x (%v)", pixmap, esetroot, err)
+	}
+
+	// Read two pixels from the pixmap, which is still there after the connection that created it was closed
+	for _, tc := range []struct {
+		x, y     int
+		expected color.RGBA
+	}{
+		{10, 100, red},
+		{310, 100, blue},
+	} {
+		body := x11Order.AppendUint32(nil, pixmap)
+		body = x11Order.AppendUint16(body, uint16(tc.x))
+		body = x11Order.AppendUint16(body, uint16(tc.y))
+		body = x11Order.AppendUint16(body, 1)
+		body = x11Order.AppendUint16(body, 1)
+		body = x11Order.AppendUint32(body, 0xffffffff) // plane mask
+		reply, err := c.request(73, 2, body)           // GetImage, ZPixmap
+		if err != nil {
+			t.Fatal(err)
+		}
+		v := c.imageOrder.Uint32(reply[32:])
+		got := color.RGBA{uint8(v >> 16), uint8(v >> 8), uint8(v), 255}
+		if got != tc.expected {
+			t.Errorf("pixel at %d,%d: expected %v, got %v", tc.x, tc.y, tc.expected, got)
+		}
+	}
+}
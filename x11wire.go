@@ -0,0 +1,577 @@
+package wallutils
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// x11Conn is a minimal client for the X11 protocol, that only knows enough
+// to list the outputs with the RandR extension. No C libraries are needed.
+type x11Conn struct {
+	conn       net.Conn
+	seq        uint16
+	screens    []x11Screen
+	screen     int // the default screen
+	ridBase    uint32
+	ridMask    uint32
+	nextID     uint32
+	maxRequest int                // the maximum request length, in bytes
+	formats    map[byte]x11Format // pixmap formats, by depth
+	imageOrder binary.ByteOrder   // the byte order of image data
+}
+
+// x11Screen contains information about a screen, from the connection setup
+type x11Screen struct {
+	root              uint32
+	widthPx, heightPx uint16
+	widthMM, heightMM uint16
+	rootVisual        uint32
+	rootDepth         byte
+	redMask           uint32 // the color masks of the root visual,
+	greenMask         uint32 // if it is a TrueColor visual
+	blueMask          uint32
+}
+
+// x11Format is a pixmap format, from the connection setup
+type x11Format struct {
+	bitsPerPixel byte
+	scanlinePad  byte
+}
+
+// x11 byte order, the client decides, and "l" is sent when connecting
+var x11Order = binary.LittleEndian
+
+// parseXDisplay parses a DISPLAY string, like ":0", "unix:0.1",
+// "localhost:10.0" or "/path/to/socket:0", and returns the network, the
+// address to dial, the display number and the screen number
+func parseXDisplay(display string) (network, address, number string, screen int, err error) {
+	i := strings.LastIndex(display, ":")
+	if i < 0 {
+		return "", "", "", 0, fmt.Errorf("invalid DISPLAY: %q", display)
+	}
+	host, rest := display[:i], display[i+1:]
+	number = rest
+	if j := strings.Index(rest, "."); j >= 0 {
+		number = rest[:j]
+		if screen, err = strconv.Atoi(rest[j+1:]); err != nil {
+			return "", "", "", 0, fmt.Errorf("invalid screen number in DISPLAY: %q", display)
+		}
+	}
+	n, err := strconv.Atoi(number)
+	if err != nil {
+		return "", "", "", 0, fmt.Errorf("invalid display number in DISPLAY: %q", display)
+	}
+	switch {
+	case strings.HasPrefix(host, "/"):
+		// A path to a socket, as used by XQuartz
+		return "unix", host + ":" + number, number, screen, nil
+	case host == "" || host == "unix":
+		return "unix", "/tmp/.X11-unix/X" + number, number, screen, nil
+	}
+	return "tcp", net.JoinHostPort(host, strconv.Itoa(6000+n)), number, screen, nil
+}
+
+// xauthCookie returns the MIT-MAGIC-COOKIE-1 for the given display number
+// from the Xauthority file, or nil if it is not found
+func xauthCookie(number string) []byte {
+	filename := os.Getenv("XAUTHORITY")
+	if filename == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return nil
+		}
+		filename = filepath.Join(home, ".Xauthority")
+	}
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil
+	}
+	hostname, _ := os.Hostname()
+	r := bytes.NewReader(data)
+	readField := func() ([]byte, error) {
+		var n uint16
+		if err := binary.Read(r, binary.BigEndian, &n); err != nil {
+			return nil, err
+		}
+		b := make([]byte, n)
+		_, err := io.ReadFull(r, b)
+		return b, err
+	}
+	for {
+		var family uint16
+		if err := binary.Read(r, binary.BigEndian, &family); err != nil {
+			return nil
+		}
+		address, err := readField()
+		if err != nil {
+			return nil
+		}
+		num, err := readField()
+		if err != nil {
+			return nil
+		}
+		name, err := readField()
+		if err != nil {
+			return nil
+		}
+		cookie, err := readField()
+		if err != nil {
+			return nil
+		}
+		const familyLocal, familyWild = 256, 65535
+		if string(name) != "MIT-MAGIC-COOKIE-1" || (len(num) > 0 && string(num) != number) {
+			continue
+		}
+		if family == familyWild || (family == familyLocal && string(address) == hostname) {
+			return cookie
+		}
+	}
+}
+
+// pad4 returns the number of bytes needed to pad n to a multiple of 4
+func pad4(n int) int {
+	return (4 - n%4) % 4
+}
+
+// dialX11 connects to the X server given by DISPLAY
+func dialX11() (*x11Conn, error) {
+	display := os.Getenv("DISPLAY")
+	if display == "" {
+		return nil, errors.New("DISPLAY is not set")
+	}
+	network, address, number, screen, err := parseXDisplay(display)
+	if err != nil {
+		return nil, err
+	}
+	conn, err := net.DialTimeout(network, address, time.Second)
+	if err != nil {
+		return nil, err
+	}
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+
+	var authName, authData []byte
+	if cookie := xauthCookie(number); cookie != nil {
+		authName, authData = []byte("MIT-MAGIC-COOKIE-1"), cookie
+	}
+	setup := []byte{'l', 0}
+	setup = x11Order.AppendUint16(setup, 11) // protocol major version
+	setup = x11Order.AppendUint16(setup, 0)  // protocol minor version
+	setup = x11Order.AppendUint16(setup, uint16(len(authName)))
+	setup = x11Order.AppendUint16(setup, uint16(len(authData)))
+	setup = append(setup, 0, 0)
+	setup = append(setup, authName...)
+	setup = append(setup, make([]byte, pad4(len(authName)))...)
+	setup = append(setup, authData...)
+	setup = append(setup, make([]byte, pad4(len(authData)))...)
+	if _, err := conn.Write(setup); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	header := make([]byte, 8)
+	if _, err := io.ReadFull(conn, header); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	body := make([]byte, int(x11Order.Uint16(header[6:]))*4)
+	if _, err := io.ReadFull(conn, body); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	if header[0] != 1 {
+		conn.Close()
+		reason := strings.TrimSpace(string(body[:min(int(header[1]), len(body))]))
+		return nil, fmt.Errorf("could not connect to the X server: %s", reason)
+	}
+
+	c := &x11Conn{conn: conn, screen: screen, formats: make(map[byte]x11Format)}
+	if len(body) < 32 {
+		conn.Close()
+		return nil, errors.New("invalid X11 connection setup")
+	}
+	c.ridBase = x11Order.Uint32(body[4:])
+	c.ridMask = x11Order.Uint32(body[8:])
+	c.maxRequest = int(x11Order.Uint16(body[18:])) * 4
+	c.imageOrder = binary.ByteOrder(binary.LittleEndian)
+	if body[22] == 1 { // MSBFirst
+		c.imageOrder = binary.BigEndian
+	}
+	vendorLen := int(x11Order.Uint16(body[16:]))
+	numScreens := int(body[20])
+	numFormats := int(body[21])
+	pos := 32 + vendorLen + pad4(vendorLen)
+	for i := 0; i < numFormats && pos+8 <= len(body); i++ {
+		c.formats[body[pos]] = x11Format{bitsPerPixel: body[pos+1], scanlinePad: body[pos+2]}
+		pos += 8
+	}
+	for i := 0; i < numScreens && pos+40 <= len(body); i++ {
+		s := body[pos:]
+		screen := x11Screen{
+			root:       x11Order.Uint32(s),
+			widthPx:    x11Order.Uint16(s[20:]),
+			heightPx:   x11Order.Uint16(s[22:]),
+			widthMM:    x11Order.Uint16(s[24:]),
+			heightMM:   x11Order.Uint16(s[26:]),
+			rootVisual: x11Order.Uint32(s[32:]),
+			rootDepth:  s[38],
+		}
+		// find the color masks of the root visual in the allowed depths
+		numDepths := int(s[39])
+		pos += 40
+		for j := 0; j < numDepths && pos+8 <= len(body); j++ {
+			numVisuals := int(x11Order.Uint16(body[pos+2:]))
+			pos += 8
+			for k := 0; k < numVisuals && pos+24 <= len(body); k++ {
+				v := body[pos:]
+				const trueColor = 4
+				if x11Order.Uint32(v) == screen.rootVisual && v[4] == trueColor {
+					screen.redMask = x11Order.Uint32(v[8:])
+					screen.greenMask = x11Order.Uint32(v[12:])
+					screen.blueMask = x11Order.Uint32(v[16:])
+				}
+				pos += 24
+			}
+		}
+		c.screens = append(c.screens, screen)
+	}
+	if len(c.screens) == 0 {
+		conn.Close()
+		return nil, errors.New("the X server has no screens")
+	}
+	if c.screen >= len(c.screens) {
+		c.screen = 0
+	}
+	return c, nil
+}
+
+func (c *x11Conn) Close() error {
+	return c.conn.Close()
+}
+
+// send sends a request that has no reply
+func (c *x11Conn) send(opcode, data byte, body []byte) error {
+	body = append(body, make([]byte, pad4(len(body)))...)
+	msg := []byte{opcode, data}
+	msg = x11Order.AppendUint16(msg, uint16((4+len(body))/4))
+	if _, err := c.conn.Write(append(msg, body...)); err != nil {
+		return err
+	}
+	c.seq++
+	return nil
+}
+
+// request sends a request and returns the reply, including the 32 byte header.
+// Errors for earlier requests that have no reply are also returned.
+func (c *x11Conn) request(opcode, data byte, body []byte) ([]byte, error) {
+	if err := c.send(opcode, data, body); err != nil {
+		return nil, err
+	}
+	var firstErr error
+	for {
+		reply := make([]byte, 32)
+		if _, err := io.ReadFull(c.conn, reply); err != nil {
+			return nil, err
+		}
+		switch reply[0] {
+		case 0: // error
+			err := fmt.Errorf("X11 error %d for request %d", reply[1], reply[10])
+			if x11Order.Uint16(reply[2:]) == c.seq {
+				return nil, err
+			}
+			if firstErr == nil {
+				firstErr = err
+			}
+		case 1: // reply
+			extra := make([]byte, int(x11Order.Uint32(reply[4:]))*4)
+			if _, err := io.ReadFull(c.conn, extra); err != nil {
+				return nil, err
+			}
+			if x11Order.Uint16(reply[2:]) == c.seq {
+				if firstErr != nil {
+					return nil, firstErr
+				}
+				return append(reply, extra...), nil
+			}
+		}
+		// an event, or a reply to an earlier request, skip it
+	}
+}
+
+// sync waits until the X server has handled all requests, and returns
+// the first error, if any of them failed
+func (c *x11Conn) sync() error {
+	_, err := c.request(43, 0, nil) // GetInputFocus
+	return err
+}
+
+// newID returns a new resource ID, for creating a pixmap or a GC
+func (c *x11Conn) newID() uint32 {
+	c.nextID++
+	return c.ridBase | (c.nextID & c.ridMask)
+}
+
+// queryExtension returns the major opcode for the given extension
+func (c *x11Conn) queryExtension(name string) (byte, error) {
+	body := x11Order.AppendUint16(nil, uint16(len(name)))
+	body = append(body, 0, 0)
+	body = append(body, name...)
+	reply, err := c.request(98, 0, body) // QueryExtension
+	if err != nil {
+		return 0, err
+	}
+	if reply[8] == 0 {
+		return 0, fmt.Errorf("the X server does not support the %s extension", name)
+	}
+	return reply[9], nil
+}
+
+// randr request numbers
+const (
+	rrQueryVersion              = 0
+	rrGetScreenResources        = 8
+	rrGetOutputInfo             = 9
+	rrGetOutputProperty         = 15
+	rrGetCrtcInfo               = 20
+	rrGetScreenResourcesCurrent = 25
+	rrGetOutputPrimary          = 31
+)
+
+// internAtom returns the atom for the given name. If create is false and the
+// atom does not exist, 0 is returned.
+func (c *x11Conn) internAtom(name string, create bool) (uint32, error) {
+	onlyIfExists := byte(1)
+	if create {
+		onlyIfExists = 0
+	}
+	body := x11Order.AppendUint16(nil, uint16(len(name)))
+	body = append(body, 0, 0)
+	body = append(body, name...)
+	reply, err := c.request(16, onlyIfExists, body) // InternAtom
+	if err != nil {
+		return 0, err
+	}
+	return x11Order.Uint32(reply[8:]), nil
+}
+
+// outputEDID returns the EDID property of the given RandR output
+func (c *x11Conn) outputEDID(major byte, output, edidAtom uint32) ([]byte, error) {
+	body := x11Order.AppendUint32(nil, output)
+	body = x11Order.AppendUint32(body, edidAtom)
+	body = x11Order.AppendUint32(body, 0)   // any property type
+	body = x11Order.AppendUint32(body, 0)   // offset
+	body = x11Order.AppendUint32(body, 128) // length, in 4 byte units
+	body = append(body, 0, 0, 0, 0)         // delete, pending and padding
+	reply, err := c.request(major, rrGetOutputProperty, body)
+	if err != nil {
+		return nil, err
+	}
+	if reply[1] != 8 { // format
+		return nil, errors.New("no EDID for this output")
+	}
+	n := int(x11Order.Uint32(reply[16:]))
+	if len(reply) < 32+n {
+		return nil, errors.New("invalid RandR output property reply")
+	}
+	return reply[32 : 32+n], nil
+}
+
+// randrModeInfo is a mode, as returned by RRGetScreenResources
+type randrModeInfo struct {
+	id            uint32
+	width, height uint16
+	dotClock      uint32
+	hTotal        uint16
+	vTotal        uint16
+	flags         uint32
+}
+
+// refresh returns the refresh rate of the mode, in Hz
+func (m randrModeInfo) refresh() float64 {
+	if m.hTotal == 0 || m.vTotal == 0 {
+		return 0
+	}
+	vTotal := float64(m.vTotal)
+	if m.flags&0x20 != 0 { // double scan
+		vTotal *= 2
+	}
+	if m.flags&0x10 != 0 { // interlace
+		vTotal /= 2
+	}
+	return float64(m.dotClock) / (float64(m.hTotal) * vTotal)
+}
+
+// randrRotation converts a RandR rotation to degrees counter-clockwise
+func randrRotation(rotation uint16) uint {
+	switch {
+	case rotation&2 != 0:
+		return 90
+	case rotation&4 != 0:
+		return 180
+	case rotation&8 != 0:
+		return 270
+	}
+	return 0
+}
+
+// x11Monitors connects to the X server without using any C libraries, and
+// returns one Monitor struct per output that is in use. If RandR is not
+// available, one Monitor struct is returned per X screen.
+func x11Monitors() ([]Monitor, error) {
+	c, err := dialX11()
+	if err != nil {
+		return nil, err
+	}
+	defer c.Close()
+
+	if monitors, err := c.randrMonitors(); err == nil && len(monitors) > 0 {
+		return monitors, nil
+	}
+
+	monitors := make([]Monitor, 0, len(c.screens))
+	for i, s := range c.screens {
+		mon := Monitor{ID: uint(i), Width: uint(s.widthPx), Height: uint(s.heightPx), Scale: 1, Primary: i == c.screen}
+		if s.widthMM > 0 && s.heightMM > 0 {
+			mon.DPIw = uint(float64(s.widthPx) / (float64(s.widthMM) / 25.4))
+			mon.DPIh = uint(float64(s.heightPx) / (float64(s.heightMM) / 25.4))
+			mon.DPISource = DPIFromServer
+		}
+		monitors = append(monitors, mon)
+	}
+	return monitors, nil
+}
+
+// randrMonitors returns one Monitor struct per RandR output that is in use
+func (c *x11Conn) randrMonitors() ([]Monitor, error) {
+	major, err := c.queryExtension("RANDR")
+	if err != nil {
+		return nil, err
+	}
+	body := x11Order.AppendUint32(nil, 1)
+	body = x11Order.AppendUint32(body, 5)
+	reply, err := c.request(major, rrQueryVersion, body)
+	if err != nil {
+		return nil, err
+	}
+	serverMajor, serverMinor := x11Order.Uint32(reply[8:]), x11Order.Uint32(reply[12:])
+	if serverMajor < 1 || (serverMajor == 1 && serverMinor < 2) {
+		return nil, errors.New("RandR 1.2 or later is needed")
+	}
+	newer := serverMajor > 1 || serverMinor >= 3
+
+	root := c.screens[c.screen].root
+	getResources := byte(rrGetScreenResources)
+	if newer {
+		getResources = rrGetScreenResourcesCurrent
+	}
+	reply, err = c.request(major, getResources, x11Order.AppendUint32(nil, root))
+	if err != nil {
+		return nil, err
+	}
+	configTimestamp := x11Order.Uint32(reply[12:])
+	numCrtcs := int(x11Order.Uint16(reply[16:]))
+	numOutputs := int(x11Order.Uint16(reply[18:]))
+	numModes := int(x11Order.Uint16(reply[20:]))
+	pos := 32 + numCrtcs*4
+	if len(reply) < pos+numOutputs*4+numModes*32 {
+		return nil, errors.New("invalid RandR screen resources reply")
+	}
+	outputs := make([]uint32, numOutputs)
+	for i := range outputs {
+		outputs[i] = x11Order.Uint32(reply[pos+i*4:])
+	}
+	pos += numOutputs * 4
+	modes := make(map[uint32]randrModeInfo, numModes)
+	for i := 0; i < numModes; i++ {
+		m := reply[pos+i*32:]
+		modes[x11Order.Uint32(m)] = randrModeInfo{
+			id:       x11Order.Uint32(m),
+			width:    x11Order.Uint16(m[4:]),
+			height:   x11Order.Uint16(m[6:]),
+			dotClock: x11Order.Uint32(m[8:]),
+			hTotal:   x11Order.Uint16(m[16:]),
+			vTotal:   x11Order.Uint16(m[24:]),
+			flags:    x11Order.Uint32(m[28:]),
+		}
+	}
+
+	var primary uint32
+	if newer {
+		if reply, err := c.request(major, rrGetOutputPrimary, x11Order.AppendUint32(nil, root)); err == nil {
+			primary = x11Order.Uint32(reply[8:])
+		}
+	}
+
+	// The EDID is available as an output property
+	edidAtom, _ := c.internAtom("EDID", false)
+
+	var (
+		monitors []Monitor
+		counter  uint
+	)
+	for _, output := range outputs {
+		body := x11Order.AppendUint32(nil, output)
+		body = x11Order.AppendUint32(body, configTimestamp)
+		reply, err := c.request(major, rrGetOutputInfo, body)
+		if err != nil {
+			return nil, err
+		}
+		crtc := x11Order.Uint32(reply[12:])
+		physW, physH := int(x11Order.Uint32(reply[16:])), int(x11Order.Uint32(reply[20:]))
+		connected := reply[24] == 0
+		nameLen := int(x11Order.Uint16(reply[34:]))
+		namePos := 36 + 4*(int(x11Order.Uint16(reply[26:]))+int(x11Order.Uint16(reply[28:]))+int(x11Order.Uint16(reply[32:])))
+		if !connected || crtc == 0 || len(reply) < namePos+nameLen {
+			// not connected, or connected but not in use
+			continue
+		}
+		name := string(reply[namePos : namePos+nameLen])
+
+		body = x11Order.AppendUint32(nil, crtc)
+		body = x11Order.AppendUint32(body, configTimestamp)
+		reply, err = c.request(major, rrGetCrtcInfo, body)
+		if err != nil {
+			return nil, err
+		}
+		mon := Monitor{
+			ID:       counter,
+			Name:     name,
+			X:        int(int16(x11Order.Uint16(reply[12:]))),
+			Y:        int(int16(x11Order.Uint16(reply[14:]))),
+			Width:    uint(x11Order.Uint16(reply[16:])),
+			Height:   uint(x11Order.Uint16(reply[18:])),
+			Scale:    1,
+			Rotation: randrRotation(x11Order.Uint16(reply[24:])),
+			Primary:  output == primary,
+		}
+		if mode, ok := modes[x11Order.Uint32(reply[20:])]; ok {
+			mon.Refresh = mode.refresh()
+		}
+		if edidAtom != 0 {
+			if data, err := c.outputEDID(major, output, edidAtom); err == nil {
+				mon.EDID, _ = ParseEDID(data)
+			}
+		}
+		if mon.Rotation == 90 || mon.Rotation == 270 {
+			// the physical size is not rotated, but the resolution is
+			physW, physH = physH, physW
+		}
+		if physW > 0 && physH > 0 {
+			// Calculate DPI, from the monitor size (in mm) and the pixel size
+			mon.DPIw = uint(float64(mon.Width) / (float64(physW) / 25.4))
+			mon.DPIh = uint(float64(mon.Height) / (float64(physH) / 25.4))
+			mon.DPISource = DPIFromServer
+		}
+		monitors = append(monitors, mon)
+		counter++
+	}
+	return monitors, nil
+}
@@ -0,0 +1,347 @@
+package wallutils
+
+import (
+	"encoding/binary"
+	"image"
+	"image/color"
+	"io"
+	"net"
+	"path/filepath"
+	"sync"
+	"testing"
+)
+
+// fakeX is the state of a minimal X server, for testing
+type fakeX struct {
+	mu            sync.Mutex
+	randr         bool
+	width, height uint16            // the size of the screen
+	atoms         map[string]uint32 // atoms that have been interned
+	properties    map[uint32]uint32 // root window properties of the type PIXMAP
+	pixmaps       map[uint32]*image.RGBA
+	background    uint32 // the background pixmap of the root window
+	closeDownMode byte
+	killed        []uint32 // resources given to KillClient
+	putImages     int      // the number of PutImage requests
+}
+
+// startFakeXServer starts a minimal X server that listens on a socket in a
+// temporary directory, and sets DISPLAY to the path of that socket. The
+// server has one 3360x2560 screen, and supports RandR if randr is true.
+func startFakeXServer(t *testing.T, randr bool) *fakeX {
+	t.Helper()
+	dir := t.TempDir()
+	l, err := net.Listen("unix", filepath.Join(dir, "xserver:1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("DISPLAY", filepath.Join(dir, "xserver")+":1")
+	t.Setenv("XAUTHORITY", filepath.Join(dir, "Xauthority"))
+	fx := &fakeX{
+		randr:      randr,
+		width:      3360,
+		height:     2560,
+		atoms:      map[string]uint32{"EDID": 0x50},
+		properties: make(map[uint32]uint32),
+		pixmaps:    make(map[uint32]*image.RGBA),
+	}
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				fx.serve(conn)
+			}()
+		}
+	}()
+	t.Cleanup(func() {
+		l.Close()
+		wg.Wait()
+	})
+	return fx
+}
+
+func (fx *fakeX) serve(conn net.Conn) {
+	defer conn.Close()
+	le := binary.LittleEndian
+
+	setup := make([]byte, 12)
+	if _, err := io.ReadFull(conn, setup); err != nil {
+		return
+	}
+	authLen := int(le.Uint16(setup[6:])) + pad4(int(le.Uint16(setup[6:])))
+	authLen += int(le.Uint16(setup[8:])) + pad4(int(le.Uint16(setup[8:])))
+	if _, err := io.ReadFull(conn, make([]byte, authLen)); err != nil {
+		return
+	}
+
+	// One screen with a 24 bit TrueColor visual, 1107x336 mm
+	fx.mu.Lock()
+	width, height, randr := fx.width, fx.height, fx.randr
+	fx.mu.Unlock()
+	body := make([]byte, 32)
+	le.PutUint32(body[4:], 0x400000) // resource ID base
+	le.PutUint32(body[8:], 0x1fffff) // resource ID mask
+	le.PutUint16(body[16:], 4)       // vendor length
+	le.PutUint16(body[18:], 1024)    // maximum request length, so that PutImage is split
+	body[20] = 1                     // number of screens
+	body[21] = 1                     // number of pixmap formats
+	body = append(body, "fake"...)
+	body = append(body, 24, 32, 32, 0, 0, 0, 0, 0) // depth, bits per pixel, scanline pad
+	screen := make([]byte, 40)
+	le.PutUint32(screen, 0x100)
+	le.PutUint16(screen[20:], width)
+	le.PutUint16(screen[22:], height)
+	le.PutUint16(screen[24:], 1107)
+	le.PutUint16(screen[26:], 336)
+	le.PutUint32(screen[32:], 0x21) // root visual
+	screen[38] = 24                 // root depth
+	screen[39] = 1                  // number of depths
+	body = append(body, screen...)
+	body = append(body, 24, 0, 1, 0, 0, 0, 0, 0) // depth 24, with one visual
+	visual := make([]byte, 24)
+	le.PutUint32(visual, 0x21)
+	visual[4], visual[5] = 4, 8 // TrueColor, 8 bits per color
+	le.PutUint16(visual[6:], 256)
+	le.PutUint32(visual[8:], 0xff0000)
+	le.PutUint32(visual[12:], 0xff00)
+	le.PutUint32(visual[16:], 0xff)
+	body = append(body, visual...)
+	header := []byte{1, 0, 11, 0, 0, 0, 0, 0}
+	le.PutUint16(header[6:], uint16(len(body)/4))
+	conn.Write(append(header, body...))
+
+	const randrOpcode = 140
+	var seq uint16
+	reply := func(data []byte) {
+		msg := make([]byte, 8, 32)
+		msg[0] = 1
+		le.PutUint16(msg[2:], seq)
+		msg = append(msg, data...)
+		for len(msg) < 32 {
+			msg = append(msg, 0)
+		}
+		le.PutUint32(msg[4:], uint32((len(msg)-32)/4))
+		conn.Write(msg)
+	}
+	u16 := func(b []byte, v uint16) []byte { return le.AppendUint16(b, v) }
+	u32 := func(b []byte, v uint32) []byte { return le.AppendUint32(b, v) }
+
+	for {
+		req := make([]byte, 4)
+		if _, err := io.ReadFull(conn, req); err != nil {
+			return
+		}
+		args := make([]byte, int(le.Uint16(req[2:]))*4-4)
+		if _, err := io.ReadFull(conn, args); err != nil {
+			return
+		}
+		seq++
+		switch {
+		case req[0] == 98: // QueryExtension
+			if randr && string(args[4:4+le.Uint16(args)]) == "RANDR" {
+				reply([]byte{1, randrOpcode})
+			} else {
+				reply([]byte{0, 0})
+			}
+		case req[0] == randrOpcode && req[1] == rrQueryVersion:
+			reply(u32(u32(nil, 1), 5))
+		case req[0] == randrOpcode && req[1] == rrGetScreenResourcesCurrent:
+			data := u32(u32(nil, 1), 1)                     // timestamps
+			data = u16(u16(u16(u16(data, 2), 3), 2), 0)     // crtcs, outputs, modes, name bytes
+			data = append(data, make([]byte, 8)...)         // unused
+			data = u32(u32(data, 0x200), 0x201)             // crtcs
+			data = u32(u32(u32(data, 0x300), 0x301), 0x302) // outputs
+			for _, m := range [][5]uint32{{0x400, 2560, 1440, 241500000, 2720<<16 | 1481}, {0x401, 1920, 1080, 148500000, 2200<<16 | 1125}} {
+				data = u32(data, m[0])
+				data = u16(u16(data, uint16(m[1])), uint16(m[2]))
+				data = u32(data, m[3])
+				data = u16(u16(u16(u16(data, 0), 0), uint16(m[4]>>16)), 0)
+				data = u16(u16(u16(u16(data, 0), 0), uint16(m[4])), 0)
+				data = u32(data, 0)
+			}
+			reply(data)
+		case req[0] == randrOpcode && req[1] == rrGetOutputInfo:
+			var (
+				crtc         uint32
+				physW, physH uint32
+				connection   byte = 1
+				name         string
+			)
+			switch le.Uint32(args) {
+			case 0x300:
+				crtc, physW, physH, connection, name = 0x200, 597, 336, 0, "DP-1"
+			case 0x301:
+				crtc, physW, physH, connection, name = 0x201, 510, 287, 0, "HDMI-1"
+			case 0x302:
+				name = "VGA-1"
+			}
+			data := u32(u32(u32(u32(nil, 1), crtc), physW), physH)
+			data = append(data, connection, 0)
+			data = u16(u16(u16(u16(u16(data, 0), 0), 0), 0), uint16(len(name)))
+			data = append(data, name...)
+			for len(data)%4 != 0 {
+				data = append(data, 0)
+			}
+			reply(data)
+		case req[0] == randrOpcode && req[1] == rrGetCrtcInfo:
+			data := u32(nil, 1)
+			switch le.Uint32(args) {
+			case 0x200: // rotated to the left
+				data = u16(u16(u16(u16(data, 0), 0), 1440), 2560)
+				data = u16(u16(u32(data, 0x400), 2), 0xf)
+			case 0x201:
+				data = u16(u16(u16(u16(data, 1440), 0), 1920), 1080)
+				data = u16(u16(u32(data, 0x401), 1), 0xf)
+			}
+			reply(append(data, 0, 0, 0, 0))
+		case req[0] == 16: // InternAtom
+			name := string(args[4 : 4+le.Uint16(args)])
+			fx.mu.Lock()
+			atom, ok := fx.atoms[name]
+			if !ok && req[1] == 0 {
+				atom = uint32(0x60 + len(fx.atoms))
+				fx.atoms[name] = atom
+			}
+			fx.mu.Unlock()
+			reply(u32(nil, atom))
+		case req[0] == 43: // GetInputFocus
+			reply(u32(nil, 0x100))
+		case req[0] == 112: // SetCloseDownMode
+			fx.mu.Lock()
+			fx.closeDownMode = req[1]
+			fx.mu.Unlock()
+		case req[0] == 53: // CreatePixmap
+			fx.mu.Lock()
+			fx.pixmaps[le.Uint32(args)] = image.NewRGBA(image.Rect(0, 0, int(le.Uint16(args[8:])), int(le.Uint16(args[10:]))))
+			fx.mu.Unlock()
+		case req[0] == 55, req[0] == 60, req[0] == 61: // CreateGC, FreeGC, ClearArea
+		case req[0] == 72: // PutImage, ZPixmap with 32 bits per pixel
+			w, h := int(le.Uint16(args[8:])), int(le.Uint16(args[10:]))
+			x, y := int(le.Uint16(args[12:])), int(le.Uint16(args[14:]))
+			fx.mu.Lock()
+			fx.putImages++
+			pixmap := fx.pixmaps[le.Uint32(args)]
+			for i := 0; i < w*h; i++ {
+				v := le.Uint32(args[20+i*4:])
+				pixmap.Set(x+i%w, y+i/w, color.RGBA{uint8(v >> 16), uint8(v >> 8), uint8(v), 255})
+			}
+			fx.mu.Unlock()
+		case req[0] == 20: // GetProperty of the type PIXMAP
+			fx.mu.Lock()
+			value, ok := fx.properties[le.Uint32(args[4:])]
+			fx.mu.Unlock()
+			if !ok {
+				reply(nil) // format 0, no such property
+				continue
+			}
+			data := u32(u32(u32(nil, 20), 0), 1) // type, bytes after, length
+			data = append(data, make([]byte, 12)...)
+			msg := make([]byte, 8, 36)
+			msg[0], msg[1] = 1, 32
+			le.PutUint16(msg[2:], seq)
+			msg = u32(append(msg, data...), value)
+			le.PutUint32(msg[4:], 1)
+			conn.Write(msg)
+		case req[0] == 18: // ChangeProperty
+			fx.mu.Lock()
+			fx.properties[le.Uint32(args[4:])] = le.Uint32(args[20:])
+			fx.mu.Unlock()
+		case req[0] == 113: // KillClient
+			fx.mu.Lock()
+			fx.killed = append(fx.killed, le.Uint32(args))
+			fx.mu.Unlock()
+		case req[0] == 2: // ChangeWindowAttributes
+			if le.Uint32(args[4:])&1 != 0 { // CWBackPixmap
+				fx.mu.Lock()
+				fx.background = le.Uint32(args[8:])
+				fx.mu.Unlock()
+			}
+		case req[0] == randrOpcode && req[1] == rrGetOutputProperty:
+			if le.Uint32(args) != 0x300 || le.Uint32(args[4:]) != 0x50 {
+				reply(nil) // format 0, no such property
+				continue
+			}
+			edid := testEDID(597, 336)
+			data := u32(u32(u32(nil, 19), 0), uint32(len(edid))) // type, bytes after, length
+			data = append(data, make([]byte, 12)...)
+			msg := make([]byte, 8, 32+len(edid))
+			msg[0], msg[1] = 1, 8
+			le.PutUint16(msg[2:], seq)
+			msg = append(append(msg, data...), edid...)
+			le.PutUint32(msg[4:], uint32(len(edid)/4))
+			conn.Write(msg)
+		case req[0] == randrOpcode && req[1] == rrGetOutputPrimary:
+			reply(u32(nil, 0x301))
+		default:
+			// BadRequest
+			msg := make([]byte, 32)
+			msg[1] = 1
+			le.PutUint16(msg[2:], seq)
+			conn.Write(msg)
+		}
+	}
+}
+
+func TestX11Monitors(t *testing.T) {
+	startFakeXServer(t, true)
+	monitors, err := x11Monitors()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(monitors) != 2 {
+		t.Fatalf("expected 2 monitors, got %d: %+v", len(monitors), monitors)
+	}
+	dp, hdmi := monitors[0], monitors[1]
+	if dp.Name != "DP-1" || dp.Width != 1440 || dp.Height != 2560 || dp.Rotation != 90 || dp.Primary {
+		t.Errorf("unexpected monitor: %+v", dp)
+	}
+	if dp.DPIw != 108 || dp.DPIh != 108 || int(dp.Refresh*100) != 5995 || dp.EDID == nil || dp.EDID.Serial != "GH85D5AJ0ZXL" {
+		t.Errorf("unexpected DPI or refresh rate: %+v", dp)
+	}
+	if hdmi.Name != "HDMI-1" || hdmi.X != 1440 || hdmi.Width != 1920 || !hdmi.Primary || hdmi.Refresh != 60 || hdmi.EDID != nil {
+		t.Errorf("unexpected monitor: %+v", hdmi)
+	}
+}
+
+func TestX11MonitorsNoRandR(t *testing.T) {
+	startFakeXServer(t, false)
+	monitors, err := x11Monitors()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(monitors) != 1 || monitors[0].Width != 3360 || monitors[0].Height != 2560 || !monitors[0].Primary {
+		t.Errorf("unexpected monitors: %+v", monitors)
+	}
+}
+
+func TestParseXDisplay(t *testing.T) {
+	for _, tc := range []struct {
+		display, network, address, number string
+		screen                            int
+	}{
+		{":0", "unix", "/tmp/.X11-unix/X0", "0", 0},
+		{"unix:1.2", "unix", "/tmp/.X11-unix/X1", "1", 2},
+		{"localhost:10.0", "tcp", "localhost:6010", "10", 0},
+		{"/tmp/launch-x/org.xquartz:0", "unix", "/tmp/launch-x/org.xquartz:0", "0", 0},
+	} {
+		network, address, number, screen, err := parseXDisplay(tc.display)
+		if err != nil {
+			t.Errorf("%s: %v", tc.display, err)
+			continue
+		}
+		if network != tc.network || address != tc.address || number != tc.number || screen != tc.screen {
+			t.Errorf("%s: got %s %s %s %d", tc.display, network, address, number, screen)
+		}
+	}
+	if _, _, _, _, err := parseXDisplay("nodisplay"); err == nil {
+		t.Error("expected an error for an invalid DISPLAY")
+	}
+}
@@ -0,0 +1,94 @@
+package wallutils
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// DecodeXBM decodes an X11 bitmap, like the ones used by "xsetroot -bitmap".
+// Older X10 bitmaps, where the bits are given as 16-bit shorts, are also
+// supported. Set bits are drawn with the foreground color, and unset bits
+// with the background color.
+func DecodeXBM(r io.Reader, fg, bg color.Color) (*image.Paletted, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	text := string(data)
+	width, height, err := xbmSize(text)
+	if err != nil {
+		return nil, err
+	}
+	start := strings.Index(text, "{")
+	end := strings.LastIndex(text, "}")
+	if start < 0 || end < start {
+		return nil, errors.New("invalid XBM image: could not find the bits")
+	}
+	// X10 bitmaps use shorts instead of chars, and each row is padded to
+	// a whole number of shorts
+	x10 := strings.Contains(text[:start], "short")
+	bitSize, rowBytes := 8, (width+7)/8
+	if x10 {
+		bitSize, rowBytes = 16, (width+15)/16*2
+	}
+	var bits []byte
+	for _, field := range strings.Split(text[start+1:end], ",") {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+		n, err := strconv.ParseUint(field, 0, bitSize)
+		if err != nil {
+			return nil, fmt.Errorf("invalid XBM image: %v", err)
+		}
+		if x10 {
+			// The low byte has the leftmost pixels
+			bits = append(bits, byte(n), byte(n>>8))
+		} else {
+			bits = append(bits, byte(n))
+		}
+	}
+	if len(bits) < rowBytes*height {
+		return nil, fmt.Errorf("invalid XBM image: expected %d bytes, got %d", rowBytes*height, len(bits))
+	}
+	img := image.NewPaletted(image.Rect(0, 0, width, height), color.Palette{bg, fg})
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			// The least significant bit is the leftmost pixel
+			if bits[y*rowBytes+x/8]&(1<<(x%8)) != 0 {
+				img.SetColorIndex(x, y, 1)
+			}
+		}
+	}
+	return img, nil
+}
+
+// xbmSize returns the width and height that are defined in an X11 bitmap
+func xbmSize(text string) (int, int, error) {
+	width, height := -1, -1
+	for _, line := range strings.Split(text, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) != 3 || fields[0] != "#define" {
+			continue
+		}
+		n, err := strconv.Atoi(fields[2])
+		if err != nil {
+			continue
+		}
+		switch {
+		case strings.HasSuffix(fields[1], "_width"):
+			width = n
+		case strings.HasSuffix(fields[1], "_height"):
+			height = n
+		}
+	}
+	if width <= 0 || height <= 0 {
+		return 0, 0, errors.New("invalid XBM image: could not find the width and height")
+	}
+	return width, height, nil
+}
@@ -3,6 +3,7 @@ package wallutils
 import (
 	"errors"
 	"fmt"
+	"sort"
 	"strings"
 
 	"github.com/xyproto/env/v2"
@@ -34,6 +35,17 @@ func (x *Xfce4) SetMode(mode string) {
 	x.mode = mode
 }
 
+// Capabilities returns the wallpaper modes that are supported by this backend,
+// if one wallpaper per output can be set and if the wallpaper is kept after
+// logging in again
+func (x *Xfce4) Capabilities() Capabilities {
+	return Capabilities{
+		Modes:      []string{"auto", "center", "centered", "tile", "tiled", "stretch", "stretched", "scale", "scaled", "fit", "fill", "zoom", "zoomed", "crop", "cropped"},
+		PerOutput:  true,
+		Persistent: true,
+	}
+}
+
 // SetVerbose can be used for setting the verbose field to true or false.
 // This will cause this backend to output information about what is is doing on stdout.
 func (x *Xfce4) SetVerbose(verbose bool) {
@@ -43,6 +55,13 @@ func (x *Xfce4) SetVerbose(verbose bool) {
 // SetWallpaper sets the desktop wallpaper, given an image filename.
 // The image must exist and be readable.
 func (x *Xfce4) SetWallpaper(imageFilename string) error {
+	return x.SetWallpaperForOutput("*", imageFilename)
+}
+
+// SetWallpaperForOutput sets the desktop wallpaper for the given monitor
+// (like "HDMI-1"), or for all monitors if the monitor name is "*".
+// The image must exist and be readable.
+func (x *Xfce4) SetWallpaperForOutput(monitor, imageFilename string) error {
 	if !exists(imageFilename) {
 		return fmt.Errorf("no such file: %s", imageFilename)
 	}
@@ -50,7 +69,7 @@ func (x *Xfce4) SetWallpaper(imageFilename string) error {
 	// Find a list of all available properties for all monitors
 	properties := strings.Split(output("xfconf-query", []string{"--channel", "xfce4-desktop", "--list"}, x.verbose), "\n")
 	if len(properties) == 0 {
-		return errors.New("could not find any properties for Xfce4")
+		return fmt.Errorf("could not find any properties for Xfce4: %w", ErrNotAvailable)
 	}
 
 	// initialize the mode setting (stretched/tiled etc)
@@ -80,11 +99,20 @@ func (x *Xfce4) SetWallpaper(imageFilename string) error {
 			fillMode = "5"
 		default:
 			// Invalid and unrecognized desktop wallpaper mode
-			return fmt.Errorf("invalid desktop wallpaper mode for Xfce4: %s", x.mode)
+			return fmt.Errorf("%w for Xfce4: %s", ErrUnsupportedMode, x.mode)
 		}
 	}
 
+	// The properties for a monitor looks like this:
+	// /backdrop/screen0/monitorHDMI-1/workspace0/last-image
+	monitorPart := "/monitor" + monitor + "/"
+
+	found := false
 	for _, prop := range properties {
+		if monitor != "*" && !strings.Contains(prop, monitorPart) {
+			continue
+		}
+		found = true
 
 		if strings.HasSuffix(prop, "/image-style") {
 			if err := run("xfconf-query", []string{"--channel", "xfce4-desktop", "--property", prop, "--set", fillMode}, x.verbose); err != nil {
@@ -97,5 +125,24 @@ func (x *Xfce4) SetWallpaper(imageFilename string) error {
 			}
 		}
 	}
+	if !found {
+		return fmt.Errorf("could not find any Xfce4 properties for monitor %s", monitor)
+	}
 	return nil
 }
+
+// CurrentWallpaper returns the current wallpaper, from the first last-image
+// property that is set
+func (x *Xfce4) CurrentWallpaper() (string, error) {
+	properties := strings.Split(output("xfconf-query", []string{"--channel", "xfce4-desktop", "--list"}, x.verbose), "\n")
+	sort.Strings(properties)
+	for _, prop := range properties {
+		if !strings.HasSuffix(prop, "/last-image") {
+			continue
+		}
+		if imageFilename := strings.TrimSpace(output("xfconf-query", []string{"--channel", "xfce4-desktop", "--property", prop}, x.verbose)); imageFilename != "" {
+			return imageFilename, nil
+		}
+	}
+	return "", errors.New("could not find a last-image property for Xfce4")
+}
@@ -27,49 +27,81 @@ func XInfo() (string, error) {
 // XMonitors returns information about the available monitors.
 // The given slices are filled with data about resolution and DPI.
 func XMonitors(IDs, widths, heights, wDPIs, hDPIs *[]uint) error {
+	monitors, err := xMonitors()
+	if err != nil {
+		return err
+	}
+	for _, mon := range monitors {
+		*IDs = append(*IDs, mon.ID)
+		*widths = append(*widths, mon.Width)
+		*heights = append(*heights, mon.Height)
+		*wDPIs = append(*wDPIs, mon.DPIw)
+		*hDPIs = append(*hDPIs, mon.DPIh)
+	}
+	return nil
+}
+
+// xMonitors returns a slice of Monitor structs. The connected outputs
+// reported by xrandr are used, if available. If not, one Monitor struct is
+// returned per X screen.
+func xMonitors() ([]Monitor, error) {
 	if !XCanConnect() {
-		return errors.New("XMonitors(): not connected over X11")
+		return nil, errors.New("XMonitors(): not connected over X11")
+	}
+	if which("xrandr") != "" {
+		if monitors := parseXRandrMonitors(output("xrandr", []string{"--query", "--prop"}, false)); len(monitors) > 0 {
+			return monitors, nil
+		}
 	}
 	info, err := XInfo()
 	if err != nil {
-		return err
+		return nil, err
 	}
-	var counter uint
+	var (
+		monitors      []Monitor
+		counter       uint
+		defaultScreen int
+	)
 	// TODO: Write a C implementation instead of parsing the info string
 	for _, line := range strings.Split(info, "\n") {
-		if strings.Contains(line, "dimensions:") {
+		if strings.HasPrefix(line, "default screen number:") {
+			fields := strings.Fields(line)
+			defaultScreen, _ = strconv.Atoi(fields[len(fields)-1])
+		} else if strings.Contains(line, "dimensions:") {
 			fields := strings.Fields(line)
 			if len(fields) > 2 && strings.Contains(fields[1], "x") {
 				resFields := strings.SplitN(fields[1], "x", 2)
 				w, err := strconv.Atoi(resFields[0])
 				if err != nil {
-					return err
+					return nil, err
 				}
 				h, err := strconv.Atoi(resFields[1])
 				if err != nil {
-					return err
+					return nil, err
 				}
-				*IDs = append(*IDs, counter)
-				*widths = append(*widths, uint(w))
-				*heights = append(*heights, uint(h))
+				monitors = append(monitors, Monitor{ID: counter, Width: uint(w), Height: uint(h), Scale: 1})
 				counter++
 			}
-		} else if strings.Contains(line, "resolution:") {
+		} else if strings.Contains(line, "resolution:") && len(monitors) > 0 {
 			fields := strings.Fields(line)
 			if len(fields) > 2 && strings.Contains(fields[1], "x") {
 				dpiFields := strings.SplitN(fields[1], "x", 2)
 				wDPI, err := strconv.Atoi(dpiFields[0])
 				if err != nil {
-					return err
+					return nil, err
 				}
 				hDPI, err := strconv.Atoi(dpiFields[1])
 				if err != nil {
-					return err
+					return nil, err
 				}
-				*wDPIs = append(*wDPIs, uint(wDPI))
-				*hDPIs = append(*hDPIs, uint(hDPI))
+				monitors[len(monitors)-1].DPIw = uint(wDPI)
+				monitors[len(monitors)-1].DPIh = uint(hDPI)
+				monitors[len(monitors)-1].DPISource = DPIFromServer
 			}
 		}
 	}
-	return nil
+	if defaultScreen >= 0 && defaultScreen < len(monitors) {
+		monitors[defaultScreen].Primary = true
+	}
+	return monitors, nil
 }
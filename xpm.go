@@ -0,0 +1,206 @@
+package wallutils
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"io"
+	"strconv"
+	"strings"
+
+	"golang.org/x/image/colornames"
+)
+
+func init() {
+	image.RegisterFormat("xpm", "/* XPM */", DecodeXPM, decodeXPMConfig)
+	image.RegisterFormat("xbm", "#define", decodeXBMImage, decodeXBMConfig)
+}
+
+// xpmStrings returns the quoted strings in an XPM file, in order
+func xpmStrings(data []byte) []string {
+	var (
+		strs    []string
+		current []byte
+		inStr   bool
+		escaped bool
+	)
+	for _, b := range data {
+		switch {
+		case !inStr && b == '"':
+			inStr = true
+			current = current[:0]
+		case inStr && escaped:
+			current = append(current, b)
+			escaped = false
+		case inStr && b == '\\':
+			escaped = true
+		case inStr && b == '"':
+			strs = append(strs, string(current))
+			inStr = false
+		case inStr:
+			current = append(current, b)
+		}
+	}
+	return strs
+}
+
+// xpmHeader parses the first string of an XPM image: width, height,
+// number of colors and characters per pixel
+func xpmHeader(strs []string) (int, int, int, int, error) {
+	if len(strs) == 0 {
+		return 0, 0, 0, 0, errors.New("invalid XPM image: no values")
+	}
+	fields := strings.Fields(strs[0])
+	if len(fields) < 4 {
+		return 0, 0, 0, 0, errors.New("invalid XPM image: too few values")
+	}
+	var values [4]int
+	for i := range values {
+		n, err := strconv.Atoi(fields[i])
+		if err != nil || n <= 0 {
+			return 0, 0, 0, 0, fmt.Errorf("invalid XPM image: %s", strs[0])
+		}
+		values[i] = n
+	}
+	return values[0], values[1], values[2], values[3], nil
+}
+
+// xpmColor parses a color in an XPM image, like "#223344", "None" or "gray50"
+func xpmColor(s string) (color.RGBA, error) {
+	lower := strings.ToLower(s)
+	switch {
+	case lower == "none":
+		return color.RGBA{}, nil
+	case strings.HasPrefix(lower, "#") && len(lower) == 13:
+		// 16 bits per channel
+		n, err := strconv.ParseUint(lower[1:], 16, 64)
+		if err != nil {
+			return color.RGBA{}, fmt.Errorf("invalid color: %s", s)
+		}
+		return color.RGBA{uint8(n >> 40), uint8(n >> 24), uint8(n >> 8), 255}, nil
+	case strings.HasPrefix(lower, "gray") || strings.HasPrefix(lower, "grey"):
+		// X11 gray levels, from gray0 to gray100
+		if level, err := strconv.Atoi(lower[4:]); err == nil && level >= 0 && level <= 100 {
+			v := uint8((level*255 + 50) / 100)
+			return color.RGBA{v, v, v, 255}, nil
+		}
+	}
+	if c, ok := colornames.Map[strings.ReplaceAll(lower, " ", "")]; ok {
+		return c, nil
+	}
+	return ParseColor(s)
+}
+
+// xpmColorTable parses the colors of an XPM image, and returns a map from
+// the characters of each pixel to the color
+func xpmColorTable(strs []string, numColors, charsPerPixel int) (map[string]color.RGBA, error) {
+	if len(strs) < 1+numColors {
+		return nil, errors.New("invalid XPM image: too few colors")
+	}
+	table := make(map[string]color.RGBA, numColors)
+	for _, line := range strs[1 : 1+numColors] {
+		if len(line) < charsPerPixel {
+			return nil, fmt.Errorf("invalid XPM color: %s", line)
+		}
+		chars := line[:charsPerPixel]
+		// The color is given as pairs of a context and a color, like "c #ffffff m white".
+		// The "c" context is for color displays, and is preferred.
+		fields := strings.Fields(line[charsPerPixel:])
+		colors := make(map[string]string)
+		for i := 0; i+1 < len(fields); i++ {
+			switch fields[i] {
+			case "c", "g", "g4", "m", "s":
+				// Color names may contain spaces, like "light blue"
+				j := i + 1
+				for j+1 < len(fields) && !strings.Contains(" c g g4 m s ", " "+fields[j+1]+" ") {
+					j++
+				}
+				colors[fields[i]] = strings.Join(fields[i+1:j+1], " ")
+				i = j
+			}
+		}
+		var name string
+		for _, context := range []string{"c", "g", "g4", "m"} {
+			if colors[context] != "" {
+				name = colors[context]
+				break
+			}
+		}
+		if name == "" {
+			return nil, fmt.Errorf("invalid XPM color: %s", line)
+		}
+		c, err := xpmColor(name)
+		if err != nil {
+			return nil, err
+		}
+		table[chars] = c
+	}
+	return table, nil
+}
+
+// DecodeXPM decodes an X11 pixmap (XPM) image
+func DecodeXPM(r io.Reader) (image.Image, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	strs := xpmStrings(data)
+	width, height, numColors, charsPerPixel, err := xpmHeader(strs)
+	if err != nil {
+		return nil, err
+	}
+	table, err := xpmColorTable(strs, numColors, charsPerPixel)
+	if err != nil {
+		return nil, err
+	}
+	rows := strs[1+numColors:]
+	if len(rows) < height {
+		return nil, fmt.Errorf("invalid XPM image: expected %d rows, got %d", height, len(rows))
+	}
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y, row := range rows[:height] {
+		if len(row) < width*charsPerPixel {
+			return nil, fmt.Errorf("invalid XPM image: row %d is too short", y)
+		}
+		for x := 0; x < width; x++ {
+			c, ok := table[row[x*charsPerPixel:(x+1)*charsPerPixel]]
+			if !ok {
+				return nil, fmt.Errorf("invalid XPM image: unknown color in row %d", y)
+			}
+			img.SetRGBA(x, y, c)
+		}
+	}
+	return img, nil
+}
+
+// decodeXPMConfig returns the size of an XPM image
+func decodeXPMConfig(r io.Reader) (image.Config, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return image.Config{}, err
+	}
+	width, height, _, _, err := xpmHeader(xpmStrings(data))
+	if err != nil {
+		return image.Config{}, err
+	}
+	return image.Config{ColorModel: color.RGBAModel, Width: width, Height: height}, nil
+}
+
+// decodeXBMImage decodes an X11 bitmap with black pixels on a white background
+func decodeXBMImage(r io.Reader) (image.Image, error) {
+	return DecodeXBM(r, color.Black, color.White)
+}
+
+// decodeXBMConfig returns the size of an X11 bitmap
+func decodeXBMConfig(r io.Reader) (image.Config, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return image.Config{}, err
+	}
+	width, height, err := xbmSize(string(data))
+	if err != nil {
+		return image.Config{}, err
+	}
+	return image.Config{ColorModel: color.GrayModel, Width: width, Height: height}, nil
+}
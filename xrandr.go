@@ -1,6 +1,7 @@
 package wallutils
 
 import (
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"image"
@@ -146,3 +147,115 @@ func NoXRandrOverlapOrExit(verbose bool) {
 		fmt.Println("Detected no overlapping monitor configurations.")
 	}
 }
+
+// parseXRandrMonitors parses the output of "xrandr --query" and returns one
+// Monitor struct per connected output that is currently in use. If the
+// output is from "xrandr --query --prop", the EDID is also decoded.
+//
+// Example output from xrandr:
+//
+//	DP-1 connected primary 2560x1440+1920+0 left (normal left inverted right x axis y axis) 597mm x 336mm
+//	   2560x1440     59.95*+ 143.97
+func parseXRandrMonitors(xrandrOutput string) []Monitor {
+	var (
+		monitors []Monitor
+		counter  uint
+		current  *Monitor // the output that mode lines currently belong to
+		edidHex  strings.Builder
+		inEDID   bool // collecting the lines after "EDID:", from "xrandr --prop"
+	)
+	flushEDID := func() {
+		if inEDID && current != nil {
+			if data, err := hex.DecodeString(edidHex.String()); err == nil {
+				current.EDID, _ = ParseEDID(data)
+			}
+		}
+		inEDID = false
+		edidHex.Reset()
+	}
+	for _, line := range strings.Split(xrandrOutput, "\n") {
+		words := strings.Fields(line)
+		if len(words) == 0 {
+			continue
+		}
+		if strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t") {
+			if current == nil {
+				continue
+			}
+			// The EDID is given as lines of hex digits, when using --prop
+			if len(words) == 1 && words[0] == "EDID:" {
+				inEDID = true
+				continue
+			}
+			if inEDID {
+				if _, err := hex.DecodeString(words[0]); err == nil && len(words) == 1 {
+					edidHex.WriteString(words[0])
+					continue
+				}
+				flushEDID()
+			}
+			// A mode line, find the refresh rate of the current mode
+			for _, word := range words[1:] {
+				if strings.Contains(word, "*") {
+					current.Refresh, _ = strconv.ParseFloat(strings.Trim(word, "*+"), 64)
+				}
+			}
+			continue
+		}
+		flushEDID()
+		current = nil
+		if len(words) < 3 || words[1] != "connected" {
+			continue
+		}
+		mon := Monitor{Name: words[0], Scale: 1}
+		rest := words[2:]
+		if rest[0] == "primary" {
+			mon.Primary = true
+			rest = rest[1:]
+		}
+		if len(rest) == 0 || strings.Count(rest[0], "+") != 2 || !strings.Contains(rest[0], "x") {
+			// connected, but not in use
+			continue
+		}
+		fields := strings.SplitN(rest[0], "x", 2)
+		ws, tail := fields[0], fields[1]
+		fields = strings.SplitN(tail, "+", 3)
+		hs, xs, ys := fields[0], fields[1], fields[2]
+		width, err := strconv.Atoi(ws)
+		if err != nil {
+			continue
+		}
+		height, err := strconv.Atoi(hs)
+		if err != nil {
+			continue
+		}
+		mon.Width, mon.Height = uint(width), uint(height)
+		mon.X, _ = strconv.Atoi(xs)
+		mon.Y, _ = strconv.Atoi(ys)
+		rest = rest[1:]
+		if len(rest) > 0 && !strings.HasPrefix(rest[0], "(") {
+			mon.Rotation = rotationFromTransform(rest[0])
+		}
+		// The physical size is given at the end of the line, as "597mm x 336mm"
+		if n := len(words); n > 3 && words[n-2] == "x" && strings.HasSuffix(words[n-3], "mm") && strings.HasSuffix(words[n-1], "mm") {
+			physW, errW := strconv.Atoi(strings.TrimSuffix(words[n-3], "mm"))
+			physH, errH := strconv.Atoi(strings.TrimSuffix(words[n-1], "mm"))
+			if mon.Rotation == 90 || mon.Rotation == 270 {
+				// the physical size is not rotated, but the resolution is
+				physW, physH = physH, physW
+			}
+			if errW == nil && errH == nil && physW > 0 && physH > 0 {
+				// Calculate DPI, from the monitor size (in mm) and the pixel size
+				mon.DPIw = uint(float64(width) / (float64(physW) / 25.4))
+				mon.DPIh = uint(float64(height) / (float64(physH) / 25.4))
+				mon.DPISource = DPIFromServer
+			}
+		}
+		mon.ID = counter
+		counter++
+		monitors = append(monitors, mon)
+		current = &monitors[len(monitors)-1]
+	}
+	flushEDID()
+	return monitors
+}
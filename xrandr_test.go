@@ -1,9 +1,65 @@
 package wallutils
 
 import (
+	"encoding/hex"
 	"testing"
 )
 
 func TestXrandrOverlap(_ *testing.T) {
 	NoXRandrOverlapOrExit(true)
 }
+
+func TestParseXRandrMonitors(t *testing.T) {
+	const xrandrOutput = `Screen 0: minimum 320 x 200, current 3360 x 2560, maximum 16384 x 16384
+HDMI-1 connected 1920x1080+0+0 (normal left inverted right x axis y axis) 531mm x 299mm
+   1920x1080     60.00 +  59.94*   50.00
+   1280x720      60.00
+DP-1 connected primary 1440x2560+1920+0 left (normal left inverted right x axis y axis) 597mm x 336mm
+   2560x1440     59.95*+
+DP-2 disconnected (normal left inverted right x axis y axis)
+DP-3 connected (normal left inverted right x axis y axis)
+   1024x768      60.00 +
+`
+	monitors := parseXRandrMonitors(xrandrOutput)
+	if len(monitors) != 2 {
+		t.Fatalf("expected 2 monitors, got %d", len(monitors))
+	}
+	hdmi, dp := monitors[0], monitors[1]
+	if hdmi.Name != "HDMI-1" || hdmi.Width != 1920 || hdmi.Height != 1080 || hdmi.Primary || hdmi.Refresh != 59.94 {
+		t.Errorf("unexpected HDMI-1 monitor: %+v", hdmi)
+	}
+	if hdmi.DPIw != 91 || hdmi.DPIh != 91 {
+		t.Errorf("expected 91x91 DPI for HDMI-1, got %dx%d", hdmi.DPIw, hdmi.DPIh)
+	}
+	if dp.ID != 1 || dp.Name != "DP-1" || dp.X != 1920 || dp.Y != 0 || !dp.Primary || dp.Rotation != 90 || dp.Refresh != 59.95 {
+		t.Errorf("unexpected DP-1 monitor: %+v", dp)
+	}
+	if dp.DPIw != 108 || dp.DPIh != 108 {
+		t.Errorf("expected 108x108 DPI for the rotated DP-1, got %dx%d", dp.DPIw, dp.DPIh)
+	}
+}
+
+func TestParseXRandrMonitorsEDID(t *testing.T) {
+	edid := hex.EncodeToString(testEDID(597, 336))
+	xrandrOutput := "Screen 0: minimum 320 x 200, current 2560 x 1440, maximum 16384 x 16384\n" +
+		"DP-1 connected primary 2560x1440+0+0 (normal left inverted right x axis y axis) 0mm x 0mm\n" +
+		"\tEDID: \n"
+	for i := 0; i < len(edid); i += 32 {
+		xrandrOutput += "\t\t" + edid[i:i+32] + "\n"
+	}
+	xrandrOutput += "\tnon-desktop: 0 \n" +
+		"\t\tsupported: 0, 1\n" +
+		"   2560x1440     59.95*+\n"
+	monitors := parseXRandrMonitors(xrandrOutput)
+	if len(monitors) != 1 {
+		t.Fatalf("expected 1 monitor, got %d", len(monitors))
+	}
+	mon := monitors[0]
+	if mon.EDID == nil || mon.EDID.Model != "DELL U2715H" || mon.Refresh != 59.95 || mon.DPIw != 0 {
+		t.Errorf("unexpected monitor: %+v", mon)
+	}
+	mon.applyEDID()
+	if mon.DPIw != 108 || mon.DPIh != 108 || mon.DPISource != DPIFromEDID {
+		t.Errorf("unexpected DPI: %dx%d from %s", mon.DPIw, mon.DPIh, mon.DPISource)
+	}
+}